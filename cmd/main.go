@@ -1,7 +1,17 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/waynewu411/blocktasks/pkg/chain"
 	"github.com/waynewu411/blocktasks/pkg/config"
@@ -9,6 +19,7 @@ import (
 	"github.com/waynewu411/blocktasks/pkg/logger"
 	"github.com/waynewu411/blocktasks/pkg/repository"
 	"github.com/waynewu411/blocktasks/pkg/request"
+	"github.com/waynewu411/blocktasks/pkg/server"
 	"github.com/waynewu411/blocktasks/pkg/tasks"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
@@ -19,6 +30,15 @@ var (
 	Build   string = ""
 )
 
+const (
+	CommandRun                 = "run"
+	CommandRetryDeadLetters    = "retry-dead-letters"
+	CommandMigrate             = "migrate"
+	CommandBackfillLogMetadata = "backfill-log-metadata"
+	CommandProcessedRanges     = "processed-ranges"
+	CommandHealthcheck         = "healthcheck"
+)
+
 func logVersionAndBuild(lg *zap.Logger) {
 	lg.Info("blocktasks", zap.String("version", Version), zap.String("build", Build))
 }
@@ -29,26 +49,288 @@ func main() {
 
 	logVersionAndBuild(lg)
 
+	command := CommandRun
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+
 	cfg := config.LoadConfig(lg)
+	if cfg.InstanceId == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			lg.Fatal("fail to get hostname", zap.Error(err))
+		}
+		cfg.InstanceId = hostname
+	}
+
+	if command == CommandMigrate {
+		if err := migrate(lg, cfg, os.Args[2:]); err != nil {
+			lg.Fatal("fail to migrate", zap.Strings("args", os.Args[2:]), zap.Error(err))
+		}
+		return
+	}
+
+	if command == CommandHealthcheck {
+		if err := healthcheck(cfg.ServerConfig, os.Args[2:]); err != nil {
+			lg.Fatal("unhealthy", zap.Strings("args", os.Args[2:]), zap.Error(err))
+		}
+		return
+	}
+
+	repo := newRepository(lg, cfg)
 
-	pgRepo := repository.NewPgRepository(lg, cfg.PgConfig)
+	var err error
+	switch command {
+	case CommandRun:
+		err = runTasks(lg, cfg, repo)
+	case CommandRetryDeadLetters:
+		err = retryDeadLetters(lg, cfg, repo)
+	case CommandBackfillLogMetadata:
+		err = backfillLogMetadata(lg, cfg, repo)
+	case CommandProcessedRanges:
+		err = printProcessedRanges(repo, os.Args[2:])
+	default:
+		lg.Fatal("unknown command", zap.String("command", command))
+	}
+
+	if err != nil {
+		lg.Fatal("blocktasks stopped", zap.String("command", command), zap.Error(err))
+	}
+}
+
+func newRepository(lg *zap.Logger, cfg *config.Config) repository.Repository {
+	switch cfg.Repository {
+	case config.RepositoryPostgres:
+		return repository.NewPgRepository(lg, cfg.PgConfig)
+	case config.RepositorySqlite:
+		return repository.NewSqliteRepository(lg, cfg.SqliteConfig)
+	default:
+		lg.Fatal("unknown repository", zap.String("repository", cfg.Repository))
+		return nil
+	}
+}
+
+func newMigrator(lg *zap.Logger, cfg *config.Config) repository.Migrator {
+	switch cfg.Repository {
+	case config.RepositoryPostgres:
+		return repository.NewPgMigrator(lg, cfg.PgConfig)
+	case config.RepositorySqlite:
+		return repository.NewSqliteMigrator(lg, cfg.SqliteConfig)
+	default:
+		lg.Fatal("unknown repository", zap.String("repository", cfg.Repository))
+		return nil
+	}
+}
 
+func newBaseChain(lg *zap.Logger, cfg config.ChainConfig) chain.Chain {
+	request := request.NewRequest(
+		lg,
+		request.WithHttpClient(
+			httpclient.NewHttpClient(lg, cfg.HttpClientConfig),
+		),
+	)
+	return chain.NewBaseChain(lg, cfg, request)
+}
+
+func runTasks(lg *zap.Logger, cfg *config.Config, repo repository.Repository) error {
 	eg, ctx := errgroup.WithContext(context.Background())
 
+	opts := []tasks.LogMonitorOption{tasks.WithInstanceId(cfg.InstanceId)}
+	if cfg.LeaseConfig.Enabled {
+		opts = append(opts, tasks.WithLease(cfg.InstanceId, cfg.LeaseConfig))
+	}
+
+	var baseChain chain.Chain
 	if cfg.BaseEventMonitorConfig.Enabled {
-		request := request.NewRequest(
-			lg,
-			request.WithHttpClient(
-				httpclient.NewHttpClient(lg, cfg.BaseEventMonitorConfig.ChainConfig.HttpClientConfig),
-			),
-		)
-		baseChain := chain.NewBaseChain(lg, cfg.BaseEventMonitorConfig.ChainConfig, request)
+		baseChain = newBaseChain(lg, cfg.BaseEventMonitorConfig.ChainConfig)
+	}
+
+	if cfg.ServerConfig.Enabled {
+		var serverOpts []server.ServerOption
+		if baseChain != nil {
+			serverOpts = append(serverOpts, server.WithTaskChain(tasks.TaskBaseLogMonitor, baseChain))
+		}
+		if cfg.ServerConfig.RpcEnabled && baseChain != nil {
+			addresses, fromBlockNumber := tasks.IndexedContracts(cfg.BaseEventMonitorConfig)
+			serverOpts = append(serverOpts, server.WithRpcChain(server.RpcChain{
+				TaskName:        tasks.TaskBaseLogMonitor,
+				Chain:           baseChain,
+				Addresses:       addresses,
+				FromBlockNumber: fromBlockNumber,
+			}))
+		}
+		srv := server.NewServer(lg, cfg.ServerConfig, repo, serverOpts...)
+		opts = append(opts, tasks.WithCommitListener(srv.NotifyLogs))
 		eg.Go(func() error {
-			return tasks.NewLogMonitor(lg, tasks.TaskBaseLogMonitor, cfg.BaseEventMonitorConfig, pgRepo, baseChain).Start(ctx)
+			return srv.Start(ctx)
 		})
 	}
 
-	if err := eg.Wait(); err != nil {
-		lg.Fatal("blocktasks stopped", zap.Error(err))
+	if baseChain != nil {
+		eg.Go(func() error {
+			return tasks.NewLogMonitor(lg, tasks.TaskBaseLogMonitor, cfg.BaseEventMonitorConfig, repo, baseChain, opts...).Start(ctx)
+		})
+		var maintainerOpts []tasks.LogPartitionMaintainerOption
+		if cfg.LeaseConfig.Enabled {
+			maintainerOpts = append(maintainerOpts, tasks.WithLeaseHolder(tasks.TaskBaseLogMonitor, cfg.InstanceId))
+		}
+		eg.Go(func() error {
+			return tasks.NewLogPartitionMaintainer(
+				lg,
+				tasks.TaskBaseLogPartitionMaintainer,
+				[]string{tasks.TaskBaseLogMonitor},
+				cfg.BaseEventMonitorConfig.PartitionConfig,
+				repo,
+				baseChain,
+				maintainerOpts...,
+			).Start(ctx)
+		})
+	}
+
+	return eg.Wait()
+}
+
+// retryDeadLetters retries the ranges which the monitors dead-lettered and
+// exits once every range has been tried.
+func retryDeadLetters(lg *zap.Logger, cfg *config.Config, repo repository.Repository) error {
+	eg, ctx := errgroup.WithContext(context.Background())
+
+	if cfg.BaseEventMonitorConfig.Enabled {
+		baseChain := newBaseChain(lg, cfg.BaseEventMonitorConfig.ChainConfig)
+		eg.Go(func() error {
+			return tasks.NewDeadLetterRetrier(lg, tasks.TaskBaseLogMonitor, cfg.InstanceId, cfg.BaseEventMonitorConfig, repo, baseChain).Start(ctx)
+		})
 	}
+
+	return eg.Wait()
+}
+
+// backfillLogMetadata fills the address and the transaction index of the
+// logs stored by earlier versions, then exits.
+func backfillLogMetadata(lg *zap.Logger, cfg *config.Config, repo repository.Repository) error {
+	eg, ctx := errgroup.WithContext(context.Background())
+
+	if cfg.BaseEventMonitorConfig.Enabled {
+		baseChain := newBaseChain(lg, cfg.BaseEventMonitorConfig.ChainConfig)
+		eg.Go(func() error {
+			return tasks.NewLogMetadataBackfill(lg, tasks.TaskBaseLogMonitor, cfg.BaseEventMonitorConfig, repo, baseChain).Start(ctx)
+		})
+	}
+
+	return eg.Wait()
+}
+
+// printProcessedRanges prints the most recently committed ranges as JSON
+// lines, only those containing the block when a block number is given.
+func printProcessedRanges(repo repository.Repository, args []string) error {
+	var filter repository.ProcessedRangeFilter
+	if len(args) > 0 {
+		blockNumber, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid block number %q", args[0])
+		}
+		filter.BlockNumber = blockNumber
+	}
+
+	processedRanges, err := repo.ProcessedRangeDao().QueryProcessedRanges(context.Background(), filter, 0)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	for _, processedRange := range processedRanges {
+		if err := encoder.Encode(processedRange); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// healthcheck requests /healthz, or the given path, from the server of the
+// running instance and fails unless it answers 200, for the health check of
+// a container without a shell. The liveness is checked by default, so that a
+// container is not restarted while its dependencies are unavailable.
+func healthcheck(cfg config.ServerConfig, args []string) error {
+	if !cfg.Enabled {
+		return errors.New("server is not enabled")
+	}
+	path := "/healthz"
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	host, port, err := net.SplitHostPort(cfg.Address)
+	if err != nil {
+		return err
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+
+	client := &http.Client{Timeout: 2 * time.Duration(max(cfg.ReadyTimeout, 1)) * time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://%s%s", net.JoinHostPort(host, port), path))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// migrate runs `migrate up`, `migrate down [steps]` or `migrate version`.
+// Down reverts one migration unless steps is given.
+func migrate(lg *zap.Logger, cfg *config.Config, args []string) error {
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	ctx := context.Background()
+	migrator := newMigrator(lg, cfg)
+
+	switch action {
+	case "up":
+		if err := migrator.Up(ctx); err != nil {
+			return err
+		}
+		// logs stored by earlier versions are only filled by a separate
+		// command, since it fetches their receipts from the provider
+		missing, err := newRepository(lg, cfg).LogDao().CountLogsWithoutAddress(ctx)
+		if err != nil {
+			return err
+		}
+		if missing > 0 {
+			lg.Warn("logs stored without their address, run backfill-log-metadata", zap.Int64("logs", missing))
+		}
+	case "down":
+		steps := int64(1)
+		if len(args) > 1 {
+			var err error
+			steps, err = strconv.ParseInt(args[1], 10, 64)
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("invalid steps %q", args[1])
+			}
+		}
+		if err := migrator.Down(ctx, steps); err != nil {
+			return err
+		}
+	case "version":
+	default:
+		return fmt.Errorf("unknown migrate action %q", action)
+	}
+
+	version, err := migrator.Version(ctx)
+	if err != nil {
+		return err
+	}
+	lg.Info("database schema version", zap.Int64("version", version), zap.Int64("latestVersion", migrator.LatestVersion()))
+
+	return nil
 }
@@ -0,0 +1,778 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: blocktasks.proto
+
+package api
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// LogFilter selects logs. Unset fields do not filter.
+type LogFilter struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	ChainId int64                  `protobuf:"varint,1,opt,name=chain_id,json=chainId,proto3" json:"chain_id,omitempty"`
+	// logs emitted by any of the addresses
+	Addresses []string `protobuf:"bytes,2,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	// topics[i] matches topic i against any of its values
+	Topics          []*TopicFilter `protobuf:"bytes,3,rep,name=topics,proto3" json:"topics,omitempty"`
+	FromBlockNumber int64          `protobuf:"varint,4,opt,name=from_block_number,json=fromBlockNumber,proto3" json:"from_block_number,omitempty"`
+	// inclusive
+	ToBlockNumber int64                  `protobuf:"varint,5,opt,name=to_block_number,json=toBlockNumber,proto3" json:"to_block_number,omitempty"`
+	FromTime      *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=from_time,json=fromTime,proto3" json:"from_time,omitempty"`
+	// inclusive
+	ToTime        *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=to_time,json=toTime,proto3" json:"to_time,omitempty"`
+	TxnHash       string                 `protobuf:"bytes,8,opt,name=txn_hash,json=txnHash,proto3" json:"txn_hash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogFilter) Reset() {
+	*x = LogFilter{}
+	mi := &file_blocktasks_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogFilter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogFilter) ProtoMessage() {}
+
+func (x *LogFilter) ProtoReflect() protoreflect.Message {
+	mi := &file_blocktasks_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogFilter.ProtoReflect.Descriptor instead.
+func (*LogFilter) Descriptor() ([]byte, []int) {
+	return file_blocktasks_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *LogFilter) GetChainId() int64 {
+	if x != nil {
+		return x.ChainId
+	}
+	return 0
+}
+
+func (x *LogFilter) GetAddresses() []string {
+	if x != nil {
+		return x.Addresses
+	}
+	return nil
+}
+
+func (x *LogFilter) GetTopics() []*TopicFilter {
+	if x != nil {
+		return x.Topics
+	}
+	return nil
+}
+
+func (x *LogFilter) GetFromBlockNumber() int64 {
+	if x != nil {
+		return x.FromBlockNumber
+	}
+	return 0
+}
+
+func (x *LogFilter) GetToBlockNumber() int64 {
+	if x != nil {
+		return x.ToBlockNumber
+	}
+	return 0
+}
+
+func (x *LogFilter) GetFromTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FromTime
+	}
+	return nil
+}
+
+func (x *LogFilter) GetToTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ToTime
+	}
+	return nil
+}
+
+func (x *LogFilter) GetTxnHash() string {
+	if x != nil {
+		return x.TxnHash
+	}
+	return ""
+}
+
+// TopicFilter matches a topic against any of the values, or any topic when
+// there are none.
+type TopicFilter struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Values        []string               `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TopicFilter) Reset() {
+	*x = TopicFilter{}
+	mi := &file_blocktasks_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TopicFilter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TopicFilter) ProtoMessage() {}
+
+func (x *TopicFilter) ProtoReflect() protoreflect.Message {
+	mi := &file_blocktasks_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TopicFilter.ProtoReflect.Descriptor instead.
+func (*TopicFilter) Descriptor() ([]byte, []int) {
+	return file_blocktasks_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *TopicFilter) GetValues() []string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type Log struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	ChainId          int64                  `protobuf:"varint,1,opt,name=chain_id,json=chainId,proto3" json:"chain_id,omitempty"`
+	BlockNumber      int64                  `protobuf:"varint,2,opt,name=block_number,json=blockNumber,proto3" json:"block_number,omitempty"`
+	BlockHash        string                 `protobuf:"bytes,3,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
+	Address          string                 `protobuf:"bytes,4,opt,name=address,proto3" json:"address,omitempty"`
+	Data             string                 `protobuf:"bytes,5,opt,name=data,proto3" json:"data,omitempty"`
+	Topics           []string               `protobuf:"bytes,6,rep,name=topics,proto3" json:"topics,omitempty"`
+	TxnHash          string                 `protobuf:"bytes,7,opt,name=txn_hash,json=txnHash,proto3" json:"txn_hash,omitempty"`
+	TransactionIndex int64                  `protobuf:"varint,8,opt,name=transaction_index,json=transactionIndex,proto3" json:"transaction_index,omitempty"`
+	LogIndex         int64                  `protobuf:"varint,9,opt,name=log_index,json=logIndex,proto3" json:"log_index,omitempty"`
+	Removed          bool                   `protobuf:"varint,10,opt,name=removed,proto3" json:"removed,omitempty"`
+	BlockTimestamp   *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=block_timestamp,json=blockTimestamp,proto3" json:"block_timestamp,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *Log) Reset() {
+	*x = Log{}
+	mi := &file_blocktasks_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Log) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Log) ProtoMessage() {}
+
+func (x *Log) ProtoReflect() protoreflect.Message {
+	mi := &file_blocktasks_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Log.ProtoReflect.Descriptor instead.
+func (*Log) Descriptor() ([]byte, []int) {
+	return file_blocktasks_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Log) GetChainId() int64 {
+	if x != nil {
+		return x.ChainId
+	}
+	return 0
+}
+
+func (x *Log) GetBlockNumber() int64 {
+	if x != nil {
+		return x.BlockNumber
+	}
+	return 0
+}
+
+func (x *Log) GetBlockHash() string {
+	if x != nil {
+		return x.BlockHash
+	}
+	return ""
+}
+
+func (x *Log) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *Log) GetData() string {
+	if x != nil {
+		return x.Data
+	}
+	return ""
+}
+
+func (x *Log) GetTopics() []string {
+	if x != nil {
+		return x.Topics
+	}
+	return nil
+}
+
+func (x *Log) GetTxnHash() string {
+	if x != nil {
+		return x.TxnHash
+	}
+	return ""
+}
+
+func (x *Log) GetTransactionIndex() int64 {
+	if x != nil {
+		return x.TransactionIndex
+	}
+	return 0
+}
+
+func (x *Log) GetLogIndex() int64 {
+	if x != nil {
+		return x.LogIndex
+	}
+	return 0
+}
+
+func (x *Log) GetRemoved() bool {
+	if x != nil {
+		return x.Removed
+	}
+	return false
+}
+
+func (x *Log) GetBlockTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.BlockTimestamp
+	}
+	return nil
+}
+
+type QueryLogsRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Filter *LogFilter             `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	// the next_cursor of the previous page, empty for the first page
+	Cursor        string `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	Limit         int32  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryLogsRequest) Reset() {
+	*x = QueryLogsRequest{}
+	mi := &file_blocktasks_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryLogsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryLogsRequest) ProtoMessage() {}
+
+func (x *QueryLogsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_blocktasks_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryLogsRequest.ProtoReflect.Descriptor instead.
+func (*QueryLogsRequest) Descriptor() ([]byte, []int) {
+	return file_blocktasks_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *QueryLogsRequest) GetFilter() *LogFilter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *QueryLogsRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *QueryLogsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type QueryLogsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Logs  []*Log                 `protobuf:"bytes,1,rep,name=logs,proto3" json:"logs,omitempty"`
+	// empty on the last page
+	NextCursor    string `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryLogsResponse) Reset() {
+	*x = QueryLogsResponse{}
+	mi := &file_blocktasks_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryLogsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryLogsResponse) ProtoMessage() {}
+
+func (x *QueryLogsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_blocktasks_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryLogsResponse.ProtoReflect.Descriptor instead.
+func (*QueryLogsResponse) Descriptor() ([]byte, []int) {
+	return file_blocktasks_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *QueryLogsResponse) GetLogs() []*Log {
+	if x != nil {
+		return x.Logs
+	}
+	return nil
+}
+
+func (x *QueryLogsResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+type SubscribeLogsRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Filter *LogFilter             `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	// the cursor of the last log received, empty to start from the first log
+	Cursor        string `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeLogsRequest) Reset() {
+	*x = SubscribeLogsRequest{}
+	mi := &file_blocktasks_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeLogsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeLogsRequest) ProtoMessage() {}
+
+func (x *SubscribeLogsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_blocktasks_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeLogsRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeLogsRequest) Descriptor() ([]byte, []int) {
+	return file_blocktasks_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SubscribeLogsRequest) GetFilter() *LogFilter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *SubscribeLogsRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+type SubscribeLogsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Log   *Log                   `protobuf:"bytes,1,opt,name=log,proto3" json:"log,omitempty"`
+	// resumes the subscription after this log
+	Cursor        string `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeLogsResponse) Reset() {
+	*x = SubscribeLogsResponse{}
+	mi := &file_blocktasks_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeLogsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeLogsResponse) ProtoMessage() {}
+
+func (x *SubscribeLogsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_blocktasks_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeLogsResponse.ProtoReflect.Descriptor instead.
+func (*SubscribeLogsResponse) Descriptor() ([]byte, []int) {
+	return file_blocktasks_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SubscribeLogsResponse) GetLog() *Log {
+	if x != nil {
+		return x.Log
+	}
+	return nil
+}
+
+func (x *SubscribeLogsResponse) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+type GetTaskStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTaskStatusRequest) Reset() {
+	*x = GetTaskStatusRequest{}
+	mi := &file_blocktasks_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTaskStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTaskStatusRequest) ProtoMessage() {}
+
+func (x *GetTaskStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_blocktasks_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTaskStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetTaskStatusRequest) Descriptor() ([]byte, []int) {
+	return file_blocktasks_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetTaskStatusRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type GetTaskStatusResponse struct {
+	state                    protoimpl.MessageState `protogen:"open.v1"`
+	Name                     string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	LastProcessedBlockNumber int64                  `protobuf:"varint,2,opt,name=last_processed_block_number,json=lastProcessedBlockNumber,proto3" json:"last_processed_block_number,omitempty"`
+	LastProcessedBlockTime   *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=last_processed_block_time,json=lastProcessedBlockTime,proto3" json:"last_processed_block_time,omitempty"`
+	// the instance holding the lease
+	Owner          string                 `protobuf:"bytes,4,opt,name=owner,proto3" json:"owner,omitempty"`
+	LeaseExpiresAt *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=lease_expires_at,json=leaseExpiresAt,proto3" json:"lease_expires_at,omitempty"`
+	FencingToken   int64                  `protobuf:"varint,6,opt,name=fencing_token,json=fencingToken,proto3" json:"fencing_token,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *GetTaskStatusResponse) Reset() {
+	*x = GetTaskStatusResponse{}
+	mi := &file_blocktasks_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTaskStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTaskStatusResponse) ProtoMessage() {}
+
+func (x *GetTaskStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_blocktasks_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTaskStatusResponse.ProtoReflect.Descriptor instead.
+func (*GetTaskStatusResponse) Descriptor() ([]byte, []int) {
+	return file_blocktasks_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetTaskStatusResponse) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *GetTaskStatusResponse) GetLastProcessedBlockNumber() int64 {
+	if x != nil {
+		return x.LastProcessedBlockNumber
+	}
+	return 0
+}
+
+func (x *GetTaskStatusResponse) GetLastProcessedBlockTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastProcessedBlockTime
+	}
+	return nil
+}
+
+func (x *GetTaskStatusResponse) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *GetTaskStatusResponse) GetLeaseExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LeaseExpiresAt
+	}
+	return nil
+}
+
+func (x *GetTaskStatusResponse) GetFencingToken() int64 {
+	if x != nil {
+		return x.FencingToken
+	}
+	return 0
+}
+
+var File_blocktasks_proto protoreflect.FileDescriptor
+
+const file_blocktasks_proto_rawDesc = "" +
+	"\n" +
+	"\x10blocktasks.proto\x12\rblocktasks.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xd5\x02\n" +
+	"\tLogFilter\x12\x19\n" +
+	"\bchain_id\x18\x01 \x01(\x03R\achainId\x12\x1c\n" +
+	"\taddresses\x18\x02 \x03(\tR\taddresses\x122\n" +
+	"\x06topics\x18\x03 \x03(\v2\x1a.blocktasks.v1.TopicFilterR\x06topics\x12*\n" +
+	"\x11from_block_number\x18\x04 \x01(\x03R\x0ffromBlockNumber\x12&\n" +
+	"\x0fto_block_number\x18\x05 \x01(\x03R\rtoBlockNumber\x127\n" +
+	"\tfrom_time\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\bfromTime\x123\n" +
+	"\ato_time\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\x06toTime\x12\x19\n" +
+	"\btxn_hash\x18\b \x01(\tR\atxnHash\"%\n" +
+	"\vTopicFilter\x12\x16\n" +
+	"\x06values\x18\x01 \x03(\tR\x06values\"\xec\x02\n" +
+	"\x03Log\x12\x19\n" +
+	"\bchain_id\x18\x01 \x01(\x03R\achainId\x12!\n" +
+	"\fblock_number\x18\x02 \x01(\x03R\vblockNumber\x12\x1d\n" +
+	"\n" +
+	"block_hash\x18\x03 \x01(\tR\tblockHash\x12\x18\n" +
+	"\aaddress\x18\x04 \x01(\tR\aaddress\x12\x12\n" +
+	"\x04data\x18\x05 \x01(\tR\x04data\x12\x16\n" +
+	"\x06topics\x18\x06 \x03(\tR\x06topics\x12\x19\n" +
+	"\btxn_hash\x18\a \x01(\tR\atxnHash\x12+\n" +
+	"\x11transaction_index\x18\b \x01(\x03R\x10transactionIndex\x12\x1b\n" +
+	"\tlog_index\x18\t \x01(\x03R\blogIndex\x12\x18\n" +
+	"\aremoved\x18\n" +
+	" \x01(\bR\aremoved\x12C\n" +
+	"\x0fblock_timestamp\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\x0eblockTimestamp\"r\n" +
+	"\x10QueryLogsRequest\x120\n" +
+	"\x06filter\x18\x01 \x01(\v2\x18.blocktasks.v1.LogFilterR\x06filter\x12\x16\n" +
+	"\x06cursor\x18\x02 \x01(\tR\x06cursor\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\"\\\n" +
+	"\x11QueryLogsResponse\x12&\n" +
+	"\x04logs\x18\x01 \x03(\v2\x12.blocktasks.v1.LogR\x04logs\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\tR\n" +
+	"nextCursor\"`\n" +
+	"\x14SubscribeLogsRequest\x120\n" +
+	"\x06filter\x18\x01 \x01(\v2\x18.blocktasks.v1.LogFilterR\x06filter\x12\x16\n" +
+	"\x06cursor\x18\x02 \x01(\tR\x06cursor\"U\n" +
+	"\x15SubscribeLogsResponse\x12$\n" +
+	"\x03log\x18\x01 \x01(\v2\x12.blocktasks.v1.LogR\x03log\x12\x16\n" +
+	"\x06cursor\x18\x02 \x01(\tR\x06cursor\"*\n" +
+	"\x14GetTaskStatusRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"\xc2\x02\n" +
+	"\x15GetTaskStatusResponse\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12=\n" +
+	"\x1blast_processed_block_number\x18\x02 \x01(\x03R\x18lastProcessedBlockNumber\x12U\n" +
+	"\x19last_processed_block_time\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x16lastProcessedBlockTime\x12\x14\n" +
+	"\x05owner\x18\x04 \x01(\tR\x05owner\x12D\n" +
+	"\x10lease_expires_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x0eleaseExpiresAt\x12#\n" +
+	"\rfencing_token\x18\x06 \x01(\x03R\ffencingToken2\x9d\x02\n" +
+	"\x11BlocktasksService\x12N\n" +
+	"\tQueryLogs\x12\x1f.blocktasks.v1.QueryLogsRequest\x1a .blocktasks.v1.QueryLogsResponse\x12\\\n" +
+	"\rSubscribeLogs\x12#.blocktasks.v1.SubscribeLogsRequest\x1a$.blocktasks.v1.SubscribeLogsResponse0\x01\x12Z\n" +
+	"\rGetTaskStatus\x12#.blocktasks.v1.GetTaskStatusRequest\x1a$.blocktasks.v1.GetTaskStatusResponseB*Z(github.com/waynewu411/blocktasks/pkg/apib\x06proto3"
+
+var (
+	file_blocktasks_proto_rawDescOnce sync.Once
+	file_blocktasks_proto_rawDescData []byte
+)
+
+func file_blocktasks_proto_rawDescGZIP() []byte {
+	file_blocktasks_proto_rawDescOnce.Do(func() {
+		file_blocktasks_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_blocktasks_proto_rawDesc), len(file_blocktasks_proto_rawDesc)))
+	})
+	return file_blocktasks_proto_rawDescData
+}
+
+var file_blocktasks_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_blocktasks_proto_goTypes = []any{
+	(*LogFilter)(nil),             // 0: blocktasks.v1.LogFilter
+	(*TopicFilter)(nil),           // 1: blocktasks.v1.TopicFilter
+	(*Log)(nil),                   // 2: blocktasks.v1.Log
+	(*QueryLogsRequest)(nil),      // 3: blocktasks.v1.QueryLogsRequest
+	(*QueryLogsResponse)(nil),     // 4: blocktasks.v1.QueryLogsResponse
+	(*SubscribeLogsRequest)(nil),  // 5: blocktasks.v1.SubscribeLogsRequest
+	(*SubscribeLogsResponse)(nil), // 6: blocktasks.v1.SubscribeLogsResponse
+	(*GetTaskStatusRequest)(nil),  // 7: blocktasks.v1.GetTaskStatusRequest
+	(*GetTaskStatusResponse)(nil), // 8: blocktasks.v1.GetTaskStatusResponse
+	(*timestamppb.Timestamp)(nil), // 9: google.protobuf.Timestamp
+}
+var file_blocktasks_proto_depIdxs = []int32{
+	1,  // 0: blocktasks.v1.LogFilter.topics:type_name -> blocktasks.v1.TopicFilter
+	9,  // 1: blocktasks.v1.LogFilter.from_time:type_name -> google.protobuf.Timestamp
+	9,  // 2: blocktasks.v1.LogFilter.to_time:type_name -> google.protobuf.Timestamp
+	9,  // 3: blocktasks.v1.Log.block_timestamp:type_name -> google.protobuf.Timestamp
+	0,  // 4: blocktasks.v1.QueryLogsRequest.filter:type_name -> blocktasks.v1.LogFilter
+	2,  // 5: blocktasks.v1.QueryLogsResponse.logs:type_name -> blocktasks.v1.Log
+	0,  // 6: blocktasks.v1.SubscribeLogsRequest.filter:type_name -> blocktasks.v1.LogFilter
+	2,  // 7: blocktasks.v1.SubscribeLogsResponse.log:type_name -> blocktasks.v1.Log
+	9,  // 8: blocktasks.v1.GetTaskStatusResponse.last_processed_block_time:type_name -> google.protobuf.Timestamp
+	9,  // 9: blocktasks.v1.GetTaskStatusResponse.lease_expires_at:type_name -> google.protobuf.Timestamp
+	3,  // 10: blocktasks.v1.BlocktasksService.QueryLogs:input_type -> blocktasks.v1.QueryLogsRequest
+	5,  // 11: blocktasks.v1.BlocktasksService.SubscribeLogs:input_type -> blocktasks.v1.SubscribeLogsRequest
+	7,  // 12: blocktasks.v1.BlocktasksService.GetTaskStatus:input_type -> blocktasks.v1.GetTaskStatusRequest
+	4,  // 13: blocktasks.v1.BlocktasksService.QueryLogs:output_type -> blocktasks.v1.QueryLogsResponse
+	6,  // 14: blocktasks.v1.BlocktasksService.SubscribeLogs:output_type -> blocktasks.v1.SubscribeLogsResponse
+	8,  // 15: blocktasks.v1.BlocktasksService.GetTaskStatus:output_type -> blocktasks.v1.GetTaskStatusResponse
+	13, // [13:16] is the sub-list for method output_type
+	10, // [10:13] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_blocktasks_proto_init() }
+func file_blocktasks_proto_init() {
+	if File_blocktasks_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_blocktasks_proto_rawDesc), len(file_blocktasks_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_blocktasks_proto_goTypes,
+		DependencyIndexes: file_blocktasks_proto_depIdxs,
+		MessageInfos:      file_blocktasks_proto_msgTypes,
+	}.Build()
+	File_blocktasks_proto = out.File
+	file_blocktasks_proto_goTypes = nil
+	file_blocktasks_proto_depIdxs = nil
+}
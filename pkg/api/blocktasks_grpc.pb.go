@@ -0,0 +1,217 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: blocktasks.proto
+
+package api
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	BlocktasksService_QueryLogs_FullMethodName     = "/blocktasks.v1.BlocktasksService/QueryLogs"
+	BlocktasksService_SubscribeLogs_FullMethodName = "/blocktasks.v1.BlocktasksService/SubscribeLogs"
+	BlocktasksService_GetTaskStatus_FullMethodName = "/blocktasks.v1.BlocktasksService/GetTaskStatus"
+)
+
+// BlocktasksServiceClient is the client API for BlocktasksService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// BlocktasksService serves the logs indexed by the tasks and their
+// checkpoints.
+type BlocktasksServiceClient interface {
+	// QueryLogs returns a page of the logs matching the filter, in the order of
+	// chain, block number, log index and transaction hash.
+	QueryLogs(ctx context.Context, in *QueryLogsRequest, opts ...grpc.CallOption) (*QueryLogsResponse, error)
+	// SubscribeLogs streams the logs matching the filter after the cursor, then
+	// the logs committed later. The chain of the filter is required.
+	SubscribeLogs(ctx context.Context, in *SubscribeLogsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SubscribeLogsResponse], error)
+	// GetTaskStatus returns the checkpoint and the lease of a task.
+	GetTaskStatus(ctx context.Context, in *GetTaskStatusRequest, opts ...grpc.CallOption) (*GetTaskStatusResponse, error)
+}
+
+type blocktasksServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBlocktasksServiceClient(cc grpc.ClientConnInterface) BlocktasksServiceClient {
+	return &blocktasksServiceClient{cc}
+}
+
+func (c *blocktasksServiceClient) QueryLogs(ctx context.Context, in *QueryLogsRequest, opts ...grpc.CallOption) (*QueryLogsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QueryLogsResponse)
+	err := c.cc.Invoke(ctx, BlocktasksService_QueryLogs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blocktasksServiceClient) SubscribeLogs(ctx context.Context, in *SubscribeLogsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SubscribeLogsResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &BlocktasksService_ServiceDesc.Streams[0], BlocktasksService_SubscribeLogs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeLogsRequest, SubscribeLogsResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BlocktasksService_SubscribeLogsClient = grpc.ServerStreamingClient[SubscribeLogsResponse]
+
+func (c *blocktasksServiceClient) GetTaskStatus(ctx context.Context, in *GetTaskStatusRequest, opts ...grpc.CallOption) (*GetTaskStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetTaskStatusResponse)
+	err := c.cc.Invoke(ctx, BlocktasksService_GetTaskStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BlocktasksServiceServer is the server API for BlocktasksService service.
+// All implementations must embed UnimplementedBlocktasksServiceServer
+// for forward compatibility.
+//
+// BlocktasksService serves the logs indexed by the tasks and their
+// checkpoints.
+type BlocktasksServiceServer interface {
+	// QueryLogs returns a page of the logs matching the filter, in the order of
+	// chain, block number, log index and transaction hash.
+	QueryLogs(context.Context, *QueryLogsRequest) (*QueryLogsResponse, error)
+	// SubscribeLogs streams the logs matching the filter after the cursor, then
+	// the logs committed later. The chain of the filter is required.
+	SubscribeLogs(*SubscribeLogsRequest, grpc.ServerStreamingServer[SubscribeLogsResponse]) error
+	// GetTaskStatus returns the checkpoint and the lease of a task.
+	GetTaskStatus(context.Context, *GetTaskStatusRequest) (*GetTaskStatusResponse, error)
+	mustEmbedUnimplementedBlocktasksServiceServer()
+}
+
+// UnimplementedBlocktasksServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedBlocktasksServiceServer struct{}
+
+func (UnimplementedBlocktasksServiceServer) QueryLogs(context.Context, *QueryLogsRequest) (*QueryLogsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryLogs not implemented")
+}
+func (UnimplementedBlocktasksServiceServer) SubscribeLogs(*SubscribeLogsRequest, grpc.ServerStreamingServer[SubscribeLogsResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeLogs not implemented")
+}
+func (UnimplementedBlocktasksServiceServer) GetTaskStatus(context.Context, *GetTaskStatusRequest) (*GetTaskStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTaskStatus not implemented")
+}
+func (UnimplementedBlocktasksServiceServer) mustEmbedUnimplementedBlocktasksServiceServer() {}
+func (UnimplementedBlocktasksServiceServer) testEmbeddedByValue()                           {}
+
+// UnsafeBlocktasksServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BlocktasksServiceServer will
+// result in compilation errors.
+type UnsafeBlocktasksServiceServer interface {
+	mustEmbedUnimplementedBlocktasksServiceServer()
+}
+
+func RegisterBlocktasksServiceServer(s grpc.ServiceRegistrar, srv BlocktasksServiceServer) {
+	// If the following call pancis, it indicates UnimplementedBlocktasksServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&BlocktasksService_ServiceDesc, srv)
+}
+
+func _BlocktasksService_QueryLogs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryLogsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlocktasksServiceServer).QueryLogs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlocktasksService_QueryLogs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlocktasksServiceServer).QueryLogs(ctx, req.(*QueryLogsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlocktasksService_SubscribeLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeLogsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BlocktasksServiceServer).SubscribeLogs(m, &grpc.GenericServerStream[SubscribeLogsRequest, SubscribeLogsResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BlocktasksService_SubscribeLogsServer = grpc.ServerStreamingServer[SubscribeLogsResponse]
+
+func _BlocktasksService_GetTaskStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTaskStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlocktasksServiceServer).GetTaskStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlocktasksService_GetTaskStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlocktasksServiceServer).GetTaskStatus(ctx, req.(*GetTaskStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// BlocktasksService_ServiceDesc is the grpc.ServiceDesc for BlocktasksService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BlocktasksService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "blocktasks.v1.BlocktasksService",
+	HandlerType: (*BlocktasksServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "QueryLogs",
+			Handler:    _BlocktasksService_QueryLogs_Handler,
+		},
+		{
+			MethodName: "GetTaskStatus",
+			Handler:    _BlocktasksService_GetTaskStatus_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeLogs",
+			Handler:       _BlocktasksService_SubscribeLogs_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "blocktasks.proto",
+}
@@ -0,0 +1,5 @@
+// Package api is the gRPC API of blocktasks, with the messages and the
+// client generated from blocktasks.proto.
+package api
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative blocktasks.proto
@@ -12,27 +12,32 @@ import (
 
 	"github.com/samber/lo"
 	"github.com/waynewu411/blocktasks/pkg/config"
+	"github.com/waynewu411/blocktasks/pkg/metrics"
 	"github.com/waynewu411/blocktasks/pkg/request"
 	"github.com/waynewu411/blocktasks/pkg/request/jsonrpc"
 	"go.uber.org/zap"
 )
 
 type BaseBlockWithFullTxns struct {
-	BlockNumber string    `json:"number"`
-	BlockHash   string    `json:"hash"`
-	Txns        []BaseTxn `json:"transactions"`
-	GasLimit    string    `json:"gasLimit"`
-	GasUsed     string    `json:"gasUsed"`
-	Timestamp   string    `json:"timestamp"`
+	BlockNumber   string    `json:"number"`
+	BlockHash     string    `json:"hash"`
+	ParentHash    string    `json:"parentHash"`
+	Txns          []BaseTxn `json:"transactions"`
+	GasLimit      string    `json:"gasLimit"`
+	GasUsed       string    `json:"gasUsed"`
+	BaseFeePerGas string    `json:"baseFeePerGas"`
+	Timestamp     string    `json:"timestamp"`
 }
 
 type BaseBlockWithoutFullTxns struct {
-	BlockNumber string   `json:"number"`
-	BlockHash   string   `json:"hash"`
-	Txns        []string `json:"transactions"`
-	GasLimit    string   `json:"gasLimit"`
-	GasUsed     string   `json:"gasUsed"`
-	Timestamp   string   `json:"timestamp"`
+	BlockNumber   string   `json:"number"`
+	BlockHash     string   `json:"hash"`
+	ParentHash    string   `json:"parentHash"`
+	Txns          []string `json:"transactions"`
+	GasLimit      string   `json:"gasLimit"`
+	GasUsed       string   `json:"gasUsed"`
+	BaseFeePerGas string   `json:"baseFeePerGas"`
+	Timestamp     string   `json:"timestamp"`
 }
 
 type BaseTxn struct {
@@ -65,6 +70,14 @@ type BaseLog struct {
 	TxnIndex    string   `json:"transactionIndex"`
 }
 
+type BaseReceipt struct {
+	BlockHash   string    `json:"blockHash"`
+	BlockNumber string    `json:"blockNumber"`
+	TxnHash     string    `json:"transactionHash"`
+	TxnIndex    string    `json:"transactionIndex"`
+	Logs        []BaseLog `json:"logs"`
+}
+
 type getLogsParam struct {
 	Addresses       []string `json:"address"`
 	FromBlockNumber string   `json:"fromBlock"`
@@ -73,19 +86,54 @@ type getLogsParam struct {
 }
 
 type BaseChain struct {
-	lg      *zap.Logger
-	cfg     config.ChainConfig
-	request request.Request
+	lg       *zap.Logger
+	cfg      config.ChainConfig
+	request  request.Request
+	endpoint string // label of the metrics, without the api key
 }
 
 func NewBaseChain(lg *zap.Logger, cfg config.ChainConfig, request request.Request) Chain {
-	return &BaseChain{lg: lg, cfg: cfg, request: request}
+	return &BaseChain{lg: lg, cfg: cfg, request: request, endpoint: metrics.Endpoint(cfg.ApiEndpoint)}
 }
 
 func (b *BaseChain) getApiUrl() string {
 	return fmt.Sprintf("%s/%s", b.cfg.ApiEndpoint, b.cfg.ApiKey)
 }
 
+// makeRequest posts the body and records the request in the metrics, where
+// a batch is counted under method.
+func (b *BaseChain) makeRequest(ctx context.Context, method string, reqBody []byte) ([]byte, error) {
+	ctx = metrics.StartRpcRequest(ctx)
+	response, err := b.request.MakeRequest(
+		ctx,
+		http.MethodPost,
+		b.getApiUrl(),
+		map[string]string{},
+		string(reqBody),
+	)
+	metrics.ObserveRpcRequest(ctx, method, b.endpoint)
+	if err != nil {
+		return nil, b.rpcError(method, err)
+	}
+	return response, nil
+}
+
+// rpcError records the error of a request, which may also be an error
+// answered by the provider in the response, and returns it.
+func (b *BaseChain) rpcError(method string, err error) error {
+	code := "transport"
+	var statusErr *request.StatusError
+	var rpcErr *jsonrpc.Error
+	switch {
+	case errors.As(err, &statusErr):
+		code = strconv.Itoa(statusErr.StatusCode)
+	case errors.As(err, &rpcErr):
+		code = strconv.FormatInt(rpcErr.Code, 10)
+	}
+	metrics.RpcErrors.WithLabelValues(method, b.endpoint, code).Inc()
+	return err
+}
+
 func (b *BaseChain) GetChainId() int64 {
 	return ChainIdBaseMainnet
 }
@@ -119,12 +167,7 @@ func (b *BaseChain) GetBlockByNumber(ctx context.Context, blockNumber int64, ful
 		return Block{}, err
 	}
 
-	response, err := b.request.MakeRequest(
-		http.MethodPost,
-		b.getApiUrl(),
-		map[string]string{},
-		string(reqBody),
-	)
+	response, err := b.makeRequest(ctx, "eth_getBlockByNumber", reqBody)
 	if err != nil {
 		return Block{}, err
 	}
@@ -134,6 +177,9 @@ func (b *BaseChain) GetBlockByNumber(ctx context.Context, blockNumber int64, ful
 		if err := json.Unmarshal(response, &respBody); err != nil {
 			return Block{}, err
 		}
+		if respBody.Error != nil {
+			return Block{}, b.rpcError("eth_getBlockByNumber", respBody.Error)
+		}
 
 		blockNumber, err = strconv.ParseInt(strings.TrimPrefix(respBody.Result.BlockNumber, "0x"), 16, 64)
 		if err != nil {
@@ -147,21 +193,18 @@ func (b *BaseChain) GetBlockByNumber(ctx context.Context, blockNumber int64, ful
 		timestamp *= 1000
 
 		block := Block{
-			BlockNumber: blockNumber,
-			BlockHash:   respBody.Result.BlockHash,
-			Timestamp:   timestamp,
+			ChainId:       b.GetChainId(),
+			BlockNumber:   blockNumber,
+			BlockHash:     respBody.Result.BlockHash,
+			ParentHash:    respBody.Result.ParentHash,
+			Timestamp:     timestamp,
+			GasLimit:      parseQuantity(respBody.Result.GasLimit),
+			GasUsed:       parseQuantity(respBody.Result.GasUsed),
+			BaseFeePerGas: respBody.Result.BaseFeePerGas,
 		}
 
 		block.Txns = lo.Map(respBody.Result.Txns, func(txn BaseTxn, _ int) Txn {
-			return Txn{
-				BlockHash:   txn.BlockHash,
-				BlockNumber: txn.BlockNumber,
-				TxnHash:     txn.TxnHash,
-				Type:        txn.Type,
-				From:        txn.From,
-				To:          txn.To,
-				Value:       txn.Value,
-			}
+			return newTxn(txn)
 		})
 
 		return block, nil
@@ -171,6 +214,9 @@ func (b *BaseChain) GetBlockByNumber(ctx context.Context, blockNumber int64, ful
 	if err := json.Unmarshal(response, &respBody); err != nil {
 		return Block{}, err
 	}
+	if respBody.Error != nil {
+		return Block{}, b.rpcError("eth_getBlockByNumber", respBody.Error)
+	}
 
 	blockNumber, err = strconv.ParseInt(strings.TrimPrefix(respBody.Result.BlockNumber, "0x"), 16, 64)
 	if err != nil {
@@ -184,10 +230,14 @@ func (b *BaseChain) GetBlockByNumber(ctx context.Context, blockNumber int64, ful
 	timestamp *= 1000
 
 	block := Block{
-		ChainId:     b.GetChainId(),
-		BlockNumber: blockNumber,
-		BlockHash:   respBody.Result.BlockHash,
-		Timestamp:   timestamp,
+		ChainId:       b.GetChainId(),
+		BlockNumber:   blockNumber,
+		BlockHash:     respBody.Result.BlockHash,
+		ParentHash:    respBody.Result.ParentHash,
+		Timestamp:     timestamp,
+		GasLimit:      parseQuantity(respBody.Result.GasLimit),
+		GasUsed:       parseQuantity(respBody.Result.GasUsed),
+		BaseFeePerGas: respBody.Result.BaseFeePerGas,
 	}
 
 	block.TxnHashes = respBody.Result.Txns
@@ -195,6 +245,10 @@ func (b *BaseChain) GetBlockByNumber(ctx context.Context, blockNumber int64, ful
 	return block, nil
 }
 
+func (b *BaseChain) GetBlockByTimestamp(ctx context.Context, timestamp int64) (Block, error) {
+	return getBlockByTimestamp(ctx, b, timestamp)
+}
+
 func (b *BaseChain) GetBlocks(ctx context.Context, fromBlockNumber int64, toBlockNumber int64, fullTxns bool, includeLogs bool, addresses []string, topics []string) ([]Block, error) {
 	// use alchemy batch request to get the event in blocks
 	// and the information of all the blocks in one request
@@ -202,7 +256,10 @@ func (b *BaseChain) GetBlocks(ctx context.Context, fromBlockNumber int64, toBloc
 	// The purpose of getting block information is to populate the timestamp
 	req := make([]jsonrpc.Request, 0)
 	id := int64(0)
+	// the batch is counted as eth_getLogs in the metrics when it has logs
+	method := "eth_getBlockByNumber"
 	if includeLogs {
+		method = "eth_getLogs"
 		id++
 		req = append(req, jsonrpc.Request{
 			Method: "eth_getLogs",
@@ -235,24 +292,28 @@ func (b *BaseChain) GetBlocks(ctx context.Context, fromBlockNumber int64, toBloc
 		return nil, err
 	}
 
-	response, err := b.request.MakeRequest(
-		http.MethodPost,
-		b.getApiUrl(),
-		map[string]string{},
-		string(reqBody),
-	)
+	response, err := b.makeRequest(ctx, method, reqBody)
 	if err != nil {
 		return nil, err
 	}
 
 	var respBody []jsonrpc.Response[json.RawMessage]
 	if err := json.Unmarshal(response, &respBody); err != nil {
+		// a rejected batch is answered with a single error response
+		var errBody jsonrpc.Response[json.RawMessage]
+		if json.Unmarshal(response, &errBody) == nil && errBody.Error != nil {
+			return nil, b.rpcError(method, errBody.Error)
+		}
 		return nil, err
 	}
 
 	blocks := make([]Block, 0)
 	var logs []Log
 	for _, resp := range respBody {
+		if resp.Error != nil {
+			return nil, b.rpcError(method, resp.Error)
+		}
+
 		// Unmarshal as block first
 		// because there will be multiple entries for blocks
 		// and only one entry for logs
@@ -270,22 +331,18 @@ func (b *BaseChain) GetBlocks(ctx context.Context, fromBlockNumber int64, toBloc
 				}
 				blockTimestamp *= 1000
 				txns := lo.Map(baseBlock.Txns, func(txn BaseTxn, _ int) Txn {
-					return Txn{
-						BlockHash:   txn.BlockHash,
-						BlockNumber: txn.BlockNumber,
-						TxnHash:     txn.TxnHash,
-						Type:        txn.Type,
-						From:        txn.From,
-						To:          txn.To,
-						Value:       txn.Value,
-					}
+					return newTxn(txn)
 				})
 				blocks = append(blocks, Block{
-					ChainId:     b.GetChainId(),
-					BlockNumber: blockNumber,
-					BlockHash:   baseBlock.BlockHash,
-					Timestamp:   blockTimestamp,
-					Txns:        txns,
+					ChainId:       b.GetChainId(),
+					BlockNumber:   blockNumber,
+					BlockHash:     baseBlock.BlockHash,
+					ParentHash:    baseBlock.ParentHash,
+					Timestamp:     blockTimestamp,
+					GasLimit:      parseQuantity(baseBlock.GasLimit),
+					GasUsed:       parseQuantity(baseBlock.GasUsed),
+					BaseFeePerGas: baseBlock.BaseFeePerGas,
+					Txns:          txns,
 				})
 				continue
 			}
@@ -303,11 +360,15 @@ func (b *BaseChain) GetBlocks(ctx context.Context, fromBlockNumber int64, toBloc
 				}
 				blockTimestamp *= 1000
 				blocks = append(blocks, Block{
-					ChainId:     b.GetChainId(),
-					BlockNumber: blockNumber,
-					BlockHash:   baseBlock.BlockHash,
-					Timestamp:   blockTimestamp,
-					TxnHashes:   baseBlock.Txns,
+					ChainId:       b.GetChainId(),
+					BlockNumber:   blockNumber,
+					BlockHash:     baseBlock.BlockHash,
+					ParentHash:    baseBlock.ParentHash,
+					Timestamp:     blockTimestamp,
+					GasLimit:      parseQuantity(baseBlock.GasLimit),
+					GasUsed:       parseQuantity(baseBlock.GasUsed),
+					BaseFeePerGas: baseBlock.BaseFeePerGas,
+					TxnHashes:     baseBlock.Txns,
 				})
 				continue
 			}
@@ -319,24 +380,7 @@ func (b *BaseChain) GetBlocks(ctx context.Context, fromBlockNumber int64, toBloc
 			continue
 		}
 		logs = lo.Map(baseLogs, func(log BaseLog, _ int) Log {
-			blockNumber, err := strconv.ParseInt(strings.TrimPrefix(log.BlockNumber, "0x"), 16, 64)
-			if err != nil {
-				return Log{}
-			}
-			logIndex, err := strconv.ParseInt(strings.TrimPrefix(log.LogIndex, "0x"), 16, 64)
-			if err != nil {
-				return Log{}
-			}
-			return Log{
-				Address:     log.Address,
-				BlockNumber: blockNumber,
-				BlockHash:   log.BlockHash,
-				Data:        log.Data,
-				Topics:      log.Topics,
-				TxnHash:     log.TxnHash,
-				LogIndex:    logIndex,
-				Removed:     log.Removed,
-			}
+			return newLog(log)
 		})
 	}
 
@@ -358,3 +402,148 @@ func (b *BaseChain) GetBlocks(ctx context.Context, fromBlockNumber int64, toBloc
 
 	return blocks, nil
 }
+
+func (b *BaseChain) GetTransactionReceipts(ctx context.Context, txnHashes []string) (map[string]Receipt, error) {
+	receipts := make(map[string]Receipt, len(txnHashes))
+	if len(txnHashes) == 0 {
+		return receipts, nil
+	}
+
+	req := lo.Map(txnHashes, func(txnHash string, i int) jsonrpc.Request {
+		return jsonrpc.Request{
+			Method:  "eth_getTransactionReceipt",
+			Params:  []any{txnHash},
+			Id:      int64(i + 1),
+			JsonRpc: "2.0",
+		}
+	})
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := b.makeRequest(ctx, "eth_getTransactionReceipt", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var respBody []jsonrpc.Response[*BaseReceipt]
+	if err := json.Unmarshal(response, &respBody); err != nil {
+		var errBody jsonrpc.Response[json.RawMessage]
+		if json.Unmarshal(response, &errBody) == nil && errBody.Error != nil {
+			return nil, b.rpcError("eth_getTransactionReceipt", errBody.Error)
+		}
+		return nil, err
+	}
+
+	for _, resp := range respBody {
+		if resp.Error != nil {
+			return nil, b.rpcError("eth_getTransactionReceipt", resp.Error)
+		}
+		// the receipt is null for a transaction which is unknown to the node
+		if resp.Result == nil {
+			continue
+		}
+
+		blockNumber, err := strconv.ParseInt(strings.TrimPrefix(resp.Result.BlockNumber, "0x"), 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		txnIndex, err := strconv.ParseInt(strings.TrimPrefix(resp.Result.TxnIndex, "0x"), 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		receipts[resp.Result.TxnHash] = Receipt{
+			TxnHash:     resp.Result.TxnHash,
+			TxnIndex:    txnIndex,
+			BlockNumber: blockNumber,
+			BlockHash:   resp.Result.BlockHash,
+			Logs: lo.Map(resp.Result.Logs, func(log BaseLog, _ int) Log {
+				return newLog(log)
+			}),
+		}
+	}
+
+	return receipts, nil
+}
+
+// Call sends a single request to the provider and returns its raw result.
+// An error answered by the provider is returned as *jsonrpc.Error.
+func (b *BaseChain) Call(ctx context.Context, method string, params []any) (json.RawMessage, error) {
+	reqBody, err := json.Marshal(jsonrpc.Request{
+		Method:  method,
+		Params:  params,
+		Id:      1,
+		JsonRpc: "2.0",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := b.makeRequest(ctx, method, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var respBody jsonrpc.Response[json.RawMessage]
+	if err := json.Unmarshal(response, &respBody); err != nil {
+		return nil, err
+	}
+	if respBody.Error != nil {
+		return nil, b.rpcError(method, respBody.Error)
+	}
+	return respBody.Result, nil
+}
+
+func newLog(log BaseLog) Log {
+	blockNumber, err := strconv.ParseInt(strings.TrimPrefix(log.BlockNumber, "0x"), 16, 64)
+	if err != nil {
+		return Log{}
+	}
+	logIndex, err := strconv.ParseInt(strings.TrimPrefix(log.LogIndex, "0x"), 16, 64)
+	if err != nil {
+		return Log{}
+	}
+	txnIndex, err := strconv.ParseInt(strings.TrimPrefix(log.TxnIndex, "0x"), 16, 64)
+	if err != nil {
+		return Log{}
+	}
+	return Log{
+		Address:     log.Address,
+		BlockNumber: blockNumber,
+		BlockHash:   log.BlockHash,
+		Data:        log.Data,
+		Topics:      log.Topics,
+		TxnHash:     log.TxnHash,
+		TxnIndex:    txnIndex,
+		LogIndex:    logIndex,
+		Removed:     log.Removed,
+	}
+}
+
+func newTxn(txn BaseTxn) Txn {
+	return Txn{
+		BlockHash:   txn.BlockHash,
+		BlockNumber: txn.BlockNumber,
+		TxnHash:     txn.TxnHash,
+		TxnIndex:    parseQuantity(txn.TxnIndex),
+		Type:        txn.Type,
+		Nonce:       txn.Nonce,
+		From:        txn.From,
+		To:          txn.To,
+		Value:       txn.Value,
+		Gas:         txn.Gas,
+		GasPrice:    txn.GasPrice,
+		Input:       txn.Input,
+	}
+}
+
+// parseQuantity parses a hex quantity which fits in int64, returning 0 when
+// the field is missing or malformed.
+func parseQuantity(s string) int64 {
+	n, err := strconv.ParseInt(strings.TrimPrefix(s, "0x"), 16, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
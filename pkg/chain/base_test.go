@@ -2,13 +2,20 @@ package chain
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 	"github.com/waynewu411/blocktasks/pkg/config"
+	"github.com/waynewu411/blocktasks/pkg/metrics"
 	"github.com/waynewu411/blocktasks/pkg/request"
+	"github.com/waynewu411/blocktasks/pkg/request/jsonrpc"
 	"go.uber.org/mock/gomock"
 	"go.uber.org/zap"
 )
@@ -21,6 +28,7 @@ func TestBase_GetBlocksWithFullTxnsAndLogs(t *testing.T) {
 	require.NoError(t, err)
 
 	request.EXPECT().MakeRequest(
+		gomock.Any(),
 		http.MethodPost,
 		gomock.Any(),
 		gomock.Any(),
@@ -35,6 +43,9 @@ func TestBase_GetBlocksWithFullTxnsAndLogs(t *testing.T) {
 		require.NotEmpty(t, block.Txns)
 		require.Empty(t, block.TxnHashes)
 		require.NotEmpty(t, block.Logs)
+		for _, log := range block.Logs {
+			require.NotEmpty(t, log.Address)
+		}
 	}
 }
 
@@ -46,6 +57,7 @@ func TestBase_GetBlocksWithoutFullTxnsAndWithLogs(t *testing.T) {
 	require.NoError(t, err)
 
 	request.EXPECT().MakeRequest(
+		gomock.Any(),
 		http.MethodPost,
 		gomock.Any(),
 		gomock.Any(),
@@ -71,6 +83,7 @@ func TestBase_GetBlocksWithFullTxnsAndWithoutLogs(t *testing.T) {
 	require.NoError(t, err)
 
 	request.EXPECT().MakeRequest(
+		gomock.Any(),
 		http.MethodPost,
 		gomock.Any(),
 		gomock.Any(),
@@ -96,6 +109,7 @@ func TestBase_GetBlocksWithoutFullTxnsAndWithoutLogs(t *testing.T) {
 	require.NoError(t, err)
 
 	request.EXPECT().MakeRequest(
+		gomock.Any(),
 		http.MethodPost,
 		gomock.Any(),
 		gomock.Any(),
@@ -112,3 +126,132 @@ func TestBase_GetBlocksWithoutFullTxnsAndWithoutLogs(t *testing.T) {
 		require.Empty(t, block.Logs)
 	}
 }
+
+func TestBase_GetBlockByTimestamp(t *testing.T) {
+	request := request.NewMockRequest(gomock.NewController(t))
+	base := NewBaseChain(zap.NewNop(), config.ChainConfig{}, request)
+
+	// block n is produced at 1700000000 + 2n seconds, the latest block is 1000
+	request.EXPECT().MakeRequest(
+		gomock.Any(),
+		http.MethodPost,
+		gomock.Any(),
+		gomock.Any(),
+		gomock.Any(),
+	).DoAndReturn(func(ctx context.Context, method string, apiUrl string, queryParams map[string]string, reqBody string) ([]byte, error) {
+		var req jsonrpc.Request
+		if err := json.Unmarshal([]byte(reqBody), &req); err != nil {
+			return nil, err
+		}
+		blockNumber := int64(1000)
+		if param := req.Params[0].(string); param != "latest" {
+			blockNumber, _ = strconv.ParseInt(strings.TrimPrefix(param, "0x"), 16, 64)
+		}
+		return []byte(fmt.Sprintf(
+			`{"jsonrpc":"2.0","id":1,"result":{"number":"0x%x","hash":"0x%x","timestamp":"0x%x","transactions":[]}}`,
+			blockNumber, blockNumber, 1700000000+2*blockNumber,
+		)), nil
+	}).AnyTimes()
+
+	block, err := base.GetBlockByTimestamp(context.Background(), (1700000000+2*345)*1000)
+	require.NoError(t, err)
+	require.Equal(t, int64(345), block.BlockNumber)
+
+	// between two blocks the later one is returned
+	block, err = base.GetBlockByTimestamp(context.Background(), (1700000000+2*345+1)*1000)
+	require.NoError(t, err)
+	require.Equal(t, int64(346), block.BlockNumber)
+
+	block, err = base.GetBlockByTimestamp(context.Background(), 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), block.BlockNumber)
+
+	_, err = base.GetBlockByTimestamp(context.Background(), (1700000000+2*1000+1)*1000)
+	require.ErrorIs(t, err, ErrBlockNotFound)
+}
+
+func TestBase_GetTransactionReceipts(t *testing.T) {
+	request := request.NewMockRequest(gomock.NewController(t))
+	base := NewBaseChain(zap.NewNop(), config.ChainConfig{}, request)
+
+	testData, err := os.ReadFile("test_data/gettransactionreceipts.json")
+	require.NoError(t, err)
+
+	request.EXPECT().MakeRequest(
+		gomock.Any(),
+		http.MethodPost,
+		gomock.Any(),
+		gomock.Any(),
+		gomock.Any(),
+	).Return(testData, nil)
+
+	txnHash := "0x9f1c3c1b2a4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7"
+	receipts, err := base.GetTransactionReceipts(context.Background(), []string{txnHash, "0x01"})
+	require.NoError(t, err)
+	require.Len(t, receipts, 1) // the unknown transaction has no receipt
+
+	receipt := receipts[txnHash]
+	require.Equal(t, int64(3), receipt.TxnIndex)
+	require.Equal(t, int64(21646464), receipt.BlockNumber)
+	require.Len(t, receipt.Logs, 1)
+	require.Equal(t, "0x833589fcd6edb6e08f4c7c32d4f71b54bda02913", receipt.Logs[0].Address)
+	require.Equal(t, int64(7), receipt.Logs[0].LogIndex)
+	require.Equal(t, int64(3), receipt.Logs[0].TxnIndex)
+}
+
+func TestBase_Call(t *testing.T) {
+	request := request.NewMockRequest(gomock.NewController(t))
+	base := NewBaseChain(zap.NewNop(), config.ChainConfig{}, request)
+
+	request.EXPECT().MakeRequest(
+		gomock.Any(),
+		http.MethodPost,
+		gomock.Any(),
+		gomock.Any(),
+		`{"method":"eth_chainId","params":[],"id":1,"jsonrpc":"2.0"}`,
+	).Return([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x2105"}`), nil)
+
+	result, err := base.Call(context.Background(), "eth_chainId", []any{})
+	require.NoError(t, err)
+	require.JSONEq(t, `"0x2105"`, string(result))
+
+	request.EXPECT().MakeRequest(
+		gomock.Any(),
+		http.MethodPost,
+		gomock.Any(),
+		gomock.Any(),
+		gomock.Any(),
+	).Return([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"query returned more than 10000 results"}}`), nil)
+
+	_, err = base.Call(context.Background(), "eth_getLogs", []any{map[string]any{"fromBlock": "0x0"}})
+	var rpcErr *jsonrpc.Error
+	require.ErrorAs(t, err, &rpcErr)
+	require.Equal(t, jsonrpc.ErrCodeLimitExceeded, rpcErr.Code)
+}
+
+func TestBase_Metrics(t *testing.T) {
+	mockRequest := request.NewMockRequest(gomock.NewController(t))
+	base := NewBaseChain(zap.NewNop(), config.ChainConfig{ApiEndpoint: "https://metrics.test/v2", ApiKey: "key"}, mockRequest)
+	ctx := context.Background()
+
+	gomock.InOrder(
+		mockRequest.EXPECT().MakeRequest(gomock.Any(), http.MethodPost, "https://metrics.test/v2/key", gomock.Any(), gomock.Any()).
+			Return([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x2105"}`), nil),
+		mockRequest.EXPECT().MakeRequest(gomock.Any(), http.MethodPost, gomock.Any(), gomock.Any(), gomock.Any()).
+			Return([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"limit exceeded"}}`), nil),
+		mockRequest.EXPECT().MakeRequest(gomock.Any(), http.MethodPost, gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, &request.StatusError{StatusCode: http.StatusTooManyRequests, Status: "429 Too Many Requests"}),
+	)
+	_, err := base.Call(ctx, "eth_chainId", []any{})
+	require.NoError(t, err)
+	_, err = base.Call(ctx, "eth_getLogs", []any{})
+	require.Error(t, err)
+	_, err = base.Call(ctx, "eth_getLogs", []any{})
+	require.Error(t, err)
+
+	// the endpoint is labelled without the api key
+	require.Equal(t, float64(1), testutil.ToFloat64(metrics.RpcRequests.WithLabelValues("eth_chainId", "metrics.test")))
+	require.Equal(t, float64(2), testutil.ToFloat64(metrics.RpcRequests.WithLabelValues("eth_getLogs", "metrics.test")))
+	require.Equal(t, float64(1), testutil.ToFloat64(metrics.RpcErrors.WithLabelValues("eth_getLogs", "metrics.test", "-32005")))
+	require.Equal(t, float64(1), testutil.ToFloat64(metrics.RpcErrors.WithLabelValues("eth_getLogs", "metrics.test", "429")))
+}
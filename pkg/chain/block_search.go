@@ -0,0 +1,33 @@
+package chain
+
+import "context"
+
+// getBlockByTimestamp binary searches the block headers of the chain for the
+// first block produced at or after the timestamp (in milliseconds).
+func getBlockByTimestamp(ctx context.Context, c Chain, timestamp int64) (Block, error) {
+	latestBlock, err := c.GetBlockByNumber(ctx, BlockNumberLatest, false)
+	if err != nil {
+		return Block{}, err
+	}
+	if latestBlock.Timestamp < timestamp {
+		return Block{}, ErrBlockNotFound
+	}
+
+	low, high := int64(0), latestBlock.BlockNumber
+	block := latestBlock
+	for low < high {
+		mid := low + (high-low)/2
+		midBlock, err := c.GetBlockByNumber(ctx, mid, false)
+		if err != nil {
+			return Block{}, err
+		}
+		if midBlock.Timestamp >= timestamp {
+			high = mid
+			block = midBlock
+		} else {
+			low = mid + 1
+		}
+	}
+
+	return block, nil
+}
@@ -2,6 +2,8 @@ package chain
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 )
 
 const (
@@ -14,24 +16,37 @@ const (
 	BlockNumberSafe      int64 = -3
 )
 
+var (
+	ErrBlockNotFound = errors.New("block not found")
+)
+
 type Block struct {
-	ChainId     int64    `json:"chainId"`
-	BlockNumber int64    `json:"number"`
-	BlockHash   string   `json:"hash"`
-	Timestamp   int64    `json:"timestamp"` // in milli seconds
-	Txns        []Txn    `json:"transactions"`
-	TxnHashes   []string `json:"transactionHashes"`
-	Logs        []Log    `json:"logs"`
+	ChainId       int64    `json:"chainId"`
+	BlockNumber   int64    `json:"number"`
+	BlockHash     string   `json:"hash"`
+	ParentHash    string   `json:"parentHash"`
+	Timestamp     int64    `json:"timestamp"` // in milli seconds
+	GasLimit      int64    `json:"gasLimit"`
+	GasUsed       int64    `json:"gasUsed"`
+	BaseFeePerGas string   `json:"baseFeePerGas"` // hex quantity in wei
+	Txns          []Txn    `json:"transactions"`
+	TxnHashes     []string `json:"transactionHashes"`
+	Logs          []Log    `json:"logs"`
 }
 
 type Txn struct {
 	BlockHash   string `json:"blockHash"`
 	BlockNumber string `json:"blockNumber"`
 	TxnHash     string `json:"hash"`
+	TxnIndex    int64  `json:"transactionIndex"`
 	Type        string `json:"type"`
+	Nonce       string `json:"nonce"`
 	From        string `json:"from"`
 	To          string `json:"to"`
 	Value       string `json:"value"`
+	Gas         string `json:"gas"`
+	GasPrice    string `json:"gasPrice"`
+	Input       string `json:"input"`
 }
 
 type Log struct {
@@ -41,12 +56,28 @@ type Log struct {
 	Data        string   `json:"data"`
 	Topics      []string `json:"topics"`
 	TxnHash     string   `json:"transactionHash"`
+	TxnIndex    int64    `json:"transactionIndex"`
 	LogIndex    int64    `json:"logIndex"`
 	Removed     bool     `json:"removed"` // in milli seconds
 }
 
+type Receipt struct {
+	TxnHash     string `json:"transactionHash"`
+	TxnIndex    int64  `json:"transactionIndex"`
+	BlockNumber int64  `json:"blockNumber"`
+	BlockHash   string `json:"blockHash"`
+	Logs        []Log  `json:"logs"`
+}
+
 type Chain interface {
 	GetChainId() int64
 	GetBlockByNumber(ctx context.Context, blockNumber int64, fullTxns bool) (Block, error)
+	GetBlockByTimestamp(ctx context.Context, timestamp int64) (Block, error)
 	GetBlocks(ctx context.Context, fromBlockNumber int64, toBlockNumber int64, fullTxns bool, includeLogs bool, addresses []string, topics []string) ([]Block, error)
+	// GetTransactionReceipts returns the receipts of the transactions found
+	// on chain, keyed by transaction hash.
+	GetTransactionReceipts(ctx context.Context, txnHashes []string) (map[string]Receipt, error)
+	// Call forwards a JSON-RPC request to the provider and returns the raw
+	// result.
+	Call(ctx context.Context, method string, params []any) (json.RawMessage, error)
 }
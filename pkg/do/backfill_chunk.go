@@ -0,0 +1,13 @@
+package do
+
+type BackfillChunk struct {
+	TaskName                 string `json:"task_name" gorm:"column:task_name;primaryKey"`
+	FromBlockNumber          int64  `json:"from_block_number" gorm:"column:from_block_number;primaryKey"`
+	ToBlockNumber            int64  `json:"to_block_number" gorm:"column:to_block_number"`
+	LastProcessedBlockNumber int64  `json:"last_processed_block_number" gorm:"column:last_processed_block_number"`
+	Completed                bool   `json:"completed" gorm:"column:completed"`
+}
+
+func (c *BackfillChunk) TableName() string {
+	return "BackfillChunks"
+}
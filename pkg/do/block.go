@@ -0,0 +1,16 @@
+package do
+
+type Block struct {
+	ChainId       int64   `json:"chain_id" gorm:"column:chain_id;primaryKey"`
+	BlockNumber   int64   `json:"block_number" gorm:"column:block_number;primaryKey"`
+	BlockHash     string  `json:"block_hash" gorm:"column:block_hash;serializer:hex"`
+	ParentHash    string  `json:"parent_hash" gorm:"column:parent_hash;serializer:hex"`
+	Timestamp     int64   `json:"timestamp" gorm:"column:timestamp"` // in milliseconds
+	GasLimit      int64   `json:"gas_limit" gorm:"column:gas_limit"`
+	GasUsed       int64   `json:"gas_used" gorm:"column:gas_used"`
+	BaseFeePerGas *string `json:"base_fee_per_gas" gorm:"column:base_fee_per_gas"` // decimal wei, nil before London
+}
+
+func (b *Block) TableName() string {
+	return "Blocks"
+}
@@ -0,0 +1,25 @@
+package do
+
+import "time"
+
+const (
+	DeadLetterStatusOpen     = "open"
+	DeadLetterStatusResolved = "resolved"
+)
+
+type DeadLetter struct {
+	Id              int64     `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	TaskName        string    `json:"task_name" gorm:"column:task_name"`
+	FromBlockNumber int64     `json:"from_block_number" gorm:"column:from_block_number"`
+	ToBlockNumber   int64     `json:"to_block_number" gorm:"column:to_block_number"`
+	Error           string    `json:"error" gorm:"column:error"`
+	Attempts        int64     `json:"attempts" gorm:"column:attempts"`
+	Status          string    `json:"status" gorm:"column:status"`
+	Skipped         bool      `json:"skipped" gorm:"column:skipped"` // the checkpoint moved past the range, leaving a gap
+	FirstFailedAt   time.Time `json:"first_failed_at" gorm:"column:first_failed_at"`
+	LastFailedAt    time.Time `json:"last_failed_at" gorm:"column:last_failed_at"`
+}
+
+func (d *DeadLetter) TableName() string {
+	return "DeadLetters"
+}
@@ -0,0 +1,63 @@
+package do
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+func init() {
+	schema.RegisterSerializer("hex", HexSerializer{})
+}
+
+// HexSerializer stores a 0x-prefixed hex string field as bytea. The empty
+// string is stored as NULL, while "0x" is stored as empty bytes.
+type HexSerializer struct{}
+
+func (HexSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue any) error {
+	var s string
+	switch v := dbValue.(type) {
+	case nil:
+	case []byte:
+		s = BytesToHex(v)
+	case string:
+		s = BytesToHex([]byte(v))
+	default:
+		return fmt.Errorf("unsupported hex column value %T", dbValue)
+	}
+	field.ReflectValueOf(ctx, dst).SetString(s)
+	return nil
+}
+
+func (HexSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue any) (any, error) {
+	s, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("unsupported hex field %T", fieldValue)
+	}
+	if s == "" {
+		return nil, nil
+	}
+	return HexToBytes(s)
+}
+
+// HexToBytes decodes a hex string with or without the 0x prefix, in either
+// case. It never returns nil bytes.
+func HexToBytes(s string) ([]byte, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		b = []byte{}
+	}
+	return b, nil
+}
+
+func BytesToHex(b []byte) string {
+	return "0x" + hex.EncodeToString(b)
+}
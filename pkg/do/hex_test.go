@@ -0,0 +1,34 @@
+package do
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHexToBytes(t *testing.T) {
+	b, err := HexToBytes("0xDDF252ad")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xdd, 0xf2, 0x52, 0xad}, b)
+	require.Equal(t, "0xddf252ad", BytesToHex(b))
+
+	b, err = HexToBytes("0x")
+	require.NoError(t, err)
+	require.NotNil(t, b)
+	require.Empty(t, b)
+
+	_, err = HexToBytes("0x123")
+	require.Error(t, err)
+}
+
+func TestLog_TopicColumns(t *testing.T) {
+	log := Log{Topics: []string{"0x01", "0x02"}}
+	require.NoError(t, log.BeforeSave(nil))
+	require.Equal(t, "0x01", log.Topic0)
+	require.Equal(t, "0x02", log.Topic1)
+	require.Empty(t, log.Topic2)
+
+	found := Log{Topic0: log.Topic0, Topic1: log.Topic1}
+	require.NoError(t, found.AfterFind(nil))
+	require.Equal(t, log.Topics, found.Topics)
+}
@@ -1,19 +1,57 @@
 package do
 
-import "github.com/lib/pq"
+import (
+	"time"
 
+	"gorm.io/gorm"
+)
+
+// Log is stored with its hashes, data and topics as bytea. Topics holds the
+// hex topics, which are persisted in the columns topic0 to topic3.
 type Log struct {
-	ChainId     int64          `json:"chain_id" gorm:"column:chain_id;primaryKey"`
-	BlockNumber int64          `json:"block_number" gorm:"column:block_number"`
-	BlockHash   string         `json:"block_hash" gorm:"column:block_hash"`
-	Data        string         `json:"data" gorm:"column:data"`
-	Topics      pq.StringArray `json:"topics" gorm:"column:topics;type:text[]"`
-	TxnHash     string         `json:"txn_hash" gorm:"column:txn_hash;primaryKey"`
-	LogIndex    int64          `json:"log_index" gorm:"column:log_index;primaryKey"`
-	Removed     bool           `json:"removed" gorm:"column:removed"`
-	Timestamp   int64          `json:"timestamp" gorm:"column:timestamp"`
+	ChainId        int64     `json:"chain_id" gorm:"column:chain_id;primaryKey"`
+	BlockNumber    int64     `json:"block_number" gorm:"column:block_number;primaryKey"`
+	BlockHash      string    `json:"block_hash" gorm:"column:block_hash;serializer:hex"`
+	Address        string    `json:"address" gorm:"column:address;serializer:hex"` // the contract which emitted the log
+	Data           string    `json:"data" gorm:"column:data;serializer:hex"`
+	Topics         []string  `json:"topics" gorm:"-"`
+	Topic0         string    `json:"-" gorm:"column:topic0;serializer:hex"`
+	Topic1         string    `json:"-" gorm:"column:topic1;serializer:hex"`
+	Topic2         string    `json:"-" gorm:"column:topic2;serializer:hex"`
+	Topic3         string    `json:"-" gorm:"column:topic3;serializer:hex"`
+	TxnHash        string    `json:"txn_hash" gorm:"column:txn_hash;primaryKey;serializer:hex"`
+	TxnIndex       int64     `json:"transaction_index" gorm:"column:transaction_index"`
+	LogIndex       int64     `json:"log_index" gorm:"column:log_index;primaryKey"`
+	Removed        bool      `json:"removed" gorm:"column:removed"`
+	Timestamp      int64     `json:"timestamp" gorm:"column:timestamp"` // block timestamp in milliseconds
+	BlockTimestamp time.Time `json:"block_timestamp" gorm:"column:block_timestamp"`
 }
 
 func (l *Log) TableName() string {
 	return "Logs"
 }
+
+// TopicColumns returns the topics by position, the empty string standing
+// for a missing topic.
+func (l *Log) TopicColumns() [4]string {
+	var columns [4]string
+	copy(columns[:], l.Topics)
+	return columns
+}
+
+func (l *Log) BeforeSave(tx *gorm.DB) error {
+	columns := l.TopicColumns()
+	l.Topic0, l.Topic1, l.Topic2, l.Topic3 = columns[0], columns[1], columns[2], columns[3]
+	return nil
+}
+
+func (l *Log) AfterFind(tx *gorm.DB) error {
+	l.Topics = nil
+	for _, topic := range []string{l.Topic0, l.Topic1, l.Topic2, l.Topic3} {
+		if topic == "" {
+			break
+		}
+		l.Topics = append(l.Topics, topic)
+	}
+	return nil
+}
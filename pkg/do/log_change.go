@@ -0,0 +1,25 @@
+package do
+
+import "time"
+
+// LogChange records a stored log replaced by a different version of it,
+// e.g. removed or moved to another block by a reorg.
+type LogChange struct {
+	Id             int64     `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	ChainId        int64     `json:"chain_id" gorm:"column:chain_id"`
+	TxnHash        string    `json:"txn_hash" gorm:"column:txn_hash;serializer:hex"`
+	LogIndex       int64     `json:"log_index" gorm:"column:log_index"`
+	OldBlockNumber int64     `json:"old_block_number" gorm:"column:old_block_number"`
+	NewBlockNumber int64     `json:"new_block_number" gorm:"column:new_block_number"`
+	OldBlockHash   string    `json:"old_block_hash" gorm:"column:old_block_hash;serializer:hex"`
+	NewBlockHash   string    `json:"new_block_hash" gorm:"column:new_block_hash;serializer:hex"`
+	OldRemoved     bool      `json:"old_removed" gorm:"column:old_removed"`
+	NewRemoved     bool      `json:"new_removed" gorm:"column:new_removed"`
+	OldTimestamp   int64     `json:"old_timestamp" gorm:"column:old_timestamp"`
+	NewTimestamp   int64     `json:"new_timestamp" gorm:"column:new_timestamp"`
+	ChangedAt      time.Time `json:"changed_at" gorm:"column:changed_at"`
+}
+
+func (c *LogChange) TableName() string {
+	return "LogChanges"
+}
@@ -0,0 +1,24 @@
+package do
+
+import "time"
+
+// ProcessedRange records a committed range of blocks, for auditing when and
+// by whom the blocks were ingested.
+type ProcessedRange struct {
+	Id              int64     `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	TaskName        string    `json:"task_name" gorm:"column:task_name"`
+	ChainId         int64     `json:"chain_id" gorm:"column:chain_id"`
+	FromBlockNumber int64     `json:"from_block_number" gorm:"column:from_block_number"`
+	ToBlockNumber   int64     `json:"to_block_number" gorm:"column:to_block_number"`
+	FromBlockHash   string    `json:"from_block_hash" gorm:"column:from_block_hash;serializer:hex"`
+	ToBlockHash     string    `json:"to_block_hash" gorm:"column:to_block_hash;serializer:hex"`
+	LogCount        int64     `json:"log_count" gorm:"column:log_count"`
+	RpcEndpoint     string    `json:"rpc_endpoint" gorm:"column:rpc_endpoint"` // without the api key
+	DurationMs      int64     `json:"duration_ms" gorm:"column:duration_ms"`   // from the first query to the commit
+	InstanceId      string    `json:"instance_id" gorm:"column:instance_id"`
+	CommittedAt     time.Time `json:"committed_at" gorm:"column:committed_at"`
+}
+
+func (r *ProcessedRange) TableName() string {
+	return "ProcessedRanges"
+}
@@ -1,9 +1,16 @@
 package do
 
+import "time"
+
 type Task struct {
-	Name                        string `json:"name" gorm:"column:name;primaryKey"`
-	LastProcessedBlockNumber    int64  `json:"last_processed_block_number" gorm:"column:last_processed_block_number"`
-	LastProcessedBlockTimestamp int64  `json:"last_processed_block_timestamp" gorm:"column:last_processed_block_timestamp"`
+	Name                        string    `json:"name" gorm:"column:name;primaryKey"`
+	LastProcessedBlockNumber    int64     `json:"last_processed_block_number" gorm:"column:last_processed_block_number"`
+	LastProcessedBlockTimestamp int64     `json:"last_processed_block_timestamp" gorm:"column:last_processed_block_timestamp"`
+	Owner                       string    `json:"owner" gorm:"column:owner"`                       // instance holding the lease
+	LeaseExpiresAt              time.Time `json:"lease_expires_at" gorm:"column:lease_expires_at"` // when a standby may take over
+	FencingToken                int64     `json:"fencing_token" gorm:"column:fencing_token"`       // incremented on every takeover
+	Paused                      bool      `json:"paused" gorm:"column:paused"`                     // the monitor processes no blocks
+	Revision                    int64     `json:"revision" gorm:"column:revision"`                 // incremented on every admin change
 }
 
 func (t *Task) TableName() string {
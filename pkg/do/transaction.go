@@ -0,0 +1,21 @@
+package do
+
+type Transaction struct {
+	ChainId     int64  `json:"chain_id" gorm:"column:chain_id;primaryKey"`
+	TxnHash     string `json:"txn_hash" gorm:"column:txn_hash;primaryKey;serializer:hex"`
+	BlockNumber int64  `json:"block_number" gorm:"column:block_number"`
+	BlockHash   string `json:"block_hash" gorm:"column:block_hash;serializer:hex"`
+	TxnIndex    int64  `json:"transaction_index" gorm:"column:transaction_index"`
+	Type        int64  `json:"type" gorm:"column:type"`
+	Nonce       int64  `json:"nonce" gorm:"column:nonce"`
+	From        string `json:"from_address" gorm:"column:from_address;serializer:hex"`
+	To          string `json:"to_address" gorm:"column:to_address;serializer:hex"` // empty for contract creation
+	Value       string `json:"value" gorm:"column:value"`                          // decimal wei
+	Gas         int64  `json:"gas" gorm:"column:gas"`
+	GasPrice    string `json:"gas_price" gorm:"column:gas_price"` // decimal wei
+	Input       string `json:"input" gorm:"column:input;serializer:hex"`
+}
+
+func (t *Transaction) TableName() string {
+	return "Transactions"
+}
@@ -35,6 +35,10 @@ func (t *debugTripper) RoundTrip(req *http.Request) (resp *http.Response, err er
 			resp.Body = io.NopCloser(io.TeeReader(resp.Body, respBody))
 		}
 
+		if resp == nil {
+			return
+		}
+
 		t.lg.Debug(
 			fmt.Sprintf(
 				"%s %s:%s %d %v",
@@ -2,8 +2,10 @@ package httpclient
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/waynewu411/blocktasks/pkg/config"
+	"github.com/waynewu411/blocktasks/pkg/metrics"
 	"go.uber.org/zap"
 	"golang.org/x/time/rate"
 )
@@ -33,10 +35,14 @@ func NewHttpClient(lg *zap.Logger, cfg config.HttpClientConfig) HttpClient {
 
 func (t *httpClient) Do(req *http.Request) (*http.Response, error) {
 	if t.rateLimiter != nil {
-		if err := t.rateLimiter.Wait(req.Context()); err != nil {
+		startedAt := time.Now()
+		err := t.rateLimiter.Wait(req.Context())
+		metrics.RateLimiterWait.WithLabelValues(req.URL.Host).Observe(time.Since(startedAt).Seconds())
+		if err != nil {
 			t.lg.Error("rate limiter wait error", zap.Error(err))
 			return nil, err
 		}
 	}
+	metrics.MarkRpcRequestSent(req.Context())
 	return t.client.Do(req)
 }
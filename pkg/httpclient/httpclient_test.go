@@ -3,11 +3,15 @@ package httpclient
 import (
 	"context"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/waynewu411/blocktasks/pkg/config"
+	"github.com/waynewu411/blocktasks/pkg/metrics"
 	"go.uber.org/zap/zaptest"
 )
 
@@ -17,9 +21,14 @@ func Test_HttpClientRateLimiter(t *testing.T) {
 		DebugEnabled: true,
 	}
 
-	hc := NewHttpClient(zaptest.NewLogger(t), &httpClientCfg)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
 
-	req, err := http.NewRequest(http.MethodGet, "https://google.com", nil)
+	hc := NewHttpClient(zaptest.NewLogger(t), httpClientCfg)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -34,11 +43,43 @@ func Test_HttpClientRateLimiter(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
 	defer cancel()
 
-	req, err = http.NewRequestWithContext(ctx, http.MethodGet, "https://google.com", nil)
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	resp, err = hc.Do(req)
 	assert.Error(t, err)
+
+	// both waits are observed, including the one which timed out
+	var wait dto.Metric
+	assert.NoError(t, metrics.RateLimiterWait.WithLabelValues(req.URL.Host).(prometheus.Histogram).Write(&wait))
+	assert.Equal(t, uint64(2), wait.GetHistogram().GetSampleCount())
+}
+
+func Test_HttpClientRpcRequestDuration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	hc := NewHttpClient(zaptest.NewLogger(t), config.HttpClientConfig{RateLimit: 5})
+	for i := 0; i < 2; i++ {
+		ctx := metrics.StartRpcRequest(context.Background())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := hc.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		metrics.ObserveRpcRequest(ctx, "eth_test", req.URL.Host)
+	}
+
+	// the second request waited about 200ms for the rate limiter, which the
+	// duration leaves out
+	var duration dto.Metric
+	assert.NoError(t, metrics.RpcRequestDuration.WithLabelValues("eth_test", server.Listener.Addr().String()).(prometheus.Histogram).Write(&duration))
+	assert.Equal(t, uint64(2), duration.GetHistogram().GetSampleCount())
+	assert.Less(t, duration.GetHistogram().GetSampleSum(), 0.1)
 }
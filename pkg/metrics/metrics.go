@@ -0,0 +1,144 @@
+// Package metrics holds the Prometheus collectors of the chain, request and
+// task layers. They are registered with the default registry, which is served
+// on /metrics by the server.
+package metrics
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "blocktasks"
+
+var (
+	RpcRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_requests_total",
+		Help:      "Requests sent to the rpc providers, a batch counting once.",
+	}, []string{"method", "endpoint"})
+
+	RpcRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rpc_request_duration_seconds",
+		Help:      "Duration of the requests sent to the rpc providers, from after the rate limiter wait.",
+		Buckets:   prometheus.ExponentialBuckets(0.025, 2, 10),
+	}, []string{"method", "endpoint"})
+
+	RpcErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_errors_total",
+		Help:      "Failed rpc requests by http status, json-rpc error code, or transport.",
+	}, []string{"method", "endpoint", "code"})
+
+	RateLimiterWait = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rate_limiter_wait_seconds",
+		Help:      "Time the requests waited for the rate limiter.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 4, 8),
+	}, []string{"endpoint"})
+
+	TaskLagBlocks = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "task_lag_blocks",
+		Help:      "Blocks between the latest safe block and the checkpoint of the task.",
+	}, []string{"task"})
+
+	TaskLagSeconds = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "task_lag_seconds",
+		Help:      "Time between the latest safe block and the checkpoint of the task.",
+	}, []string{"task"})
+
+	LogsInserted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logs_inserted_total",
+		Help:      "Logs stored by the task, including those stored again.",
+	}, []string{"task"})
+
+	RangesProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ranges_processed_total",
+		Help:      "Ranges of blocks committed by the task.",
+	}, []string{"task"})
+
+	Retries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "retries_total",
+		Help:      "Operations retried by the retry policy, by class of error.",
+	}, []string{"class"})
+
+	// counted as the changes are recorded, so also within a transaction
+	// which rolls back
+	ReorgedLogs = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reorged_logs_total",
+		Help:      "Stored logs removed or moved to another block by a reorg.",
+	})
+
+	DbTransactionDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_transaction_duration_seconds",
+		Help:      "Duration of the database transactions.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"result"})
+)
+
+// results of DbTransactionDuration
+const (
+	ResultCommit   = "commit"
+	ResultRollback = "rollback"
+)
+
+type rpcRequestKey struct{}
+
+// rpcRequest is the time a request was sent, set by the http client once the
+// rate limiter lets it through.
+type rpcRequest struct {
+	sentAt time.Time
+}
+
+// StartRpcRequest returns the context of a request to be recorded with
+// ObserveRpcRequest, sent now unless MarkRpcRequestSent is called.
+func StartRpcRequest(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rpcRequestKey{}, &rpcRequest{sentAt: time.Now()})
+}
+
+// MarkRpcRequestSent records that the request of the context is sent now,
+// so that its duration leaves out the rate limiter wait.
+func MarkRpcRequestSent(ctx context.Context) {
+	if r, ok := ctx.Value(rpcRequestKey{}).(*rpcRequest); ok {
+		r.sentAt = time.Now()
+	}
+}
+
+// ObserveRpcRequest records the request of the context sent to the endpoint.
+func ObserveRpcRequest(ctx context.Context, method string, endpoint string) {
+	RpcRequests.WithLabelValues(method, endpoint).Inc()
+	if r, ok := ctx.Value(rpcRequestKey{}).(*rpcRequest); ok {
+		RpcRequestDuration.WithLabelValues(method, endpoint).Observe(time.Since(r.sentAt).Seconds())
+	}
+}
+
+// ObserveDbTransaction records a transaction started at startedAt, which
+// rolled back when err is not nil.
+func ObserveDbTransaction(startedAt time.Time, err error) {
+	result := ResultCommit
+	if err != nil {
+		result = ResultRollback
+	}
+	DbTransactionDuration.WithLabelValues(result).Observe(time.Since(startedAt).Seconds())
+}
+
+// Endpoint returns the host of the url, leaving out the credentials and the
+// api key in its path.
+func Endpoint(apiUrl string) string {
+	u, err := url.Parse(apiUrl)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BackfillChunkDao interface {
+	InsertChunks(ctx context.Context, chunks []do.BackfillChunk) error
+	UpdateChunk(ctx context.Context, chunk do.BackfillChunk) (do.BackfillChunk, error)
+	GetChunks(ctx context.Context, taskName string) ([]do.BackfillChunk, error)
+}
+
+type backfillChunkDao struct {
+	db *gorm.DB
+}
+
+func NewBackfillChunkDao(db *gorm.DB) BackfillChunkDao {
+	return &backfillChunkDao{db: db}
+}
+
+func (b *backfillChunkDao) InsertChunks(ctx context.Context, chunks []do.BackfillChunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+	err := b.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "task_name"}, {Name: "from_block_number"}},
+			DoNothing: true,
+		}).
+		Create(&chunks).Error
+	if err != nil {
+		return transformGormError(err)
+	}
+	return nil
+}
+
+func (b *backfillChunkDao) UpdateChunk(ctx context.Context, chunk do.BackfillChunk) (do.BackfillChunk, error) {
+	if err := b.db.WithContext(ctx).Save(&chunk).Error; err != nil {
+		return do.BackfillChunk{}, transformGormError(err)
+	}
+	return chunk, nil
+}
+
+func (b *backfillChunkDao) GetChunks(ctx context.Context, taskName string) ([]do.BackfillChunk, error) {
+	var chunks []do.BackfillChunk
+	err := b.db.WithContext(ctx).
+		Where("task_name = ?", taskName).
+		Order("from_block_number").
+		Find(&chunks).Error
+	if err != nil {
+		return nil, transformGormError(err)
+	}
+	return chunks, nil
+}
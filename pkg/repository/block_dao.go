@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BlockDao interface {
+	// InsertBlocks stores the block headers, replacing the headers of the
+	// same block numbers, e.g. after a reorg.
+	InsertBlocks(ctx context.Context, blocks []do.Block) error
+	GetBlock(ctx context.Context, chainId int64, blockNumber int64) (do.Block, error)
+	// GetBlocks returns the stored headers from fromBlockNumber to
+	// toBlockNumber inclusive, ordered by block number.
+	GetBlocks(ctx context.Context, chainId int64, fromBlockNumber int64, toBlockNumber int64) ([]do.Block, error)
+}
+
+type blockDao struct {
+	db *gorm.DB
+}
+
+func NewBlockDao(db *gorm.DB) BlockDao {
+	return &blockDao{db: db}
+}
+
+func (b *blockDao) InsertBlocks(ctx context.Context, blocks []do.Block) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+	err := b.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "chain_id"}, {Name: "block_number"}},
+			UpdateAll: true,
+		}).
+		Create(&blocks).Error
+	if err != nil {
+		return transformGormError(err)
+	}
+	return nil
+}
+
+func (b *blockDao) GetBlock(ctx context.Context, chainId int64, blockNumber int64) (do.Block, error) {
+	var block do.Block
+	err := b.db.WithContext(ctx).
+		Where("chain_id = ? AND block_number = ?", chainId, blockNumber).
+		First(&block).Error
+	if err != nil {
+		return do.Block{}, transformGormError(err)
+	}
+	return block, nil
+}
+
+func (b *blockDao) GetBlocks(ctx context.Context, chainId int64, fromBlockNumber int64, toBlockNumber int64) ([]do.Block, error) {
+	var blocks []do.Block
+	err := b.db.WithContext(ctx).
+		Where("chain_id = ? AND block_number BETWEEN ? AND ?", chainId, fromBlockNumber, toBlockNumber).
+		Order("block_number").
+		Find(&blocks).Error
+	if err != nil {
+		return nil, transformGormError(err)
+	}
+	return blocks, nil
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DeadLetterDao interface {
+	// RecordDeadLetter inserts the dead letter, or adds its attempts to an
+	// existing dead letter of the same range and reopens it.
+	RecordDeadLetter(ctx context.Context, deadLetter do.DeadLetter) (do.DeadLetter, error)
+	UpdateDeadLetter(ctx context.Context, deadLetter do.DeadLetter) (do.DeadLetter, error)
+	GetDeadLetters(ctx context.Context, taskName string, status string) ([]do.DeadLetter, error)
+}
+
+type deadLetterDao struct {
+	db *gorm.DB
+}
+
+func NewDeadLetterDao(db *gorm.DB) DeadLetterDao {
+	return &deadLetterDao{db: db}
+}
+
+func (d *deadLetterDao) RecordDeadLetter(ctx context.Context, deadLetter do.DeadLetter) (do.DeadLetter, error) {
+	err := d.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "task_name"}, {Name: "from_block_number"}, {Name: "to_block_number"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"error":          gorm.Expr("excluded.error"),
+				"attempts":       gorm.Expr(`"DeadLetters".attempts + excluded.attempts`),
+				"status":         gorm.Expr("excluded.status"),
+				"skipped":        gorm.Expr("excluded.skipped"),
+				"last_failed_at": gorm.Expr("excluded.last_failed_at"),
+			}),
+		}).
+		Create(&deadLetter).Error
+	if err != nil {
+		return do.DeadLetter{}, transformGormError(err)
+	}
+	return deadLetter, nil
+}
+
+func (d *deadLetterDao) UpdateDeadLetter(ctx context.Context, deadLetter do.DeadLetter) (do.DeadLetter, error) {
+	if err := d.db.WithContext(ctx).Save(&deadLetter).Error; err != nil {
+		return do.DeadLetter{}, transformGormError(err)
+	}
+	return deadLetter, nil
+}
+
+func (d *deadLetterDao) GetDeadLetters(ctx context.Context, taskName string, status string) ([]do.DeadLetter, error) {
+	var deadLetters []do.DeadLetter
+	err := d.db.WithContext(ctx).
+		Where("task_name = ? AND status = ?", taskName, status).
+		Order("from_block_number").
+		Find(&deadLetters).Error
+	if err != nil {
+		return nil, transformGormError(err)
+	}
+	return deadLetters, nil
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/waynewu411/blocktasks/pkg/do"
+)
+
+// logIdentity identifies a log across reorgs, which may move it to another
+// block.
+type logIdentity struct {
+	chainId  int64
+	txnHash  string
+	logIndex int64
+}
+
+func newLogIdentity(log do.Log) logIdentity {
+	return logIdentity{chainId: log.ChainId, txnHash: log.TxnHash, logIndex: log.LogIndex}
+}
+
+// newLogChange compares a stored log with a new version of it, both
+// normalized, and returns the change unless they agree.
+func newLogChange(stored do.Log, log do.Log, changedAt time.Time) (do.LogChange, bool) {
+	if stored.BlockNumber == log.BlockNumber && stored.BlockHash == log.BlockHash &&
+		stored.Removed == log.Removed && stored.Timestamp == log.Timestamp {
+		return do.LogChange{}, false
+	}
+	return do.LogChange{
+		ChainId:        stored.ChainId,
+		TxnHash:        stored.TxnHash,
+		LogIndex:       stored.LogIndex,
+		OldBlockNumber: stored.BlockNumber,
+		NewBlockNumber: log.BlockNumber,
+		OldBlockHash:   stored.BlockHash,
+		NewBlockHash:   log.BlockHash,
+		OldRemoved:     stored.Removed,
+		NewRemoved:     log.Removed,
+		OldTimestamp:   stored.Timestamp,
+		NewTimestamp:   log.Timestamp,
+		ChangedAt:      changedAt,
+	}, true
+}
+
+// normalizeLog returns the log as it reads back from the database, with
+// lowercase hex values and the topic columns set.
+func normalizeLog(log do.Log) (do.Log, error) {
+	var err error
+	for _, value := range []*string{&log.BlockHash, &log.Address, &log.Data, &log.TxnHash} {
+		if *value, err = normalizeHex(*value); err != nil {
+			return do.Log{}, err
+		}
+	}
+	columns := log.TopicColumns()
+	for i := range columns {
+		if columns[i], err = normalizeHex(columns[i]); err != nil {
+			return do.Log{}, err
+		}
+	}
+	log.Topic0, log.Topic1, log.Topic2, log.Topic3 = columns[0], columns[1], columns[2], columns[3]
+	log.Topics = nil
+	for _, topic := range columns {
+		if topic == "" {
+			break
+		}
+		log.Topics = append(log.Topics, topic)
+	}
+	return log, nil
+}
+
+func normalizeHex(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	b, err := do.HexToBytes(s)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrDatabase, err)
+	}
+	return do.BytesToHex(b), nil
+}
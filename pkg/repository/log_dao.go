@@ -2,33 +2,397 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
+	"strings"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/samber/lo"
 	"github.com/waynewu411/blocktasks/pkg/do"
+	"github.com/waynewu411/blocktasks/pkg/metrics"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
 type LogDao interface {
 	InsertLogs(ctx context.Context, logs []do.Log) error
+	// GetLogsWithoutAddress returns the keys of the logs stored before the
+	// emitting address was recorded, in block order, starting after the log
+	// if any.
+	GetLogsWithoutAddress(ctx context.Context, chainId int64, after *do.Log, limit int) ([]do.Log, error)
+	// CountLogsWithoutAddress counts the logs of every chain stored before
+	// the emitting address was recorded.
+	CountLogsWithoutAddress(ctx context.Context) (int64, error)
+	// UpdateLogMetadata sets the address and the transaction index of the
+	// logs. The address of a log is left unknown when it is empty.
+	UpdateLogMetadata(ctx context.Context, logs []do.Log) error
+	// QueryLogs returns a page of the logs matching the filter in canonical
+	// order, starting after the cursor.
+	QueryLogs(ctx context.Context, filter LogFilter, cursor string, limit int) (LogPage, error)
+	// IterateLogs calls fn with every log matching the filter in canonical
+	// order, loading them page by page. It stops at the first error of fn.
+	IterateLogs(ctx context.Context, filter LogFilter, fn func(do.Log) error) error
+	// GetLogChanges returns the changes of the logs of the transaction, in
+	// the order they were made.
+	GetLogChanges(ctx context.Context, chainId int64, txnHash string) ([]do.LogChange, error)
 }
 
 type logDao struct {
 	db *gorm.DB
+	// conn is the connection of the transaction, nil outside a transaction
+	conn         *sql.Conn
+	partitionDao LogPartitionDao
 }
 
-func NewLogDao(db *gorm.DB) LogDao {
-	return &logDao{db: db}
+func NewLogDao(db *gorm.DB, partitionBlockRange int64) LogDao {
+	return newLogDao(db, nil, partitionBlockRange)
 }
 
+func newLogDao(db *gorm.DB, conn *sql.Conn, partitionBlockRange int64) LogDao {
+	return &logDao{db: db, conn: conn, partitionDao: NewLogPartitionDao(db, partitionBlockRange)}
+}
+
+// logColumns are the columns copied by InsertLogs.
+var logColumns = []string{
+	"chain_id",
+	"block_number",
+	"block_hash",
+	"address",
+	"data",
+	"topic0",
+	"topic1",
+	"topic2",
+	"topic3",
+	"txn_hash",
+	"transaction_index",
+	"log_index",
+	"removed",
+	"timestamp",
+	"block_timestamp",
+}
+
+// pgxTx is implemented by both *pgx.Conn and pgx.Tx.
+type pgxTx interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+// InsertLogs copies the logs into a staging table and merges them into
+// "Logs". A stored log of the same transaction and log index which differs
+// in its block or removed flag is replaced, and the change is recorded in
+// "LogChanges". The partitions of the logs are created first when missing. Inside a transaction the logs are
+// copied on the connection of the transaction, otherwise in a transaction of
+// their own.
 func (e *logDao) InsertLogs(ctx context.Context, logs []do.Log) error {
-	err := e.db.WithContext(ctx).
-		Clauses(clause.OnConflict{
-			Columns:   []clause.Column{{Name: "chain_id"}, {Name: "txn_hash"}, {Name: "log_index"}},
-			DoNothing: true,
-		}).
-		Create(&logs).Error
+	if len(logs) == 0 {
+		return nil
+	}
+
+	logsByChain := lo.GroupBy(logs, func(log do.Log) int64 {
+		return log.ChainId
+	})
+	for chainId, chainLogs := range logsByChain {
+		blockNumbers := lo.Map(chainLogs, func(log do.Log, _ int) int64 {
+			return log.BlockNumber
+		})
+		if err := e.partitionDao.EnsureLogPartitions(ctx, chainId, lo.Min(blockNumbers), lo.Max(blockNumbers)); err != nil {
+			return err
+		}
+	}
+
+	if e.conn != nil {
+		return e.withPgxConn(ctx, e.conn, func(conn *pgx.Conn) error {
+			return copyLogs(ctx, conn, logs)
+		})
+	}
+
+	sqlDB, err := e.db.DB()
+	if err != nil {
+		return transformGormError(err)
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return transformGormError(err)
+	}
+	defer conn.Close()
+
+	return e.withPgxConn(ctx, conn, func(conn *pgx.Conn) error {
+		return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			return copyLogs(ctx, tx, logs)
+		})
+	})
+}
+
+func (e *logDao) withPgxConn(ctx context.Context, conn *sql.Conn, fn func(conn *pgx.Conn) error) error {
+	err := conn.Raw(func(driverConn any) error {
+		stdlibConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("unexpected driver connection %T", driverConn)
+		}
+		return fn(stdlibConn.Conn())
+	})
 	if err != nil {
 		return transformGormError(err)
 	}
 	return nil
 }
+
+func copyLogs(ctx context.Context, tx pgxTx, logs []do.Log) error {
+	// the staging table lives until the end of the transaction, and is
+	// emptied after each merge so that it can be reused within it
+	_, err := tx.Exec(ctx, `CREATE TEMP TABLE IF NOT EXISTS "LogsStaging" (LIKE "Logs" INCLUDING DEFAULTS) ON COMMIT DROP`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"LogsStaging"}, logColumns, pgx.CopyFromSlice(len(logs), func(i int) ([]any, error) {
+		log := logs[i]
+		topics := log.TopicColumns()
+		hexValues, err := hexColumnValues(log.BlockHash, log.Address, log.Data, topics[0], topics[1], topics[2], topics[3], log.TxnHash)
+		if err != nil {
+			return nil, fmt.Errorf("log %s/%d: %w", log.TxnHash, log.LogIndex, err)
+		}
+		return []any{
+			log.ChainId,
+			log.BlockNumber,
+			hexValues[0], // block_hash
+			hexValues[1], // address
+			hexValues[2], // data
+			hexValues[3], // topic0
+			hexValues[4], // topic1
+			hexValues[5], // topic2
+			hexValues[6], // topic3
+			hexValues[7], // txn_hash
+			log.TxnIndex,
+			log.LogIndex,
+			log.Removed,
+			log.Timestamp,
+			log.BlockTimestamp,
+		}, nil
+	}))
+	if err != nil {
+		return err
+	}
+
+	// the changed logs are recorded before they are updated, and the update
+	// may move them to another partition
+	changes, err := tx.Exec(ctx, insertLogChangesSQL)
+	if err != nil {
+		return err
+	}
+	metrics.ReorgedLogs.Add(float64(changes.RowsAffected()))
+	if _, err := tx.Exec(ctx, updateChangedLogsSQL); err != nil {
+		return err
+	}
+
+	columns := strings.Join(logColumns, ", ")
+	_, err = tx.Exec(ctx, fmt.Sprintf(
+		`INSERT INTO "Logs" (%s) SELECT %s FROM "LogsStaging" ON CONFLICT (chain_id, block_number, txn_hash, log_index) DO NOTHING`,
+		columns,
+		columns,
+	))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `TRUNCATE "LogsStaging"`)
+	return err
+}
+
+// changedLogsCondition matches the stored logs l replaced by the staged logs
+// s. A log is identified by its transaction and log index, since a reorg may
+// move it to another block.
+const changedLogsCondition = `l.chain_id = s.chain_id AND l.txn_hash = s.txn_hash AND l.log_index = s.log_index
+	AND (l.block_number, l.block_hash, l.removed, l.timestamp) IS DISTINCT FROM (s.block_number, s.block_hash, s.removed, s.timestamp)`
+
+const insertLogChangesSQL = `INSERT INTO "LogChanges" (
+	chain_id, txn_hash, log_index,
+	old_block_number, new_block_number, old_block_hash, new_block_hash,
+	old_removed, new_removed, old_timestamp, new_timestamp
+)
+SELECT l.chain_id, l.txn_hash, l.log_index,
+	l.block_number, s.block_number, l.block_hash, s.block_hash,
+	l.removed, s.removed, l.timestamp, s.timestamp
+FROM "LogsStaging" s JOIN "Logs" l ON ` + changedLogsCondition
+
+const updateChangedLogsSQL = `UPDATE "Logs" l
+SET block_number = s.block_number, block_hash = s.block_hash, removed = s.removed,
+	timestamp = s.timestamp, block_timestamp = s.block_timestamp
+FROM "LogsStaging" s WHERE ` + changedLogsCondition
+
+// hexColumnValues decodes hex strings into the values of bytea columns, the
+// empty string becoming NULL.
+func hexColumnValues(values ...string) ([]any, error) {
+	columnValues := make([]any, len(values))
+	for i, value := range values {
+		if value == "" {
+			continue
+		}
+		b, err := do.HexToBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		columnValues[i] = b
+	}
+	return columnValues, nil
+}
+
+// hexFilterValues decodes the hex values of a filter.
+func hexFilterValues(values []string) ([][]byte, error) {
+	filterValues := make([][]byte, len(values))
+	for i, value := range values {
+		b, err := do.HexToBytes(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidFilter, value, err)
+		}
+		filterValues[i] = b
+	}
+	return filterValues, nil
+}
+
+func (e *logDao) GetLogsWithoutAddress(ctx context.Context, chainId int64, after *do.Log, limit int) ([]do.Log, error) {
+	db := e.db.WithContext(ctx).
+		Select("chain_id", "block_number", "txn_hash", "log_index").
+		Where("chain_id = ? AND address IS NULL", chainId)
+	if after != nil {
+		txnHash, err := do.HexToBytes(after.TxnHash)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where("(block_number, txn_hash, log_index) > (?, ?, ?)", after.BlockNumber, txnHash, after.LogIndex)
+	}
+
+	var logs []do.Log
+	err := db.
+		Order("block_number, txn_hash, log_index").
+		Limit(limit).
+		Find(&logs).Error
+	if err != nil {
+		return nil, transformGormError(err)
+	}
+	return logs, nil
+}
+
+func (e *logDao) CountLogsWithoutAddress(ctx context.Context) (int64, error) {
+	var count int64
+	err := e.db.WithContext(ctx).Model(&do.Log{}).Where("address IS NULL").Count(&count).Error
+	if err != nil {
+		return 0, transformGormError(err)
+	}
+	return count, nil
+}
+
+func (e *logDao) UpdateLogMetadata(ctx context.Context, logs []do.Log) error {
+	for _, log := range logs {
+		txnHash, err := do.HexToBytes(log.TxnHash)
+		if err != nil {
+			return err
+		}
+		// an unknown address stays NULL, so that the log is retried
+		if log.Address == "" {
+			continue
+		}
+		address, err := do.HexToBytes(log.Address)
+		if err != nil {
+			return err
+		}
+		err = e.db.WithContext(ctx).Model(&do.Log{}).
+			Where("chain_id = ? AND block_number = ? AND txn_hash = ? AND log_index = ?", log.ChainId, log.BlockNumber, txnHash, log.LogIndex).
+			Updates(map[string]any{
+				"address":           address,
+				"transaction_index": log.TxnIndex,
+			}).Error
+		if err != nil {
+			return transformGormError(err)
+		}
+	}
+	return nil
+}
+
+func (e *logDao) GetLogChanges(ctx context.Context, chainId int64, txnHash string) ([]do.LogChange, error) {
+	txnHashValue, err := do.HexToBytes(txnHash)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidFilter, txnHash, err)
+	}
+	var changes []do.LogChange
+	err = e.db.WithContext(ctx).
+		Where("chain_id = ? AND txn_hash = ?", chainId, txnHashValue).
+		Order("id").
+		Find(&changes).Error
+	if err != nil {
+		return nil, transformGormError(err)
+	}
+	return changes, nil
+}
+
+func (e *logDao) QueryLogs(ctx context.Context, filter LogFilter, cursor string, limit int) (LogPage, error) {
+	return queryLogPage(ctx, e.queryLogs, filter, cursor, limit)
+}
+
+func (e *logDao) IterateLogs(ctx context.Context, filter LogFilter, fn func(do.Log) error) error {
+	return iterateLogs(ctx, e.queryLogs, filter, fn)
+}
+
+func (e *logDao) queryLogs(ctx context.Context, filter LogFilter, after *LogCursor, limit int) ([]do.Log, error) {
+	db := e.db.WithContext(ctx).Model(&do.Log{})
+	if filter.ChainId != 0 {
+		db = db.Where("chain_id = ?", filter.ChainId)
+	}
+	if len(filter.Addresses) > 0 {
+		addresses, err := hexFilterValues(filter.Addresses)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where("address IN ?", addresses)
+	}
+	if len(filter.Topics) > 4 {
+		return nil, fmt.Errorf("%w: at most 4 topic positions", ErrInvalidFilter)
+	}
+	for i, topics := range filter.Topics {
+		if len(topics) > 0 {
+			topicValues, err := hexFilterValues(topics)
+			if err != nil {
+				return nil, err
+			}
+			db = db.Where(fmt.Sprintf("topic%d IN ?", i), topicValues)
+		}
+	}
+	if filter.FromBlockNumber != 0 {
+		db = db.Where("block_number >= ?", filter.FromBlockNumber)
+	}
+	if filter.ToBlockNumber != 0 {
+		db = db.Where("block_number <= ?", filter.ToBlockNumber)
+	}
+	if !filter.FromTime.IsZero() {
+		db = db.Where("block_timestamp >= ?", filter.FromTime)
+	}
+	if !filter.ToTime.IsZero() {
+		db = db.Where("block_timestamp <= ?", filter.ToTime)
+	}
+	if filter.TxnHash != "" {
+		txnHash, err := hexFilterValues([]string{filter.TxnHash})
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where("txn_hash = ?", txnHash[0])
+	}
+	if filter.Removed != nil {
+		db = db.Where("removed = ?", *filter.Removed)
+	}
+	if after != nil && after.TxnHash == "" {
+		db = db.Where("(chain_id, block_number, log_index) > (?, ?, ?)", after.ChainId, after.BlockNumber, after.LogIndex)
+	} else if after != nil {
+		txnHash, err := do.HexToBytes(after.TxnHash)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where("(chain_id, block_number, log_index, txn_hash) > (?, ?, ?, ?)", after.ChainId, after.BlockNumber, after.LogIndex, txnHash)
+	}
+
+	var logs []do.Log
+	if err := db.Order("chain_id, block_number, log_index, txn_hash").Limit(limit).Find(&logs).Error; err != nil {
+		return nil, transformGormError(err)
+	}
+	return logs, nil
+}
@@ -0,0 +1,295 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"github.com/waynewu411/blocktasks/pkg/metrics"
+	"gorm.io/gorm/clause"
+)
+
+func TestLogCursor_EncodeDecode(t *testing.T) {
+	cursor := LogCursor{ChainId: 8453, BlockNumber: 21646720, LogIndex: 12, TxnHash: "0x0a"}
+	decoded, err := DecodeLogCursor(cursor.Encode())
+	require.NoError(t, err)
+	require.Equal(t, cursor, *decoded)
+
+	decoded, err = DecodeLogCursor("")
+	require.NoError(t, err)
+	require.Nil(t, decoded)
+
+	_, err = DecodeLogCursor("not a cursor")
+	require.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+// insertTestLogs stores 3 logs in each of 4 blocks on a chain id unique to
+// the test run, alternating between 2 addresses.
+func insertTestLogs(t *testing.T, repo Repository) int64 {
+	t.Helper()
+
+	chainId := time.Now().UnixNano()
+	insertTestLogsOnChain(t, repo, chainId)
+	return chainId
+}
+
+func insertTestLogsOnChain(t *testing.T, repo Repository, chainId int64) {
+	t.Helper()
+
+	start := time.Unix(1700000000, 0).UTC()
+	var logs []do.Log
+	for blockNumber := int64(100); blockNumber < 104; blockNumber++ {
+		blockTime := start.Add(time.Duration(blockNumber-100) * 2 * time.Second)
+		for logIndex := int64(0); logIndex < 3; logIndex++ {
+			logs = append(logs, do.Log{
+				ChainId:        chainId,
+				BlockNumber:    blockNumber,
+				BlockHash:      fmt.Sprintf("0x%08x", blockNumber),
+				Address:        fmt.Sprintf("0xa%d", logIndex%2),
+				Data:           "0x",
+				Topics:         []string{"0xf0", fmt.Sprintf("0xf%d", logIndex)},
+				TxnHash:        fmt.Sprintf("0x%016x", blockNumber),
+				TxnIndex:       0,
+				LogIndex:       logIndex,
+				Timestamp:      blockTime.UnixMilli(),
+				BlockTimestamp: blockTime,
+			})
+		}
+	}
+	require.NoError(t, repo.LogDao().InsertLogs(context.Background(), logs))
+}
+
+func TestLogDao_QueryLogsPagination(t *testing.T) {
+	repo := newTestPgRepository(t)
+	chainId := insertTestLogs(t, repo)
+	ctx := context.Background()
+
+	var logs []do.Log
+	cursor := ""
+	pages := 0
+	for {
+		page, err := repo.LogDao().QueryLogs(ctx, LogFilter{ChainId: chainId}, cursor, 5)
+		require.NoError(t, err)
+		logs = append(logs, page.Logs...)
+		pages++
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	require.Equal(t, 3, pages)
+	require.Len(t, logs, 12)
+	for i := 1; i < len(logs); i++ {
+		prev, cur := logs[i-1], logs[i]
+		require.True(t, prev.BlockNumber < cur.BlockNumber || (prev.BlockNumber == cur.BlockNumber && prev.LogIndex < cur.LogIndex))
+	}
+}
+
+func TestLogDao_QueryLogsFilters(t *testing.T) {
+	repo := newTestPgRepository(t)
+	chainId := insertTestLogs(t, repo)
+	ctx := context.Background()
+	start := time.Unix(1700000000, 0).UTC()
+
+	tests := []struct {
+		name   string
+		filter LogFilter
+		count  int
+	}{
+		{"chain", LogFilter{ChainId: chainId}, 12},
+		{"address", LogFilter{ChainId: chainId, Addresses: []string{"0xA1"}}, 4},
+		{"topic position", LogFilter{ChainId: chainId, Topics: [][]string{{}, {"0xf1", "0xF2"}}}, 8},
+		{"block range", LogFilter{ChainId: chainId, FromBlockNumber: 101, ToBlockNumber: 102}, 6},
+		{"time range", LogFilter{ChainId: chainId, FromTime: start.Add(2 * time.Second), ToTime: start.Add(2 * time.Second)}, 3},
+		{"txn hash", LogFilter{ChainId: chainId, TxnHash: fmt.Sprintf("0x%016x", 103)}, 3},
+	}
+	for _, test := range tests {
+		page, err := repo.LogDao().QueryLogs(ctx, test.filter, "", 0)
+		require.NoError(t, err, test.name)
+		require.Len(t, page.Logs, test.count, test.name)
+	}
+}
+
+func TestLogDao_IterateLogs(t *testing.T) {
+	repo := newTestPgRepository(t)
+	chainId := insertTestLogs(t, repo)
+
+	count := 0
+	err := repo.LogDao().IterateLogs(context.Background(), LogFilter{ChainId: chainId, Addresses: []string{"0xa0"}}, func(log do.Log) error {
+		require.Equal(t, "0xa0", log.Address)
+		count++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 8, count)
+}
+
+func newBenchmarkLogs(chainId int64, blockNumber int64, count int) []do.Log {
+	blockTime := time.Now().UTC()
+	logs := make([]do.Log, count)
+	for i := range logs {
+		logs[i] = do.Log{
+			ChainId:        chainId,
+			BlockNumber:    blockNumber,
+			BlockHash:      fmt.Sprintf("0x%08x", blockNumber),
+			Address:        "0x833589fcd6edb6e08f4c7c32d4f71b54bda02913",
+			Data:           "0x00000000000000000000000000000000000000000000000000000000000f4240",
+			Topics:         []string{"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"},
+			TxnHash:        fmt.Sprintf("0x%016x", blockNumber),
+			LogIndex:       int64(i),
+			Timestamp:      blockTime.UnixMilli(),
+			BlockTimestamp: blockTime,
+		}
+	}
+	return logs
+}
+
+// BenchmarkLogDao_InsertLogs measures the COPY and merge of a range of logs
+// in a transaction, as committed by the monitor.
+func BenchmarkLogDao_InsertLogs(b *testing.B) {
+	repo := newTestPgRepository(b)
+	chainId := time.Now().UnixNano()
+	ctx := context.Background()
+
+	const logsPerRange = 1000
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logs := newBenchmarkLogs(chainId, int64(i), logsPerRange)
+		err := repo.Transaction(func(repo Repository) error {
+			return repo.LogDao().InsertLogs(ctx, logs)
+		})
+		require.NoError(b, err)
+	}
+	b.ReportMetric(float64(b.N*logsPerRange)/b.Elapsed().Seconds(), "logs/s")
+}
+
+// BenchmarkLogDao_CreateLogs is the baseline of inserting the same logs with
+// a multi-row INSERT ... ON CONFLICT DO NOTHING.
+func BenchmarkLogDao_CreateLogs(b *testing.B) {
+	repo := newTestPgRepository(b).(*pgRepository)
+	chainId := time.Now().UnixNano()
+	ctx := context.Background()
+
+	const logsPerRange = 1000
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logs := newBenchmarkLogs(chainId, int64(i), logsPerRange)
+		err := repo.db.WithContext(ctx).
+			Clauses(clause.OnConflict{DoNothing: true}).
+			CreateInBatches(&logs, logsPerRange).Error
+		require.NoError(b, err)
+	}
+	b.ReportMetric(float64(b.N*logsPerRange)/b.Elapsed().Seconds(), "logs/s")
+}
+
+// testLogUpsert checks that a stored log converges to its latest version,
+// recording each change.
+func testLogUpsert(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	chainId := time.Now().UnixNano()
+	log := do.Log{
+		ChainId:        chainId,
+		BlockNumber:    100,
+		BlockHash:      "0x0a",
+		Address:        "0xa0",
+		Data:           "0x",
+		Topics:         []string{"0xf0"},
+		TxnHash:        "0xABCD",
+		LogIndex:       3,
+		Timestamp:      1000,
+		BlockTimestamp: time.UnixMilli(1000).UTC(),
+	}
+	reorgedLogs := testutil.ToFloat64(metrics.ReorgedLogs)
+	require.NoError(t, repo.LogDao().InsertLogs(ctx, []do.Log{log}))
+	require.NoError(t, repo.LogDao().InsertLogs(ctx, []do.Log{log}))
+
+	removed := log
+	removed.Removed = true
+	require.NoError(t, repo.LogDao().InsertLogs(ctx, []do.Log{removed}))
+
+	moved := log
+	moved.BlockNumber = 101
+	moved.BlockHash = "0x0b"
+	moved.Timestamp = 3000
+	moved.BlockTimestamp = time.UnixMilli(3000).UTC()
+	require.NoError(t, repo.LogDao().InsertLogs(ctx, []do.Log{moved}))
+
+	page, err := repo.LogDao().QueryLogs(ctx, LogFilter{ChainId: chainId}, "", 0)
+	require.NoError(t, err)
+	require.Len(t, page.Logs, 1)
+	require.Equal(t, int64(101), page.Logs[0].BlockNumber)
+	require.Equal(t, "0x0b", page.Logs[0].BlockHash)
+	require.Equal(t, int64(3000), page.Logs[0].Timestamp)
+	require.False(t, page.Logs[0].Removed)
+	require.Equal(t, "0xa0", page.Logs[0].Address)
+
+	changes, err := repo.LogDao().GetLogChanges(ctx, chainId, "0xabcd")
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	require.Equal(t, reorgedLogs+2, testutil.ToFloat64(metrics.ReorgedLogs))
+	require.False(t, changes[0].OldRemoved)
+	require.True(t, changes[0].NewRemoved)
+	require.Equal(t, int64(100), changes[1].OldBlockNumber)
+	require.Equal(t, int64(101), changes[1].NewBlockNumber)
+	require.Equal(t, "0x0a", changes[1].OldBlockHash)
+	require.Equal(t, "0x0b", changes[1].NewBlockHash)
+	require.True(t, changes[1].OldRemoved)
+	require.False(t, changes[1].NewRemoved)
+}
+
+func TestLogDao_InsertLogsUpsert(t *testing.T) {
+	testLogUpsert(t, newTestPgRepository(t))
+}
+
+// testLogPagesTiebreak checks that logs stored at the same block and log
+// index, as after a reorg, are each returned once across pages.
+func testLogPagesTiebreak(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	chainId := time.Now().UnixNano()
+	var logs []do.Log
+	for _, txnHash := range []string{"0x0c", "0x0a", "0x0b"} {
+		logs = append(logs, do.Log{
+			ChainId:     chainId,
+			BlockNumber: 100,
+			BlockHash:   "0x01",
+			Address:     "0xa0",
+			Data:        "0x",
+			Topics:      []string{"0xf0"},
+			TxnHash:     txnHash,
+			LogIndex:    0,
+		})
+	}
+	require.NoError(t, repo.LogDao().InsertLogs(ctx, logs))
+
+	var txnHashes []string
+	cursor := ""
+	for {
+		page, err := repo.LogDao().QueryLogs(ctx, LogFilter{ChainId: chainId}, cursor, 1)
+		require.NoError(t, err)
+		for _, log := range page.Logs {
+			txnHashes = append(txnHashes, log.TxnHash)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	require.Equal(t, []string{"0x0a", "0x0b", "0x0c"}, txnHashes)
+
+	// a cursor encoded before the transaction hash was added is after every
+	// log at its position
+	cursor = base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "%d:%d:%d", chainId, 100, 0))
+	page, err := repo.LogDao().QueryLogs(ctx, LogFilter{ChainId: chainId}, cursor, 0)
+	require.NoError(t, err)
+	require.Empty(t, page.Logs)
+}
+
+func TestLogDao_QueryLogsTiebreak(t *testing.T) {
+	testLogPagesTiebreak(t, newTestPgRepository(t))
+}
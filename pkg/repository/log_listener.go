@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/waynewu411/blocktasks/pkg/config"
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"go.uber.org/zap"
+)
+
+// LogListener follows the logs committed by a task through LISTEN, so that
+// consumers need not poll the Logs table. Notifications sent while it is not
+// listening are lost, so a consumer catches up with QueryLogs from its own
+// checkpoint before listening.
+type LogListener struct {
+	lg       *zap.Logger
+	cfg      config.PgConfig
+	logDao   LogDao
+	taskName string
+}
+
+func NewLogListener(lg *zap.Logger, cfg config.PgConfig, repo Repository, taskName string) *LogListener {
+	return &LogListener{
+		lg:       lg,
+		cfg:      cfg,
+		logDao:   repo.LogDao(),
+		taskName: taskName,
+	}
+}
+
+// Listen calls fn with every log of each range announced by the task, in
+// canonical order, until ctx is done or fn fails. The connection is not
+// re-established when it breaks, the error is returned instead.
+func (l *LogListener) Listen(ctx context.Context, fn func(LogNotification, do.Log) error) error {
+	dsn, err := withSearchPath(l.cfg.Url, l.cfg.Schema)
+	if err != nil {
+		return err
+	}
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	channel := LogChannel(l.taskName)
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return err
+	}
+	l.lg.Info("listening for logs", zap.String("channel", channel))
+
+	for {
+		pgNotification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var notification LogNotification
+		if err := json.Unmarshal([]byte(pgNotification.Payload), &notification); err != nil {
+			l.lg.Error("fail to decode notification", zap.String("channel", channel), zap.String("payload", pgNotification.Payload), zap.Error(err))
+			continue
+		}
+
+		filter := LogFilter{
+			ChainId:         notification.ChainId,
+			FromBlockNumber: notification.FromBlockNumber,
+			ToBlockNumber:   notification.ToBlockNumber,
+		}
+		err = l.logDao.IterateLogs(ctx, filter, func(log do.Log) error {
+			return fn(notification, log)
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+// LogPartition is a partition of the logs of a chain, holding the blocks
+// from FromBlockNumber up to but excluding ToBlockNumber.
+type LogPartition struct {
+	ChainId         int64
+	Name            string
+	FromBlockNumber int64
+	ToBlockNumber   int64
+}
+
+// LogPartitionDao manages the partitions of "Logs", which is partitioned by
+// chain and each chain by block range.
+type LogPartitionDao interface {
+	// GetLogPartitions returns the partitions of the chain ordered by block.
+	GetLogPartitions(ctx context.Context, chainId int64) ([]LogPartition, error)
+	// EnsureLogPartitions creates the missing partitions holding the blocks
+	// from fromBlockNumber to toBlockNumber inclusive.
+	EnsureLogPartitions(ctx context.Context, chainId int64, fromBlockNumber int64, toBlockNumber int64) error
+	DropLogPartition(ctx context.Context, partition LogPartition) error
+	// ArchiveLogPartition detaches the partition and moves it into the
+	// archive schema, where it can still be queried.
+	ArchiveLogPartition(ctx context.Context, partition LogPartition, archiveSchema string) error
+}
+
+type logPartitionDao struct {
+	db         *gorm.DB
+	blockRange int64
+}
+
+func NewLogPartitionDao(db *gorm.DB, blockRange int64) LogPartitionDao {
+	return &logPartitionDao{db: db, blockRange: max(blockRange, 1)}
+}
+
+var partitionBoundPattern = regexp.MustCompile(`FROM \('?(-?\d+)'?\) TO \('?(-?\d+)'?\)`)
+
+func chainLogTableName(chainId int64) string {
+	return fmt.Sprintf("Logs_%d", chainId)
+}
+
+func (p *logPartitionDao) GetLogPartitions(ctx context.Context, chainId int64) ([]LogPartition, error) {
+	return p.getLogPartitions(p.db.WithContext(ctx), chainId)
+}
+
+func (p *logPartitionDao) getLogPartitions(db *gorm.DB, chainId int64) ([]LogPartition, error) {
+	var rows []struct {
+		Name  string
+		Bound string
+	}
+	err := db.Raw(
+		`SELECT c.relname AS name, pg_get_expr(c.relpartbound, c.oid) AS bound
+		FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = to_regclass(?)`,
+		pgx.Identifier{chainLogTableName(chainId)}.Sanitize(),
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, transformGormError(err)
+	}
+
+	partitions := make([]LogPartition, 0, len(rows))
+	for _, row := range rows {
+		match := partitionBoundPattern.FindStringSubmatch(row.Bound)
+		if match == nil {
+			return nil, fmt.Errorf("%w: unexpected bound %s of partition %s", ErrDatabase, row.Bound, row.Name)
+		}
+		from, _ := strconv.ParseInt(match[1], 10, 64)
+		to, _ := strconv.ParseInt(match[2], 10, 64)
+		partitions = append(partitions, LogPartition{ChainId: chainId, Name: row.Name, FromBlockNumber: from, ToBlockNumber: to})
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitions[i].FromBlockNumber < partitions[j].FromBlockNumber
+	})
+
+	return partitions, nil
+}
+
+func (p *logPartitionDao) EnsureLogPartitions(ctx context.Context, chainId int64, fromBlockNumber int64, toBlockNumber int64) error {
+	db := p.db.WithContext(ctx)
+
+	// the partitions usually exist, so look them up before taking the lock
+	partitions, err := p.getLogPartitions(db, chainId)
+	if err != nil {
+		return err
+	}
+	if len(p.missingRanges(partitions, fromBlockNumber, toBlockNumber)) == 0 {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		chainTable := chainLogTableName(chainId)
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtextextended(?, 0))", chainTable).Error; err != nil {
+			return transformGormError(err)
+		}
+
+		err := tx.Exec(fmt.Sprintf(
+			`CREATE TABLE IF NOT EXISTS %s PARTITION OF "Logs" FOR VALUES IN (%d) PARTITION BY RANGE (block_number)`,
+			pgx.Identifier{chainTable}.Sanitize(),
+			chainId,
+		)).Error
+		if err != nil {
+			return transformGormError(err)
+		}
+
+		partitions, err := p.getLogPartitions(tx, chainId)
+		if err != nil {
+			return err
+		}
+		for _, r := range p.missingRanges(partitions, fromBlockNumber, toBlockNumber) {
+			err := tx.Exec(fmt.Sprintf(
+				`CREATE TABLE %s PARTITION OF %s FOR VALUES FROM (%d) TO (%d)`,
+				pgx.Identifier{fmt.Sprintf("%s_%d", chainTable, r.FromBlockNumber)}.Sanitize(),
+				pgx.Identifier{chainTable}.Sanitize(),
+				r.FromBlockNumber,
+				r.ToBlockNumber,
+			)).Error
+			if err != nil {
+				return transformGormError(err)
+			}
+		}
+		return nil
+	})
+}
+
+// missingRanges returns the gaps between the partitions within the aligned
+// block ranges covering fromBlockNumber to toBlockNumber. The partitions
+// must be ordered by block.
+func (p *logPartitionDao) missingRanges(partitions []LogPartition, fromBlockNumber int64, toBlockNumber int64) []LogPartition {
+	var missing []LogPartition
+	lower := fromBlockNumber - fromBlockNumber%p.blockRange
+	upper := toBlockNumber - toBlockNumber%p.blockRange + p.blockRange
+
+	cursor := lower
+	for _, partition := range partitions {
+		if partition.ToBlockNumber <= cursor {
+			continue
+		}
+		if partition.FromBlockNumber >= upper {
+			break
+		}
+		if partition.FromBlockNumber > cursor {
+			missing = append(missing, p.alignedRanges(cursor, partition.FromBlockNumber)...)
+		}
+		cursor = partition.ToBlockNumber
+	}
+	if cursor < upper {
+		missing = append(missing, p.alignedRanges(cursor, upper)...)
+	}
+
+	return missing
+}
+
+// alignedRanges splits the gap at the block range boundaries, so that a gap
+// does not become one oversized partition.
+func (p *logPartitionDao) alignedRanges(from int64, to int64) []LogPartition {
+	var ranges []LogPartition
+	for from < to {
+		next := min(from-from%p.blockRange+p.blockRange, to)
+		ranges = append(ranges, LogPartition{FromBlockNumber: from, ToBlockNumber: next})
+		from = next
+	}
+	return ranges
+}
+
+func (p *logPartitionDao) DropLogPartition(ctx context.Context, partition LogPartition) error {
+	if err := p.db.WithContext(ctx).Exec("DROP TABLE IF EXISTS " + pgx.Identifier{partition.Name}.Sanitize()).Error; err != nil {
+		return transformGormError(err)
+	}
+	return nil
+}
+
+func (p *logPartitionDao) ArchiveLogPartition(ctx context.Context, partition LogPartition, archiveSchema string) error {
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			"CREATE SCHEMA IF NOT EXISTS " + pgx.Identifier{archiveSchema}.Sanitize(),
+			fmt.Sprintf(
+				"ALTER TABLE %s DETACH PARTITION %s",
+				pgx.Identifier{chainLogTableName(partition.ChainId)}.Sanitize(),
+				pgx.Identifier{partition.Name}.Sanitize(),
+			),
+			fmt.Sprintf(
+				"ALTER TABLE %s SET SCHEMA %s",
+				pgx.Identifier{partition.Name}.Sanitize(),
+				pgx.Identifier{archiveSchema}.Sanitize(),
+			),
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return transformGormError(err)
+			}
+		}
+		return nil
+	})
+}
+
+// noopLogPartitionDao stands for a database without partitions, such as
+// SQLite or the in-memory repository.
+type noopLogPartitionDao struct{}
+
+func (noopLogPartitionDao) GetLogPartitions(ctx context.Context, chainId int64) ([]LogPartition, error) {
+	return nil, nil
+}
+
+func (noopLogPartitionDao) EnsureLogPartitions(ctx context.Context, chainId int64, fromBlockNumber int64, toBlockNumber int64) error {
+	return nil
+}
+
+func (noopLogPartitionDao) DropLogPartition(ctx context.Context, partition LogPartition) error {
+	return fmt.Errorf("%w: log partitions are not supported", ErrDatabase)
+}
+
+func (noopLogPartitionDao) ArchiveLogPartition(ctx context.Context, partition LogPartition, archiveSchema string) error {
+	return fmt.Errorf("%w: log partitions are not supported", ErrDatabase)
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogPartitionDao_MissingRanges(t *testing.T) {
+	dao := &logPartitionDao{blockRange: 100}
+
+	tests := []struct {
+		name       string
+		partitions []LogPartition
+		from, to   int64
+		missing    []LogPartition
+	}{
+		{
+			name: "no partitions",
+			from: 150, to: 250,
+			missing: []LogPartition{{FromBlockNumber: 100, ToBlockNumber: 200}, {FromBlockNumber: 200, ToBlockNumber: 300}},
+		},
+		{
+			name:       "covered",
+			partitions: []LogPartition{{FromBlockNumber: 100, ToBlockNumber: 200}},
+			from:       100, to: 199,
+		},
+		{
+			name:       "next partition",
+			partitions: []LogPartition{{FromBlockNumber: 100, ToBlockNumber: 200}},
+			from:       190, to: 210,
+			missing: []LogPartition{{FromBlockNumber: 200, ToBlockNumber: 300}},
+		},
+		{
+			name:       "gap clipped by partitions of another range",
+			partitions: []LogPartition{{FromBlockNumber: 0, ToBlockNumber: 150}, {FromBlockNumber: 180, ToBlockNumber: 400}},
+			from:       120, to: 420,
+			missing: []LogPartition{{FromBlockNumber: 150, ToBlockNumber: 180}, {FromBlockNumber: 400, ToBlockNumber: 500}},
+		},
+	}
+	for _, test := range tests {
+		require.Equal(t, test.missing, dao.missingRanges(test.partitions, test.from, test.to), test.name)
+	}
+}
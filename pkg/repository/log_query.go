@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/waynewu411/blocktasks/pkg/do"
+)
+
+const (
+	DefaultLogQueryLimit = 100
+	MaxLogQueryLimit     = 1000
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidFilter = errors.New("invalid filter")
+)
+
+// LogFilter selects logs. Zero values do not filter. Addresses, topics and
+// the transaction hash are hex strings in either case.
+type LogFilter struct {
+	ChainId         int64
+	Addresses       []string   // logs emitted by any of the addresses
+	Topics          [][]string // Topics[i] matches topic i against any of the values, empty matches any topic
+	FromBlockNumber int64
+	ToBlockNumber   int64 // inclusive
+	FromTime        time.Time
+	ToTime          time.Time // inclusive
+	TxnHash         string
+	Removed         *bool // nil matches logs either removed or not
+}
+
+// LogCursor is the position of a log in the canonical order of chain,
+// block number, log index and transaction hash. The transaction hash only
+// breaks the tie between logs stored at the same position, e.g. a log moved
+// by a reorg whose replacement was stored before the move was seen.
+type LogCursor struct {
+	ChainId     int64
+	BlockNumber int64
+	LogIndex    int64
+	TxnHash     string // empty in the cursors encoded before it was added
+}
+
+func NewLogCursor(log do.Log) LogCursor {
+	return LogCursor{ChainId: log.ChainId, BlockNumber: log.BlockNumber, LogIndex: log.LogIndex, TxnHash: log.TxnHash}
+}
+
+// Encode returns the cursor as an opaque string.
+func (c LogCursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "%d:%d:%d:%s", c.ChainId, c.BlockNumber, c.LogIndex, c.TxnHash))
+}
+
+// DecodeLogCursor parses a cursor returned by Encode. The empty string is
+// the start of the result set.
+func DecodeLogCursor(s string) (*LogCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parts := strings.Split(string(b), ":")
+	if len(parts) != 3 && len(parts) != 4 {
+		return nil, ErrInvalidCursor
+	}
+	var c LogCursor
+	if n, err := fmt.Sscanf(strings.Join(parts[:3], ":"), "%d:%d:%d", &c.ChainId, &c.BlockNumber, &c.LogIndex); err != nil || n != 3 {
+		return nil, ErrInvalidCursor
+	}
+	if len(parts) == 4 {
+		if c.TxnHash, err = normalizeHex(parts[3]); err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+	return &c, nil
+}
+
+type LogPage struct {
+	Logs       []do.Log
+	NextCursor string // empty on the last page
+}
+
+// logQuery returns at most limit logs matching the filter in canonical
+// order, after the cursor when it is not nil.
+type logQuery func(ctx context.Context, filter LogFilter, after *LogCursor, limit int) ([]do.Log, error)
+
+func queryLogPage(ctx context.Context, query logQuery, filter LogFilter, cursor string, limit int) (LogPage, error) {
+	after, err := DecodeLogCursor(cursor)
+	if err != nil {
+		return LogPage{}, err
+	}
+	if limit <= 0 {
+		limit = DefaultLogQueryLimit
+	}
+	limit = min(limit, MaxLogQueryLimit)
+
+	logs, err := query(ctx, filter, after, limit+1)
+	if err != nil {
+		return LogPage{}, err
+	}
+
+	page := LogPage{Logs: logs}
+	if len(logs) > limit {
+		page.Logs = logs[:limit]
+		page.NextCursor = NewLogCursor(page.Logs[limit-1]).Encode()
+	}
+	return page, nil
+}
+
+func iterateLogs(ctx context.Context, query logQuery, filter LogFilter, fn func(do.Log) error) error {
+	var after *LogCursor
+	for {
+		logs, err := query(ctx, filter, after, MaxLogQueryLimit)
+		if err != nil {
+			return err
+		}
+		for _, log := range logs {
+			if err := fn(log); err != nil {
+				return err
+			}
+		}
+		if len(logs) < MaxLogQueryLimit {
+			return nil
+		}
+		cursor := NewLogCursor(logs[len(logs)-1])
+		after = &cursor
+	}
+}
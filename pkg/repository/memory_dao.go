@@ -0,0 +1,654 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"github.com/waynewu411/blocktasks/pkg/metrics"
+)
+
+type memoryTaskDao struct {
+	r *memoryRepository
+}
+
+func (t *memoryTaskDao) InsertTask(ctx context.Context, task do.Task) (do.Task, error) {
+	err := t.r.write(ctx, func(data *memoryData) error {
+		if _, ok := data.tasks[task.Name]; ok {
+			return ErrDuplicatedKey
+		}
+		data.tasks[task.Name] = task
+		return nil
+	})
+	if err != nil {
+		return do.Task{}, err
+	}
+	return task, nil
+}
+
+func (t *memoryTaskDao) UpdateTask(ctx context.Context, task do.Task) (do.Task, error) {
+	err := t.r.write(ctx, func(data *memoryData) error {
+		stored, ok := data.tasks[task.Name]
+		if !ok || stored.FencingToken != task.FencingToken {
+			return ErrStaleFencingToken
+		}
+		if stored.Revision != task.Revision {
+			return ErrTaskRevised
+		}
+		stored.LastProcessedBlockNumber = task.LastProcessedBlockNumber
+		stored.LastProcessedBlockTimestamp = task.LastProcessedBlockTimestamp
+		data.tasks[task.Name] = stored
+		return nil
+	})
+	if err != nil {
+		return do.Task{}, err
+	}
+	return task, nil
+}
+
+func (t *memoryTaskDao) UpdateLease(ctx context.Context, task do.Task) (do.Task, error) {
+	err := t.r.write(ctx, func(data *memoryData) error {
+		stored, ok := data.tasks[task.Name]
+		if !ok {
+			return nil
+		}
+		stored.Owner = task.Owner
+		stored.LeaseExpiresAt = task.LeaseExpiresAt
+		stored.FencingToken = task.FencingToken
+		data.tasks[task.Name] = stored
+		return nil
+	})
+	if err != nil {
+		return do.Task{}, err
+	}
+	return task, nil
+}
+
+func (t *memoryTaskDao) ReviseTask(ctx context.Context, task do.Task) (do.Task, error) {
+	err := t.r.write(ctx, func(data *memoryData) error {
+		stored, ok := data.tasks[task.Name]
+		if !ok || stored.Revision != task.Revision {
+			return ErrTaskRevised
+		}
+		stored.LastProcessedBlockNumber = task.LastProcessedBlockNumber
+		stored.LastProcessedBlockTimestamp = task.LastProcessedBlockTimestamp
+		stored.Paused = task.Paused
+		stored.Revision++
+		data.tasks[task.Name] = stored
+		task = stored
+		return nil
+	})
+	if err != nil {
+		return do.Task{}, err
+	}
+	return task, nil
+}
+
+func (t *memoryTaskDao) GetTask(ctx context.Context, name string) (do.Task, error) {
+	var task do.Task
+	err := t.r.read(ctx, func(data *memoryData) error {
+		var ok bool
+		if task, ok = data.tasks[name]; !ok {
+			return ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return do.Task{}, err
+	}
+	return task, nil
+}
+
+// GetTaskForUpdate needs no lock, since transactions are serialized.
+func (t *memoryTaskDao) GetTaskForUpdate(ctx context.Context, name string) (do.Task, error) {
+	return t.GetTask(ctx, name)
+}
+
+// GetTaskForShare needs no lock, since transactions are serialized.
+func (t *memoryTaskDao) GetTaskForShare(ctx context.Context, name string) (do.Task, error) {
+	return t.GetTask(ctx, name)
+}
+
+func (t *memoryTaskDao) GetTasks(ctx context.Context) ([]do.Task, error) {
+	var tasks []do.Task
+	err := t.r.read(ctx, func(data *memoryData) error {
+		tasks = slices.SortedFunc(maps.Values(data.tasks), func(a, b do.Task) int {
+			return cmp.Compare(a.Name, b.Name)
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (t *memoryTaskDao) Now(ctx context.Context) (time.Time, error) {
+	return time.Now(), nil
+}
+
+type memoryLogDao struct {
+	r *memoryRepository
+}
+
+func (e *memoryLogDao) InsertLogs(ctx context.Context, logs []do.Log) error {
+	return e.r.write(ctx, func(data *memoryData) error {
+		keys := map[logIdentity]memoryLogKey{}
+		for key, log := range data.logs {
+			keys[newLogIdentity(log)] = key
+		}
+
+		changedAt := time.Now()
+		for _, log := range logs {
+			log, err := normalizeLog(log)
+			if err != nil {
+				return err
+			}
+			identity := newLogIdentity(log)
+
+			key, ok := keys[identity]
+			if !ok {
+				key = memoryLogKey{log.ChainId, log.BlockNumber, log.TxnHash, log.LogIndex}
+				data.logs[key] = log
+				keys[identity] = key
+				continue
+			}
+
+			stored := data.logs[key]
+			change, changed := newLogChange(stored, log, changedAt)
+			if !changed {
+				continue
+			}
+			change.Id = int64(len(data.logChanges)) + 1
+			data.logChanges = append(data.logChanges, change)
+			metrics.ReorgedLogs.Inc()
+
+			stored.BlockNumber = log.BlockNumber
+			stored.BlockHash = log.BlockHash
+			stored.Removed = log.Removed
+			stored.Timestamp = log.Timestamp
+			stored.BlockTimestamp = log.BlockTimestamp
+			delete(data.logs, key)
+			key = memoryLogKey{stored.ChainId, stored.BlockNumber, stored.TxnHash, stored.LogIndex}
+			data.logs[key] = stored
+			keys[identity] = key
+		}
+		return nil
+	})
+}
+
+func (e *memoryLogDao) GetLogsWithoutAddress(ctx context.Context, chainId int64, after *do.Log, limit int) ([]do.Log, error) {
+	compare := func(a, b do.Log) int {
+		return cmp.Or(
+			cmp.Compare(a.BlockNumber, b.BlockNumber),
+			cmp.Compare(a.TxnHash, b.TxnHash),
+			cmp.Compare(a.LogIndex, b.LogIndex),
+		)
+	}
+	if after != nil {
+		txnHash, err := normalizeHex(after.TxnHash)
+		if err != nil {
+			return nil, err
+		}
+		after = &do.Log{BlockNumber: after.BlockNumber, TxnHash: txnHash, LogIndex: after.LogIndex}
+	}
+
+	var logs []do.Log
+	err := e.r.read(ctx, func(data *memoryData) error {
+		for _, log := range data.logs {
+			if log.ChainId == chainId && log.Address == "" && (after == nil || compare(log, *after) > 0) {
+				logs = append(logs, log)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(logs, compare)
+	return logs[:min(len(logs), limit)], nil
+}
+
+func (e *memoryLogDao) CountLogsWithoutAddress(ctx context.Context) (int64, error) {
+	var count int64
+	err := e.r.read(ctx, func(data *memoryData) error {
+		for _, log := range data.logs {
+			if log.Address == "" {
+				count++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (e *memoryLogDao) UpdateLogMetadata(ctx context.Context, logs []do.Log) error {
+	return e.r.write(ctx, func(data *memoryData) error {
+		for _, log := range logs {
+			txnHash, err := normalizeHex(log.TxnHash)
+			if err != nil {
+				return err
+			}
+			// an unknown address stays unset, as in Postgres
+			if log.Address == "" {
+				continue
+			}
+			address, err := normalizeHex(log.Address)
+			if err != nil {
+				return err
+			}
+			key := memoryLogKey{log.ChainId, log.BlockNumber, txnHash, log.LogIndex}
+			stored, ok := data.logs[key]
+			if !ok {
+				continue
+			}
+			stored.Address = address
+			stored.TxnIndex = log.TxnIndex
+			data.logs[key] = stored
+		}
+		return nil
+	})
+}
+
+func (e *memoryLogDao) GetLogChanges(ctx context.Context, chainId int64, txnHash string) ([]do.LogChange, error) {
+	txnHash, err := normalizeHex(txnHash)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidFilter, txnHash, err)
+	}
+	var changes []do.LogChange
+	err = e.r.read(ctx, func(data *memoryData) error {
+		for _, change := range data.logChanges {
+			if change.ChainId == chainId && change.TxnHash == txnHash {
+				changes = append(changes, change)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func (e *memoryLogDao) QueryLogs(ctx context.Context, filter LogFilter, cursor string, limit int) (LogPage, error) {
+	return queryLogPage(ctx, e.queryLogs, filter, cursor, limit)
+}
+
+func (e *memoryLogDao) IterateLogs(ctx context.Context, filter LogFilter, fn func(do.Log) error) error {
+	return iterateLogs(ctx, e.queryLogs, filter, fn)
+}
+
+func (e *memoryLogDao) queryLogs(ctx context.Context, filter LogFilter, after *LogCursor, limit int) ([]do.Log, error) {
+	match, err := newLogMatcher(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var logs []do.Log
+	err = e.r.read(ctx, func(data *memoryData) error {
+		for _, log := range data.logs {
+			if match(log) && (after == nil || logAfterCursor(log, *after)) {
+				logs = append(logs, log)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(logs, func(a, b do.Log) int {
+		return compareLogCursors(NewLogCursor(a), NewLogCursor(b))
+	})
+	return logs[:min(len(logs), limit)], nil
+}
+
+func compareLogCursors(a, b LogCursor) int {
+	return cmp.Or(
+		cmp.Compare(a.ChainId, b.ChainId),
+		cmp.Compare(a.BlockNumber, b.BlockNumber),
+		cmp.Compare(a.LogIndex, b.LogIndex),
+		cmp.Compare(a.TxnHash, b.TxnHash),
+	)
+}
+
+// logAfterCursor tells whether the log comes after the cursor. A cursor
+// without a transaction hash is after every log at its position.
+func logAfterCursor(log do.Log, after LogCursor) bool {
+	if after.TxnHash == "" {
+		log.TxnHash = ""
+	}
+	return compareLogCursors(NewLogCursor(log), after) > 0
+}
+
+// newLogMatcher returns whether a stored log matches the filter, comparing
+// the hex values as they are normalized by normalizeLog.
+func newLogMatcher(filter LogFilter) (func(do.Log) bool, error) {
+	if len(filter.Topics) > 4 {
+		return nil, fmt.Errorf("%w: at most 4 topic positions", ErrInvalidFilter)
+	}
+	hexSet := func(values []string) (map[string]bool, error) {
+		filterValues, err := hexFilterValues(values)
+		if err != nil {
+			return nil, err
+		}
+		return lo.SliceToMap(filterValues, func(b []byte) (string, bool) {
+			return do.BytesToHex(b), true
+		}), nil
+	}
+
+	addresses, err := hexSet(filter.Addresses)
+	if err != nil {
+		return nil, err
+	}
+	var topics [4]map[string]bool
+	for i := range filter.Topics {
+		if topics[i], err = hexSet(filter.Topics[i]); err != nil {
+			return nil, err
+		}
+	}
+	txnHashes, err := hexSet(lo.Compact([]string{filter.TxnHash}))
+	if err != nil {
+		return nil, err
+	}
+
+	return func(log do.Log) bool {
+		switch {
+		case filter.ChainId != 0 && log.ChainId != filter.ChainId,
+			len(addresses) > 0 && !addresses[log.Address],
+			filter.FromBlockNumber != 0 && log.BlockNumber < filter.FromBlockNumber,
+			filter.ToBlockNumber != 0 && log.BlockNumber > filter.ToBlockNumber,
+			!filter.FromTime.IsZero() && log.BlockTimestamp.Before(filter.FromTime),
+			!filter.ToTime.IsZero() && log.BlockTimestamp.After(filter.ToTime),
+			len(txnHashes) > 0 && !txnHashes[log.TxnHash],
+			filter.Removed != nil && log.Removed != *filter.Removed:
+			return false
+		}
+		columns := log.TopicColumns()
+		for i := range topics {
+			if len(topics[i]) > 0 && !topics[i][columns[i]] {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+type memoryBackfillChunkDao struct {
+	r *memoryRepository
+}
+
+func (b *memoryBackfillChunkDao) InsertChunks(ctx context.Context, chunks []do.BackfillChunk) error {
+	return b.r.write(ctx, func(data *memoryData) error {
+		for _, chunk := range chunks {
+			key := memoryChunkKey{chunk.TaskName, chunk.FromBlockNumber}
+			if _, ok := data.chunks[key]; !ok {
+				data.chunks[key] = chunk
+			}
+		}
+		return nil
+	})
+}
+
+func (b *memoryBackfillChunkDao) UpdateChunk(ctx context.Context, chunk do.BackfillChunk) (do.BackfillChunk, error) {
+	err := b.r.write(ctx, func(data *memoryData) error {
+		data.chunks[memoryChunkKey{chunk.TaskName, chunk.FromBlockNumber}] = chunk
+		return nil
+	})
+	if err != nil {
+		return do.BackfillChunk{}, err
+	}
+	return chunk, nil
+}
+
+func (b *memoryBackfillChunkDao) GetChunks(ctx context.Context, taskName string) ([]do.BackfillChunk, error) {
+	var chunks []do.BackfillChunk
+	err := b.r.read(ctx, func(data *memoryData) error {
+		for _, chunk := range data.chunks {
+			if chunk.TaskName == taskName {
+				chunks = append(chunks, chunk)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(chunks, func(a, b do.BackfillChunk) int {
+		return cmp.Compare(a.FromBlockNumber, b.FromBlockNumber)
+	})
+	return chunks, nil
+}
+
+type memoryDeadLetterDao struct {
+	r *memoryRepository
+}
+
+func (d *memoryDeadLetterDao) RecordDeadLetter(ctx context.Context, deadLetter do.DeadLetter) (do.DeadLetter, error) {
+	err := d.r.write(ctx, func(data *memoryData) error {
+		for id, stored := range data.deadLetters {
+			if stored.TaskName != deadLetter.TaskName ||
+				stored.FromBlockNumber != deadLetter.FromBlockNumber ||
+				stored.ToBlockNumber != deadLetter.ToBlockNumber {
+				continue
+			}
+			stored.Error = deadLetter.Error
+			stored.Attempts += deadLetter.Attempts
+			stored.Status = deadLetter.Status
+			stored.Skipped = deadLetter.Skipped
+			stored.LastFailedAt = deadLetter.LastFailedAt
+			data.deadLetters[id] = stored
+			deadLetter.Id = id
+			return nil
+		}
+
+		data.deadLetterId++
+		deadLetter.Id = data.deadLetterId
+		data.deadLetters[deadLetter.Id] = deadLetter
+		return nil
+	})
+	if err != nil {
+		return do.DeadLetter{}, err
+	}
+	return deadLetter, nil
+}
+
+func (d *memoryDeadLetterDao) UpdateDeadLetter(ctx context.Context, deadLetter do.DeadLetter) (do.DeadLetter, error) {
+	err := d.r.write(ctx, func(data *memoryData) error {
+		if deadLetter.Id == 0 {
+			data.deadLetterId++
+			deadLetter.Id = data.deadLetterId
+		}
+		data.deadLetters[deadLetter.Id] = deadLetter
+		return nil
+	})
+	if err != nil {
+		return do.DeadLetter{}, err
+	}
+	return deadLetter, nil
+}
+
+func (d *memoryDeadLetterDao) GetDeadLetters(ctx context.Context, taskName string, status string) ([]do.DeadLetter, error) {
+	var deadLetters []do.DeadLetter
+	err := d.r.read(ctx, func(data *memoryData) error {
+		for _, deadLetter := range data.deadLetters {
+			if deadLetter.TaskName == taskName && deadLetter.Status == status {
+				deadLetters = append(deadLetters, deadLetter)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(deadLetters, func(a, b do.DeadLetter) int {
+		return cmp.Compare(a.FromBlockNumber, b.FromBlockNumber)
+	})
+	return deadLetters, nil
+}
+
+type memoryBlockDao struct {
+	r *memoryRepository
+}
+
+func (b *memoryBlockDao) InsertBlocks(ctx context.Context, blocks []do.Block) error {
+	return b.r.write(ctx, func(data *memoryData) error {
+		for _, block := range blocks {
+			data.blocks[memoryBlockKey{block.ChainId, block.BlockNumber}] = block
+		}
+		return nil
+	})
+}
+
+func (b *memoryBlockDao) GetBlock(ctx context.Context, chainId int64, blockNumber int64) (do.Block, error) {
+	var block do.Block
+	err := b.r.read(ctx, func(data *memoryData) error {
+		var ok bool
+		if block, ok = data.blocks[memoryBlockKey{chainId, blockNumber}]; !ok {
+			return ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return do.Block{}, err
+	}
+	return block, nil
+}
+
+func (b *memoryBlockDao) GetBlocks(ctx context.Context, chainId int64, fromBlockNumber int64, toBlockNumber int64) ([]do.Block, error) {
+	var blocks []do.Block
+	err := b.r.read(ctx, func(data *memoryData) error {
+		for _, block := range data.blocks {
+			if block.ChainId == chainId && block.BlockNumber >= fromBlockNumber && block.BlockNumber <= toBlockNumber {
+				blocks = append(blocks, block)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(blocks, func(a, b do.Block) int {
+		return cmp.Compare(a.BlockNumber, b.BlockNumber)
+	})
+	return blocks, nil
+}
+
+type memoryTransactionDao struct {
+	r *memoryRepository
+}
+
+func (t *memoryTransactionDao) InsertTransactions(ctx context.Context, txns []do.Transaction) error {
+	return t.r.write(ctx, func(data *memoryData) error {
+		for _, txn := range txns {
+			txnHash, err := normalizeHex(txn.TxnHash)
+			if err != nil {
+				return err
+			}
+			key := memoryTransactionKey{txn.ChainId, txnHash}
+			// only the position of a stored transaction is updated
+			if stored, ok := data.transactions[key]; ok {
+				stored.BlockNumber = txn.BlockNumber
+				stored.BlockHash = txn.BlockHash
+				stored.TxnIndex = txn.TxnIndex
+				txn = stored
+			}
+			data.transactions[key] = txn
+		}
+		return nil
+	})
+}
+
+func (t *memoryTransactionDao) GetTransaction(ctx context.Context, chainId int64, txnHash string) (do.Transaction, error) {
+	txnHash, err := normalizeHex(txnHash)
+	if err != nil {
+		return do.Transaction{}, fmt.Errorf("%w: %s: %w", ErrInvalidFilter, txnHash, err)
+	}
+	var txn do.Transaction
+	err = t.r.read(ctx, func(data *memoryData) error {
+		var ok bool
+		if txn, ok = data.transactions[memoryTransactionKey{chainId, txnHash}]; !ok {
+			return ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return do.Transaction{}, err
+	}
+	return txn, nil
+}
+
+func (t *memoryTransactionDao) GetTransactionsByBlock(ctx context.Context, chainId int64, blockNumber int64) ([]do.Transaction, error) {
+	var txns []do.Transaction
+	err := t.r.read(ctx, func(data *memoryData) error {
+		for _, txn := range data.transactions {
+			if txn.ChainId == chainId && txn.BlockNumber == blockNumber {
+				txns = append(txns, txn)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(txns, func(a, b do.Transaction) int {
+		return cmp.Compare(a.TxnIndex, b.TxnIndex)
+	})
+	return txns, nil
+}
+
+type memoryProcessedRangeDao struct {
+	r *memoryRepository
+}
+
+func (p *memoryProcessedRangeDao) InsertProcessedRange(ctx context.Context, processedRange do.ProcessedRange) (do.ProcessedRange, error) {
+	err := p.r.write(ctx, func(data *memoryData) error {
+		processedRange.Id = int64(len(data.processedRanges)) + 1
+		data.processedRanges = append(data.processedRanges, processedRange)
+		return nil
+	})
+	if err != nil {
+		return do.ProcessedRange{}, err
+	}
+	return processedRange, nil
+}
+
+func (p *memoryProcessedRangeDao) QueryProcessedRanges(ctx context.Context, filter ProcessedRangeFilter, limit int) ([]do.ProcessedRange, error) {
+	var processedRanges []do.ProcessedRange
+	err := p.r.read(ctx, func(data *memoryData) error {
+		for _, r := range data.processedRanges {
+			switch {
+			case filter.TaskName != "" && r.TaskName != filter.TaskName,
+				filter.ChainId != 0 && r.ChainId != filter.ChainId,
+				filter.BlockNumber != 0 && (r.FromBlockNumber > filter.BlockNumber || r.ToBlockNumber < filter.BlockNumber),
+				filter.InstanceId != "" && r.InstanceId != filter.InstanceId,
+				!filter.FromTime.IsZero() && r.CommittedAt.Before(filter.FromTime),
+				!filter.ToTime.IsZero() && r.CommittedAt.After(filter.ToTime):
+				continue
+			}
+			processedRanges = append(processedRanges, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(processedRanges, func(a, b do.ProcessedRange) int {
+		return cmp.Or(b.CommittedAt.Compare(a.CommittedAt), cmp.Compare(b.Id, a.Id))
+	})
+	return processedRanges[:min(len(processedRanges), processedRangeQueryLimit(limit))], nil
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/waynewu411/blocktasks/pkg/do"
+)
+
+// memoryRepository keeps everything in memory, for tests. Transactions are
+// serialized and work on a copy of the data, which replaces the data when
+// they commit, so that a failed transaction leaves nothing behind.
+type memoryRepository struct {
+	store *memoryStore
+	// tx is the data of the transaction, nil outside a transaction
+	tx *memoryData
+}
+
+type memoryStore struct {
+	mu   sync.Mutex
+	data *memoryData
+}
+
+type memoryData struct {
+	tasks           map[string]do.Task
+	logs            map[memoryLogKey]do.Log
+	logChanges      []do.LogChange
+	chunks          map[memoryChunkKey]do.BackfillChunk
+	deadLetters     map[int64]do.DeadLetter
+	deadLetterId    int64
+	blocks          map[memoryBlockKey]do.Block
+	transactions    map[memoryTransactionKey]do.Transaction
+	processedRanges []do.ProcessedRange
+}
+
+type memoryLogKey struct {
+	chainId     int64
+	blockNumber int64
+	txnHash     string
+	logIndex    int64
+}
+
+type memoryChunkKey struct {
+	taskName        string
+	fromBlockNumber int64
+}
+
+type memoryBlockKey struct {
+	chainId     int64
+	blockNumber int64
+}
+
+type memoryTransactionKey struct {
+	chainId int64
+	txnHash string
+}
+
+func NewMemoryRepository() Repository {
+	return &memoryRepository{
+		store: &memoryStore{
+			data: &memoryData{
+				tasks:        map[string]do.Task{},
+				logs:         map[memoryLogKey]do.Log{},
+				chunks:       map[memoryChunkKey]do.BackfillChunk{},
+				deadLetters:  map[int64]do.DeadLetter{},
+				blocks:       map[memoryBlockKey]do.Block{},
+				transactions: map[memoryTransactionKey]do.Transaction{},
+			},
+		},
+	}
+}
+
+func (d *memoryData) clone() *memoryData {
+	return &memoryData{
+		tasks:           maps.Clone(d.tasks),
+		logs:            maps.Clone(d.logs),
+		logChanges:      slices.Clone(d.logChanges),
+		chunks:          maps.Clone(d.chunks),
+		deadLetters:     maps.Clone(d.deadLetters),
+		deadLetterId:    d.deadLetterId,
+		blocks:          maps.Clone(d.blocks),
+		transactions:    maps.Clone(d.transactions),
+		processedRanges: slices.Clone(d.processedRanges),
+	}
+}
+
+// Transaction holds the lock of the store until fn returns. A nested
+// transaction rolls back to where it started, like a savepoint.
+func (r *memoryRepository) Transaction(fn func(Repository) error) error {
+	if r.tx != nil {
+		data := r.tx.clone()
+		if err := fn(&memoryRepository{store: r.store, tx: data}); err != nil {
+			return err
+		}
+		*r.tx = *data
+		return nil
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	data := r.store.data.clone()
+	if err := fn(&memoryRepository{store: r.store, tx: data}); err != nil {
+		return err
+	}
+	r.store.data = data
+	return nil
+}
+
+// read calls fn with the data of the transaction, or with the committed data.
+func (r *memoryRepository) read(ctx context.Context, fn func(*memoryData) error) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrDatabase, err)
+	}
+	if r.tx != nil {
+		return fn(r.tx)
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return fn(r.store.data)
+}
+
+// write calls fn in a transaction of its own unless it is already inside
+// one, so that a failed write changes nothing.
+func (r *memoryRepository) write(ctx context.Context, fn func(*memoryData) error) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrDatabase, err)
+	}
+	if r.tx != nil {
+		return fn(r.tx)
+	}
+	return r.Transaction(func(repo Repository) error {
+		return fn(repo.(*memoryRepository).tx)
+	})
+}
+
+func (r *memoryRepository) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (r *memoryRepository) TaskDao() TaskDao {
+	return &memoryTaskDao{r: r}
+}
+
+func (r *memoryRepository) LogDao() LogDao {
+	return &memoryLogDao{r: r}
+}
+
+func (r *memoryRepository) BackfillChunkDao() BackfillChunkDao {
+	return &memoryBackfillChunkDao{r: r}
+}
+
+func (r *memoryRepository) DeadLetterDao() DeadLetterDao {
+	return &memoryDeadLetterDao{r: r}
+}
+
+func (r *memoryRepository) LogPartitionDao() LogPartitionDao {
+	return noopLogPartitionDao{}
+}
+
+func (r *memoryRepository) BlockDao() BlockDao {
+	return &memoryBlockDao{r: r}
+}
+
+func (r *memoryRepository) TransactionDao() TransactionDao {
+	return &memoryTransactionDao{r: r}
+}
+
+func (r *memoryRepository) ProcessedRangeDao() ProcessedRangeDao {
+	return &memoryProcessedRangeDao{r: r}
+}
+
+func (r *memoryRepository) NotificationDao() NotificationDao {
+	return noopNotificationDao{}
+}
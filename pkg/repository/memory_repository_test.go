@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/waynewu411/blocktasks/pkg/do"
+)
+
+func TestMemoryRepository_Transaction(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	_, err := repo.TaskDao().InsertTask(ctx, do.Task{Name: "Task_1", LastProcessedBlockNumber: 100})
+	require.NoError(t, err)
+	_, err = repo.TaskDao().InsertTask(ctx, do.Task{Name: "Task_1"})
+	require.ErrorIs(t, err, ErrDuplicatedKey)
+
+	err = repo.Transaction(func(repo Repository) error {
+		if _, err := repo.TaskDao().UpdateTask(ctx, do.Task{Name: "Task_1", LastProcessedBlockNumber: 200}); err != nil {
+			return err
+		}
+		// the nested transaction rolls back on its own
+		err := repo.Transaction(func(repo Repository) error {
+			if _, err := repo.TaskDao().UpdateTask(ctx, do.Task{Name: "Task_1", LastProcessedBlockNumber: 300}); err != nil {
+				return err
+			}
+			return errors.New("Revert")
+		})
+		require.Error(t, err)
+
+		task, err := repo.TaskDao().GetTask(ctx, "Task_1")
+		require.NoError(t, err)
+		require.Equal(t, int64(200), task.LastProcessedBlockNumber)
+
+		return errors.New("Revert")
+	})
+	require.Error(t, err)
+
+	task, err := repo.TaskDao().GetTask(ctx, "Task_1")
+	require.NoError(t, err)
+	require.Equal(t, int64(100), task.LastProcessedBlockNumber)
+
+	_, err = repo.TaskDao().UpdateTask(ctx, do.Task{Name: "Task_1", FencingToken: 1})
+	require.ErrorIs(t, err, ErrStaleFencingToken)
+}
+
+func TestMemoryRepository_QueryLogs(t *testing.T) {
+	repo := NewMemoryRepository()
+	chainId := insertTestLogs(t, repo)
+	ctx := context.Background()
+
+	// inserting the same logs again is a no-op
+	insertTestLogsOnChain(t, repo, chainId)
+
+	var logs []do.Log
+	err := repo.LogDao().IterateLogs(ctx, LogFilter{ChainId: chainId}, func(log do.Log) error {
+		logs = append(logs, log)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 12)
+
+	page, err := repo.LogDao().QueryLogs(ctx, LogFilter{ChainId: chainId, Addresses: []string{"0xA1"}, Topics: [][]string{{"0xF0"}}}, "", 3)
+	require.NoError(t, err)
+	require.Len(t, page.Logs, 3)
+	require.Equal(t, "0xa1", page.Logs[0].Address)
+	page, err = repo.LogDao().QueryLogs(ctx, LogFilter{ChainId: chainId, Addresses: []string{"0xA1"}}, page.NextCursor, 3)
+	require.NoError(t, err)
+	require.Len(t, page.Logs, 1)
+	require.Empty(t, page.NextCursor)
+
+	_, err = repo.LogDao().QueryLogs(ctx, LogFilter{Addresses: []string{"0xzz"}}, "", 0)
+	require.ErrorIs(t, err, ErrInvalidFilter)
+}
+
+func TestMemoryRepository_InsertLogsUpsert(t *testing.T) {
+	testLogUpsert(t, NewMemoryRepository())
+}
+
+func TestMemoryRepository_QueryLogsTiebreak(t *testing.T) {
+	testLogPagesTiebreak(t, NewMemoryRepository())
+}
+
+func TestMemoryRepository_Transactions(t *testing.T) {
+	testTransactions(t, NewMemoryRepository())
+}
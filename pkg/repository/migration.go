@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrSchemaAhead means the database was migrated by a newer binary
+	ErrSchemaAhead = errors.New("database schema is ahead of the binary")
+	// ErrSchemaBehind means the database has pending migrations
+	ErrSchemaBehind = errors.New("database schema is behind the binary")
+)
+
+// Migrator applies the versioned migrations embedded in the binary and
+// records the applied versions in the database.
+type Migrator interface {
+	// Up applies every pending migration.
+	Up(ctx context.Context) error
+	// Down reverts the latest applied migrations, at most steps of them.
+	Down(ctx context.Context, steps int64) error
+	// Version returns the latest version applied to the database, 0 when
+	// no migration was applied.
+	Version(ctx context.Context) (int64, error)
+	// LatestVersion returns the latest version known to the binary.
+	LatestVersion() int64
+}
+
+type migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// loadMigrations reads the migrations from files named
+// <version>_<name>.up.sql and <version>_<name>.down.sql in dir. Versions
+// must start from 1 without gaps, and every migration needs both files.
+func loadMigrations(fsys fs.FS, dir string) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*migration{}
+	for _, entry := range entries {
+		fileName := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(fileName, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		versionStr, name, ok := strings.Cut(strings.TrimSuffix(fileName, "."+direction+".sql"), "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name %s", fileName)
+		}
+		version, err := strconv.ParseInt(versionStr, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", fileName)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, fileName))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("migration %d has different names %s and %s", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both up and down files", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i, m := range migrations {
+		if m.Version != int64(i+1) {
+			return nil, fmt.Errorf("missing migration version %d", i+1)
+		}
+	}
+
+	return migrations, nil
+}
+
+// CheckSchemaVersion refuses a database which is ahead of the binary, or
+// which still has pending migrations.
+func CheckSchemaVersion(ctx context.Context, migrator Migrator) error {
+	version, err := migrator.Version(ctx)
+	if err != nil {
+		return err
+	}
+	latestVersion := migrator.LatestVersion()
+	switch {
+	case version > latestVersion:
+		return fmt.Errorf("%w: database version %d, binary version %d", ErrSchemaAhead, version, latestVersion)
+	case version < latestVersion:
+		return fmt.Errorf("%w: database version %d, binary version %d", ErrSchemaBehind, version, latestVersion)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations_Pg(t *testing.T) {
+	migrations, err := loadMigrations(pgMigrationFS, "migrations/pg")
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	for i, m := range migrations {
+		require.Equal(t, int64(i+1), m.Version)
+		require.NotEmpty(t, m.Up)
+		require.NotEmpty(t, m.Down)
+	}
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"missing down": {
+			"m/0001_init.up.sql": {Data: []byte("SELECT 1;")},
+		},
+		"gap": {
+			"m/0001_init.up.sql":   {Data: []byte("SELECT 1;")},
+			"m/0001_init.down.sql": {Data: []byte("SELECT 1;")},
+			"m/0003_next.up.sql":   {Data: []byte("SELECT 1;")},
+			"m/0003_next.down.sql": {Data: []byte("SELECT 1;")},
+		},
+		"bad version": {
+			"m/init.up.sql": {Data: []byte("SELECT 1;")},
+		},
+	}
+	for name, fsys := range tests {
+		_, err := loadMigrations(fsys, "m")
+		require.Error(t, err, name)
+	}
+}
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "Logs";
+
+DROP TABLE IF EXISTS "Tasks";
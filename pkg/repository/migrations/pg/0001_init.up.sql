@@ -1,10 +1,10 @@
-CREATE TABLE "Tasks" (
+CREATE TABLE IF NOT EXISTS "Tasks" (
     name VARCHAR(256) NOT NULL PRIMARY KEY,
     last_processed_block_number BIGINT DEFAULT 0,
     last_processed_block_timestamp BIGINT DEFAULT 0
 );
 
-CREATE TABLE "Logs" (
+CREATE TABLE IF NOT EXISTS "Logs" (
     chain_id BIGINT NOT NULL,
     block_number BIGINT NOT NULL,
     block_hash VARCHAR(256) NOT NULL,
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS "BackfillChunks" (
+    task_name VARCHAR(256) NOT NULL,
+    from_block_number BIGINT NOT NULL,
+    to_block_number BIGINT NOT NULL,
+    last_processed_block_number BIGINT NOT NULL,
+    completed BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (task_name, from_block_number)
+);
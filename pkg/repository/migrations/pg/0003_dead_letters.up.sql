@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS "DeadLetters" (
+    id BIGSERIAL PRIMARY KEY,
+    task_name VARCHAR(256) NOT NULL,
+    from_block_number BIGINT NOT NULL,
+    to_block_number BIGINT NOT NULL,
+    error TEXT NOT NULL,
+    attempts BIGINT NOT NULL DEFAULT 0,
+    status VARCHAR(32) NOT NULL,
+    skipped BOOLEAN NOT NULL DEFAULT FALSE,
+    first_failed_at TIMESTAMPTZ NOT NULL,
+    last_failed_at TIMESTAMPTZ NOT NULL,
+    UNIQUE (task_name, from_block_number, to_block_number)
+);
@@ -0,0 +1,4 @@
+ALTER TABLE "Tasks"
+    DROP COLUMN IF EXISTS fencing_token,
+    DROP COLUMN IF EXISTS lease_expires_at,
+    DROP COLUMN IF EXISTS owner;
@@ -0,0 +1,4 @@
+ALTER TABLE "Tasks"
+    ADD COLUMN IF NOT EXISTS owner VARCHAR(256) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMPTZ NOT NULL DEFAULT to_timestamp(0),
+    ADD COLUMN IF NOT EXISTS fencing_token BIGINT NOT NULL DEFAULT 0;
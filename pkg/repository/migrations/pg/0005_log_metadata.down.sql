@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS "Logs_missing_address_idx";
+DROP INDEX IF EXISTS "Logs_chain_id_block_number_transaction_index_idx";
+DROP INDEX IF EXISTS "Logs_chain_id_block_timestamp_idx";
+DROP INDEX IF EXISTS "Logs_chain_id_address_block_number_idx";
+
+ALTER TABLE "Logs"
+    DROP COLUMN IF EXISTS block_timestamp,
+    DROP COLUMN IF EXISTS transaction_index,
+    DROP COLUMN IF EXISTS address;
@@ -0,0 +1,13 @@
+ALTER TABLE "Logs"
+    ADD COLUMN IF NOT EXISTS address VARCHAR(256),
+    ADD COLUMN IF NOT EXISTS transaction_index BIGINT,
+    ADD COLUMN IF NOT EXISTS block_timestamp TIMESTAMPTZ;
+
+-- address and transaction_index of the existing rows are backfilled from
+-- the transaction receipts by `app backfill-log-metadata`
+UPDATE "Logs" SET block_timestamp = to_timestamp(timestamp / 1000.0) WHERE block_timestamp IS NULL;
+
+CREATE INDEX IF NOT EXISTS "Logs_chain_id_address_block_number_idx" ON "Logs" (chain_id, address, block_number);
+CREATE INDEX IF NOT EXISTS "Logs_chain_id_block_timestamp_idx" ON "Logs" (chain_id, block_timestamp);
+CREATE INDEX IF NOT EXISTS "Logs_chain_id_block_number_transaction_index_idx" ON "Logs" (chain_id, block_number, transaction_index);
+CREATE INDEX IF NOT EXISTS "Logs_missing_address_idx" ON "Logs" (chain_id, block_number) WHERE address IS NULL;
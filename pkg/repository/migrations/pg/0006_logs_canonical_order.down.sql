@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "Logs_chain_id_block_number_log_index_idx";
@@ -0,0 +1,2 @@
+-- serves the keyset pagination of the log queries
+CREATE INDEX IF NOT EXISTS "Logs_chain_id_block_number_log_index_idx" ON "Logs" (chain_id, block_number, log_index);
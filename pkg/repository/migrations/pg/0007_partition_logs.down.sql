@@ -0,0 +1,38 @@
+ALTER TABLE "Logs" RENAME TO "LogsPartitioned";
+ALTER INDEX "Logs_chain_id_address_block_number_idx" RENAME TO "LogsPartitioned_chain_id_address_block_number_idx";
+ALTER INDEX "Logs_chain_id_block_timestamp_idx" RENAME TO "LogsPartitioned_chain_id_block_timestamp_idx";
+ALTER INDEX "Logs_chain_id_block_number_transaction_index_idx" RENAME TO "LogsPartitioned_chain_id_block_number_transaction_index_idx";
+ALTER INDEX "Logs_missing_address_idx" RENAME TO "LogsPartitioned_missing_address_idx";
+ALTER INDEX "Logs_chain_id_block_number_log_index_idx" RENAME TO "LogsPartitioned_chain_id_block_number_log_index_idx";
+ALTER INDEX "Logs_chain_id_txn_hash_log_index_block_number_key" RENAME TO "LogsPartitioned_chain_id_txn_hash_log_index_block_number_key";
+ALTER TABLE "LogsPartitioned" RENAME CONSTRAINT "Logs_pkey" TO "LogsPartitioned_pkey";
+
+CREATE TABLE "Logs" (
+    chain_id BIGINT NOT NULL,
+    block_number BIGINT NOT NULL,
+    block_hash VARCHAR(256) NOT NULL,
+    data TEXT NOT NULL,
+    topics TEXT[] NOT NULL,
+    txn_hash VARCHAR(256) NOT NULL,
+    log_index BIGINT NOT NULL,
+    removed BOOLEAN NOT NULL,
+    timestamp BIGINT NOT NULL,
+    address VARCHAR(256),
+    transaction_index BIGINT,
+    block_timestamp TIMESTAMPTZ,
+    PRIMARY KEY (chain_id, txn_hash, log_index)
+);
+
+INSERT INTO "Logs" (chain_id, block_number, block_hash, data, topics, txn_hash, log_index, removed, timestamp, address, transaction_index, block_timestamp)
+SELECT chain_id, block_number, block_hash, data, topics, txn_hash, log_index, removed, timestamp, address, transaction_index, block_timestamp
+FROM "LogsPartitioned"
+ORDER BY block_number DESC
+ON CONFLICT DO NOTHING;
+
+DROP TABLE "LogsPartitioned";
+
+CREATE INDEX "Logs_chain_id_address_block_number_idx" ON "Logs" (chain_id, address, block_number);
+CREATE INDEX "Logs_chain_id_block_timestamp_idx" ON "Logs" (chain_id, block_timestamp);
+CREATE INDEX "Logs_chain_id_block_number_transaction_index_idx" ON "Logs" (chain_id, block_number, transaction_index);
+CREATE INDEX "Logs_missing_address_idx" ON "Logs" (chain_id, block_number) WHERE address IS NULL;
+CREATE INDEX "Logs_chain_id_block_number_log_index_idx" ON "Logs" (chain_id, block_number, log_index);
@@ -0,0 +1,58 @@
+-- "Logs" is partitioned by chain, and each chain by block range. The
+-- partitions of the existing rows are created here with the range of
+-- PG_CONFIG.LOG_PARTITION_BLOCK_RANGE, set by the migrator for the
+-- transaction; later partitions are created by the repository.
+--
+-- A log is identified by its chain, transaction hash and log index. The keys
+-- of a partitioned table must hold the partition columns, so the block number
+-- is part of them, and a log moved to another block by a reorg is updated in
+-- place by the repository rather than stored again.
+ALTER TABLE "Logs" RENAME TO "LogsUnpartitioned";
+ALTER TABLE "LogsUnpartitioned" RENAME CONSTRAINT "Logs_pkey" TO "LogsUnpartitioned_pkey";
+
+CREATE TABLE "Logs" (
+    chain_id BIGINT NOT NULL,
+    block_number BIGINT NOT NULL,
+    block_hash VARCHAR(256) NOT NULL,
+    data TEXT NOT NULL,
+    topics TEXT[] NOT NULL,
+    txn_hash VARCHAR(256) NOT NULL,
+    log_index BIGINT NOT NULL,
+    removed BOOLEAN NOT NULL,
+    timestamp BIGINT NOT NULL,
+    address VARCHAR(256),
+    transaction_index BIGINT,
+    block_timestamp TIMESTAMPTZ,
+    PRIMARY KEY (chain_id, block_number, txn_hash, log_index)
+) PARTITION BY LIST (chain_id);
+
+DO $$
+DECLARE
+    block_range BIGINT := COALESCE(NULLIF(current_setting('blocktasks.log_partition_block_range', true), '')::BIGINT, 1000000);
+    r RECORD;
+BEGIN
+    FOR r IN SELECT DISTINCT chain_id, block_number / block_range * block_range AS from_block_number FROM "LogsUnpartitioned" LOOP
+        EXECUTE format(
+            'CREATE TABLE IF NOT EXISTS %I PARTITION OF "Logs" FOR VALUES IN (%s) PARTITION BY RANGE (block_number)',
+            'Logs_' || r.chain_id, r.chain_id
+        );
+        EXECUTE format(
+            'CREATE TABLE %I PARTITION OF %I FOR VALUES FROM (%s) TO (%s)',
+            'Logs_' || r.chain_id || '_' || r.from_block_number, 'Logs_' || r.chain_id,
+            r.from_block_number, r.from_block_number + block_range
+        );
+    END LOOP;
+END $$;
+
+INSERT INTO "Logs" (chain_id, block_number, block_hash, data, topics, txn_hash, log_index, removed, timestamp, address, transaction_index, block_timestamp)
+SELECT chain_id, block_number, block_hash, data, topics, txn_hash, log_index, removed, timestamp, address, transaction_index, block_timestamp
+FROM "LogsUnpartitioned";
+
+DROP TABLE "LogsUnpartitioned";
+
+CREATE INDEX "Logs_chain_id_address_block_number_idx" ON "Logs" (chain_id, address, block_number);
+CREATE INDEX "Logs_chain_id_block_timestamp_idx" ON "Logs" (chain_id, block_timestamp);
+CREATE INDEX "Logs_chain_id_block_number_transaction_index_idx" ON "Logs" (chain_id, block_number, transaction_index);
+CREATE INDEX "Logs_missing_address_idx" ON "Logs" (chain_id, block_number) WHERE address IS NULL;
+CREATE INDEX "Logs_chain_id_block_number_log_index_idx" ON "Logs" (chain_id, block_number, log_index);
+CREATE UNIQUE INDEX "Logs_chain_id_txn_hash_log_index_block_number_key" ON "Logs" (chain_id, txn_hash, log_index, block_number);
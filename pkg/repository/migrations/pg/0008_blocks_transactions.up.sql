@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS "Blocks" (
+    chain_id BIGINT NOT NULL,
+    block_number BIGINT NOT NULL,
+    block_hash VARCHAR(256) NOT NULL,
+    parent_hash VARCHAR(256) NOT NULL,
+    timestamp BIGINT NOT NULL,
+    gas_limit BIGINT NOT NULL,
+    gas_used BIGINT NOT NULL,
+    base_fee_per_gas NUMERIC(78, 0),
+    PRIMARY KEY (chain_id, block_number)
+);
+
+CREATE INDEX IF NOT EXISTS "Blocks_chain_id_block_hash_idx" ON "Blocks" (chain_id, block_hash);
+
+CREATE TABLE IF NOT EXISTS "Transactions" (
+    chain_id BIGINT NOT NULL,
+    txn_hash VARCHAR(256) NOT NULL,
+    block_number BIGINT NOT NULL,
+    block_hash VARCHAR(256) NOT NULL,
+    transaction_index BIGINT NOT NULL,
+    type BIGINT NOT NULL,
+    nonce BIGINT NOT NULL,
+    from_address VARCHAR(256) NOT NULL,
+    to_address VARCHAR(256) NOT NULL,
+    value NUMERIC(78, 0) NOT NULL,
+    gas BIGINT NOT NULL,
+    gas_price NUMERIC(78, 0) NOT NULL,
+    input TEXT NOT NULL,
+    PRIMARY KEY (chain_id, txn_hash)
+);
+
+CREATE INDEX IF NOT EXISTS "Transactions_chain_id_block_number_transaction_index_idx" ON "Transactions" (chain_id, block_number, transaction_index);
+CREATE INDEX IF NOT EXISTS "Transactions_chain_id_from_address_idx" ON "Transactions" (chain_id, from_address);
+CREATE INDEX IF NOT EXISTS "Transactions_chain_id_to_address_idx" ON "Transactions" (chain_id, to_address);
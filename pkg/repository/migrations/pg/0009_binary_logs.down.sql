@@ -0,0 +1,38 @@
+ALTER TABLE "Transactions"
+    ALTER COLUMN txn_hash TYPE VARCHAR(256) USING '0x' || encode(txn_hash, 'hex'),
+    ALTER COLUMN block_hash TYPE VARCHAR(256) USING '0x' || encode(block_hash, 'hex'),
+    ALTER COLUMN from_address TYPE VARCHAR(256) USING '0x' || encode(from_address, 'hex'),
+    ALTER COLUMN to_address TYPE VARCHAR(256) USING COALESCE('0x' || encode(to_address, 'hex'), ''),
+    ALTER COLUMN input TYPE TEXT USING '0x' || encode(input, 'hex'),
+    ALTER COLUMN to_address SET NOT NULL;
+
+ALTER TABLE "Blocks"
+    ALTER COLUMN block_hash TYPE VARCHAR(256) USING '0x' || encode(block_hash, 'hex'),
+    ALTER COLUMN parent_hash TYPE VARCHAR(256) USING '0x' || encode(parent_hash, 'hex');
+
+DROP INDEX IF EXISTS "Logs_chain_id_topic3_block_number_idx";
+DROP INDEX IF EXISTS "Logs_chain_id_topic2_block_number_idx";
+DROP INDEX IF EXISTS "Logs_chain_id_topic1_block_number_idx";
+DROP INDEX IF EXISTS "Logs_chain_id_topic0_block_number_idx";
+
+ALTER TABLE "Logs"
+    ALTER COLUMN block_hash TYPE VARCHAR(256) USING '0x' || encode(block_hash, 'hex'),
+    ALTER COLUMN address TYPE VARCHAR(256) USING '0x' || encode(address, 'hex'),
+    ALTER COLUMN data TYPE TEXT USING '0x' || encode(data, 'hex'),
+    ALTER COLUMN txn_hash TYPE VARCHAR(256) USING '0x' || encode(txn_hash, 'hex');
+
+ALTER TABLE "Logs" ADD COLUMN topics TEXT[];
+
+UPDATE "Logs" SET topics = array_remove(ARRAY[
+    '0x' || encode(topic0, 'hex'),
+    '0x' || encode(topic1, 'hex'),
+    '0x' || encode(topic2, 'hex'),
+    '0x' || encode(topic3, 'hex')
+], NULL);
+
+ALTER TABLE "Logs"
+    ALTER COLUMN topics SET NOT NULL,
+    DROP COLUMN topic3,
+    DROP COLUMN topic2,
+    DROP COLUMN topic1,
+    DROP COLUMN topic0;
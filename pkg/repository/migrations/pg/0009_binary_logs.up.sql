@@ -0,0 +1,62 @@
+-- hashes, addresses, data and topics are stored as bytes instead of
+-- 0x-prefixed hex, and each topic position gets its own indexed column
+--
+-- The empty string is stored as NULL, an odd number of digits is padded with
+-- a leading zero, and any other value which is not hex fails the migration.
+CREATE FUNCTION pg_temp.hex_to_bytea(value TEXT) RETURNS BYTEA AS $$
+DECLARE
+    digits TEXT;
+BEGIN
+    IF value IS NULL OR value = '' THEN
+        RETURN NULL;
+    END IF;
+    IF value !~ '^0[xX][0-9a-fA-F]*$' THEN
+        RAISE EXCEPTION 'malformed hex value: %', value;
+    END IF;
+    digits := substr(value, 3);
+    IF length(digits) % 2 = 1 THEN
+        digits := '0' || digits;
+    END IF;
+    RETURN decode(digits, 'hex');
+END
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+ALTER TABLE "Logs"
+    ADD COLUMN topic0 BYTEA,
+    ADD COLUMN topic1 BYTEA,
+    ADD COLUMN topic2 BYTEA,
+    ADD COLUMN topic3 BYTEA;
+
+UPDATE "Logs" SET
+    topic0 = pg_temp.hex_to_bytea(topics[1]),
+    topic1 = pg_temp.hex_to_bytea(topics[2]),
+    topic2 = pg_temp.hex_to_bytea(topics[3]),
+    topic3 = pg_temp.hex_to_bytea(topics[4]);
+
+ALTER TABLE "Logs" DROP COLUMN topics;
+
+ALTER TABLE "Logs"
+    ALTER COLUMN block_hash TYPE BYTEA USING pg_temp.hex_to_bytea(block_hash),
+    ALTER COLUMN address TYPE BYTEA USING pg_temp.hex_to_bytea(address),
+    ALTER COLUMN data TYPE BYTEA USING COALESCE(pg_temp.hex_to_bytea(data), ''::BYTEA),
+    ALTER COLUMN txn_hash TYPE BYTEA USING pg_temp.hex_to_bytea(txn_hash);
+
+CREATE INDEX "Logs_chain_id_topic0_block_number_idx" ON "Logs" (chain_id, topic0, block_number);
+CREATE INDEX "Logs_chain_id_topic1_block_number_idx" ON "Logs" (chain_id, topic1, block_number);
+CREATE INDEX "Logs_chain_id_topic2_block_number_idx" ON "Logs" (chain_id, topic2, block_number);
+CREATE INDEX "Logs_chain_id_topic3_block_number_idx" ON "Logs" (chain_id, topic3, block_number);
+
+ALTER TABLE "Blocks"
+    ALTER COLUMN block_hash TYPE BYTEA USING pg_temp.hex_to_bytea(block_hash),
+    ALTER COLUMN parent_hash TYPE BYTEA USING pg_temp.hex_to_bytea(parent_hash);
+
+-- the recipient of a contract creation is NULL
+ALTER TABLE "Transactions"
+    ALTER COLUMN to_address DROP NOT NULL,
+    ALTER COLUMN txn_hash TYPE BYTEA USING pg_temp.hex_to_bytea(txn_hash),
+    ALTER COLUMN block_hash TYPE BYTEA USING pg_temp.hex_to_bytea(block_hash),
+    ALTER COLUMN from_address TYPE BYTEA USING pg_temp.hex_to_bytea(from_address),
+    ALTER COLUMN to_address TYPE BYTEA USING pg_temp.hex_to_bytea(to_address),
+    ALTER COLUMN input TYPE BYTEA USING COALESCE(pg_temp.hex_to_bytea(input), ''::BYTEA);
+
+DROP FUNCTION pg_temp.hex_to_bytea(TEXT);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS "ProcessedRanges" (
+    id BIGSERIAL PRIMARY KEY,
+    task_name VARCHAR(256) NOT NULL,
+    chain_id BIGINT NOT NULL,
+    from_block_number BIGINT NOT NULL,
+    to_block_number BIGINT NOT NULL,
+    from_block_hash BYTEA NOT NULL,
+    to_block_hash BYTEA NOT NULL,
+    log_count BIGINT NOT NULL,
+    rpc_endpoint VARCHAR(256) NOT NULL,
+    duration_ms BIGINT NOT NULL,
+    instance_id VARCHAR(256) NOT NULL,
+    committed_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS "ProcessedRanges_chain_id_to_block_number_idx" ON "ProcessedRanges" (chain_id, to_block_number);
+CREATE INDEX IF NOT EXISTS "ProcessedRanges_task_name_committed_at_idx" ON "ProcessedRanges" (task_name, committed_at);
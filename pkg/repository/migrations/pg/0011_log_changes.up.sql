@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS "LogChanges" (
+    id BIGSERIAL PRIMARY KEY,
+    chain_id BIGINT NOT NULL,
+    txn_hash BYTEA NOT NULL,
+    log_index BIGINT NOT NULL,
+    old_block_number BIGINT NOT NULL,
+    new_block_number BIGINT NOT NULL,
+    old_block_hash BYTEA,
+    new_block_hash BYTEA,
+    old_removed BOOLEAN NOT NULL,
+    new_removed BOOLEAN NOT NULL,
+    old_timestamp BIGINT NOT NULL,
+    new_timestamp BIGINT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS "LogChanges_chain_id_txn_hash_idx" ON "LogChanges" (chain_id, txn_hash);
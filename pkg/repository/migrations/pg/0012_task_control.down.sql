@@ -0,0 +1,3 @@
+ALTER TABLE "Tasks"
+    DROP COLUMN IF EXISTS revision,
+    DROP COLUMN IF EXISTS paused;
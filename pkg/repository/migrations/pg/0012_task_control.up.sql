@@ -0,0 +1,3 @@
+ALTER TABLE "Tasks"
+    ADD COLUMN IF NOT EXISTS paused BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS revision BIGINT NOT NULL DEFAULT 0;
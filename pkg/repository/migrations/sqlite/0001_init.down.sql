@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS "Transactions";
+DROP TABLE IF EXISTS "Blocks";
+DROP TABLE IF EXISTS "DeadLetters";
+DROP TABLE IF EXISTS "BackfillChunks";
+DROP TABLE IF EXISTS "Logs";
+DROP TABLE IF EXISTS "Tasks";
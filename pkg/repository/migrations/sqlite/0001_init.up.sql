@@ -0,0 +1,87 @@
+CREATE TABLE IF NOT EXISTS "Tasks" (
+    name TEXT NOT NULL PRIMARY KEY,
+    last_processed_block_number INTEGER DEFAULT 0,
+    last_processed_block_timestamp INTEGER DEFAULT 0,
+    owner TEXT NOT NULL DEFAULT '',
+    lease_expires_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00+00:00',
+    fencing_token INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS "Logs" (
+    chain_id INTEGER NOT NULL,
+    block_number INTEGER NOT NULL,
+    block_hash BLOB NOT NULL,
+    address BLOB,
+    data BLOB NOT NULL,
+    topic0 BLOB,
+    topic1 BLOB,
+    topic2 BLOB,
+    topic3 BLOB,
+    txn_hash BLOB NOT NULL,
+    transaction_index INTEGER,
+    log_index INTEGER NOT NULL,
+    removed BOOLEAN NOT NULL,
+    timestamp INTEGER NOT NULL,
+    block_timestamp DATETIME,
+    PRIMARY KEY (chain_id, block_number, txn_hash, log_index)
+);
+
+CREATE INDEX IF NOT EXISTS "Logs_chain_id_block_number_log_index_idx" ON "Logs" (chain_id, block_number, log_index);
+CREATE INDEX IF NOT EXISTS "Logs_chain_id_address_block_number_idx" ON "Logs" (chain_id, address, block_number);
+CREATE INDEX IF NOT EXISTS "Logs_chain_id_topic0_block_number_idx" ON "Logs" (chain_id, topic0, block_number);
+CREATE INDEX IF NOT EXISTS "Logs_chain_id_txn_hash_idx" ON "Logs" (chain_id, txn_hash);
+CREATE INDEX IF NOT EXISTS "Logs_chain_id_block_timestamp_idx" ON "Logs" (chain_id, block_timestamp);
+
+CREATE TABLE IF NOT EXISTS "BackfillChunks" (
+    task_name TEXT NOT NULL,
+    from_block_number INTEGER NOT NULL,
+    to_block_number INTEGER NOT NULL,
+    last_processed_block_number INTEGER NOT NULL,
+    completed BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (task_name, from_block_number)
+);
+
+CREATE TABLE IF NOT EXISTS "DeadLetters" (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_name TEXT NOT NULL,
+    from_block_number INTEGER NOT NULL,
+    to_block_number INTEGER NOT NULL,
+    error TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL,
+    skipped BOOLEAN NOT NULL DEFAULT FALSE,
+    first_failed_at DATETIME NOT NULL,
+    last_failed_at DATETIME NOT NULL,
+    UNIQUE (task_name, from_block_number, to_block_number)
+);
+
+CREATE TABLE IF NOT EXISTS "Blocks" (
+    chain_id INTEGER NOT NULL,
+    block_number INTEGER NOT NULL,
+    block_hash TEXT NOT NULL,
+    parent_hash TEXT NOT NULL,
+    timestamp INTEGER NOT NULL,
+    gas_limit INTEGER NOT NULL,
+    gas_used INTEGER NOT NULL,
+    base_fee_per_gas TEXT,
+    PRIMARY KEY (chain_id, block_number)
+);
+
+CREATE TABLE IF NOT EXISTS "Transactions" (
+    chain_id INTEGER NOT NULL,
+    txn_hash TEXT NOT NULL,
+    block_number INTEGER NOT NULL,
+    block_hash TEXT NOT NULL,
+    transaction_index INTEGER NOT NULL,
+    type INTEGER NOT NULL,
+    nonce INTEGER NOT NULL,
+    from_address TEXT NOT NULL,
+    to_address TEXT NOT NULL,
+    value TEXT NOT NULL,
+    gas INTEGER NOT NULL,
+    gas_price TEXT NOT NULL,
+    input TEXT NOT NULL,
+    PRIMARY KEY (chain_id, txn_hash)
+);
+
+CREATE INDEX IF NOT EXISTS "Transactions_chain_id_block_number_transaction_index_idx" ON "Transactions" (chain_id, block_number, transaction_index);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS "ProcessedRanges" (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_name TEXT NOT NULL,
+    chain_id INTEGER NOT NULL,
+    from_block_number INTEGER NOT NULL,
+    to_block_number INTEGER NOT NULL,
+    from_block_hash BLOB NOT NULL,
+    to_block_hash BLOB NOT NULL,
+    log_count INTEGER NOT NULL,
+    rpc_endpoint TEXT NOT NULL,
+    duration_ms INTEGER NOT NULL,
+    instance_id TEXT NOT NULL,
+    committed_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS "ProcessedRanges_chain_id_to_block_number_idx" ON "ProcessedRanges" (chain_id, to_block_number);
+CREATE INDEX IF NOT EXISTS "ProcessedRanges_task_name_committed_at_idx" ON "ProcessedRanges" (task_name, committed_at);
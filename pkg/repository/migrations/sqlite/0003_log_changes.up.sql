@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS "LogChanges" (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    chain_id INTEGER NOT NULL,
+    txn_hash BLOB NOT NULL,
+    log_index INTEGER NOT NULL,
+    old_block_number INTEGER NOT NULL,
+    new_block_number INTEGER NOT NULL,
+    old_block_hash BLOB,
+    new_block_hash BLOB,
+    old_removed BOOLEAN NOT NULL,
+    new_removed BOOLEAN NOT NULL,
+    old_timestamp INTEGER NOT NULL,
+    new_timestamp INTEGER NOT NULL,
+    changed_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "LogChanges_chain_id_txn_hash_idx" ON "LogChanges" (chain_id, txn_hash);
@@ -0,0 +1,2 @@
+ALTER TABLE "Tasks" DROP COLUMN revision;
+ALTER TABLE "Tasks" DROP COLUMN paused;
@@ -0,0 +1,2 @@
+ALTER TABLE "Tasks" ADD COLUMN paused BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE "Tasks" ADD COLUMN revision INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,45 @@
+CREATE TABLE "TransactionsText" (
+    chain_id INTEGER NOT NULL,
+    txn_hash TEXT NOT NULL,
+    block_number INTEGER NOT NULL,
+    block_hash TEXT NOT NULL,
+    transaction_index INTEGER NOT NULL,
+    type INTEGER NOT NULL,
+    nonce INTEGER NOT NULL,
+    from_address TEXT NOT NULL,
+    to_address TEXT NOT NULL,
+    value TEXT NOT NULL,
+    gas INTEGER NOT NULL,
+    gas_price TEXT NOT NULL,
+    input TEXT NOT NULL,
+    PRIMARY KEY (chain_id, txn_hash)
+);
+
+INSERT INTO "TransactionsText" (chain_id, txn_hash, block_number, block_hash, transaction_index, type, nonce, from_address, to_address, value, gas, gas_price, input)
+SELECT chain_id, '0x' || lower(hex(txn_hash)), block_number, '0x' || lower(hex(block_hash)), transaction_index, type, nonce,
+    '0x' || lower(hex(from_address)), COALESCE('0x' || lower(hex(to_address)), ''), value, gas, gas_price, '0x' || lower(hex(input))
+FROM "Transactions";
+
+DROP TABLE "Transactions";
+ALTER TABLE "TransactionsText" RENAME TO "Transactions";
+
+CREATE INDEX IF NOT EXISTS "Transactions_chain_id_block_number_transaction_index_idx" ON "Transactions" (chain_id, block_number, transaction_index);
+
+CREATE TABLE "BlocksText" (
+    chain_id INTEGER NOT NULL,
+    block_number INTEGER NOT NULL,
+    block_hash TEXT NOT NULL,
+    parent_hash TEXT NOT NULL,
+    timestamp INTEGER NOT NULL,
+    gas_limit INTEGER NOT NULL,
+    gas_used INTEGER NOT NULL,
+    base_fee_per_gas TEXT,
+    PRIMARY KEY (chain_id, block_number)
+);
+
+INSERT INTO "BlocksText" (chain_id, block_number, block_hash, parent_hash, timestamp, gas_limit, gas_used, base_fee_per_gas)
+SELECT chain_id, block_number, '0x' || lower(hex(block_hash)), '0x' || lower(hex(parent_hash)), timestamp, gas_limit, gas_used, base_fee_per_gas
+FROM "Blocks";
+
+DROP TABLE "Blocks";
+ALTER TABLE "BlocksText" RENAME TO "Blocks";
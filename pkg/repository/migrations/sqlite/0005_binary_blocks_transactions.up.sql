@@ -0,0 +1,59 @@
+-- block and transaction hashes, addresses and input are stored as bytes
+-- instead of 0x-prefixed hex, as are those of the logs. The empty string is
+-- stored as NULL, an odd number of digits is padded with a leading zero, and
+-- any other value which is not hex fails a NOT NULL constraint.
+CREATE TABLE "BlocksBinary" (
+    chain_id INTEGER NOT NULL,
+    block_number INTEGER NOT NULL,
+    block_hash BLOB NOT NULL,
+    parent_hash BLOB NOT NULL,
+    timestamp INTEGER NOT NULL,
+    gas_limit INTEGER NOT NULL,
+    gas_used INTEGER NOT NULL,
+    base_fee_per_gas TEXT,
+    PRIMARY KEY (chain_id, block_number)
+);
+
+INSERT INTO "BlocksBinary" (chain_id, block_number, block_hash, parent_hash, timestamp, gas_limit, gas_used, base_fee_per_gas)
+SELECT chain_id, block_number,
+    CASE WHEN block_hash LIKE '0x%' THEN unhex(CASE WHEN length(block_hash) % 2 = 1 THEN '0' || substr(block_hash, 3) ELSE substr(block_hash, 3) END) END,
+    CASE WHEN parent_hash LIKE '0x%' THEN unhex(CASE WHEN length(parent_hash) % 2 = 1 THEN '0' || substr(parent_hash, 3) ELSE substr(parent_hash, 3) END) END,
+    timestamp, gas_limit, gas_used, base_fee_per_gas
+FROM "Blocks";
+
+DROP TABLE "Blocks";
+ALTER TABLE "BlocksBinary" RENAME TO "Blocks";
+
+CREATE TABLE "TransactionsBinary" (
+    chain_id INTEGER NOT NULL,
+    txn_hash BLOB NOT NULL,
+    block_number INTEGER NOT NULL,
+    block_hash BLOB NOT NULL,
+    transaction_index INTEGER NOT NULL,
+    type INTEGER NOT NULL,
+    nonce INTEGER NOT NULL,
+    from_address BLOB NOT NULL,
+    to_address BLOB,
+    value TEXT NOT NULL,
+    gas INTEGER NOT NULL,
+    gas_price TEXT NOT NULL,
+    input BLOB NOT NULL,
+    PRIMARY KEY (chain_id, txn_hash)
+);
+
+INSERT INTO "TransactionsBinary" (chain_id, txn_hash, block_number, block_hash, transaction_index, type, nonce, from_address, to_address, value, gas, gas_price, input)
+SELECT chain_id,
+    CASE WHEN txn_hash LIKE '0x%' THEN unhex(CASE WHEN length(txn_hash) % 2 = 1 THEN '0' || substr(txn_hash, 3) ELSE substr(txn_hash, 3) END) END,
+    block_number,
+    CASE WHEN block_hash LIKE '0x%' THEN unhex(CASE WHEN length(block_hash) % 2 = 1 THEN '0' || substr(block_hash, 3) ELSE substr(block_hash, 3) END) END,
+    transaction_index, type, nonce,
+    CASE WHEN from_address LIKE '0x%' THEN unhex(CASE WHEN length(from_address) % 2 = 1 THEN '0' || substr(from_address, 3) ELSE substr(from_address, 3) END) END,
+    CASE WHEN to_address LIKE '0x%' THEN unhex(CASE WHEN length(to_address) % 2 = 1 THEN '0' || substr(to_address, 3) ELSE substr(to_address, 3) END) END,
+    value, gas, gas_price,
+    CASE WHEN input LIKE '0x%' THEN unhex(CASE WHEN length(input) % 2 = 1 THEN '0' || substr(input, 3) ELSE substr(input, 3) END) END
+FROM "Transactions";
+
+DROP TABLE "Transactions";
+ALTER TABLE "TransactionsBinary" RENAME TO "Transactions";
+
+CREATE INDEX IF NOT EXISTS "Transactions_chain_id_block_number_transaction_index_idx" ON "Transactions" (chain_id, block_number, transaction_index);
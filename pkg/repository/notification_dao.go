@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"gorm.io/gorm"
+)
+
+// LogNotification announces the logs of a block range committed by a task.
+type LogNotification struct {
+	TaskName        string `json:"task_name"`
+	ChainId         int64  `json:"chain_id"`
+	FromBlockNumber int64  `json:"from_block_number"`
+	ToBlockNumber   int64  `json:"to_block_number"`
+}
+
+// LogChannel returns the channel on which the logs committed by the task are
+// announced.
+func LogChannel(taskName string) string {
+	return "logs_" + taskName
+}
+
+type NotificationDao interface {
+	// NotifyLogs announces the logs on the channel of the task. Inside a
+	// transaction the notification is only delivered when it commits.
+	NotifyLogs(ctx context.Context, notification LogNotification) error
+}
+
+type pgNotificationDao struct {
+	db *gorm.DB
+}
+
+func NewPgNotificationDao(db *gorm.DB) NotificationDao {
+	return &pgNotificationDao{db: db}
+}
+
+func (n *pgNotificationDao) NotifyLogs(ctx context.Context, notification LogNotification) error {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	err = n.db.WithContext(ctx).Exec("SELECT pg_notify(?, ?)", LogChannel(notification.TaskName), string(payload)).Error
+	if err != nil {
+		return transformGormError(err)
+	}
+	return nil
+}
+
+// noopNotificationDao stands for a database without notifications.
+type noopNotificationDao struct{}
+
+func (noopNotificationDao) NotifyLogs(ctx context.Context, notification LogNotification) error {
+	return nil
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"embed"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/pg/*.sql
+var pgMigrationFS embed.FS
+
+// pgMigrationLockId is the advisory lock held while migrating, so that
+// replicas starting together apply each migration once.
+const pgMigrationLockId = 7265636
+
+type pgMigrationDialect struct {
+	schema                 string
+	logPartitionBlockRange int64
+}
+
+func newPgMigrator(lg *zap.Logger, db *gorm.DB, schema string, logPartitionBlockRange int64) (*sqlMigrator, error) {
+	migrations, err := loadMigrations(pgMigrationFS, "migrations/pg")
+	if err != nil {
+		return nil, err
+	}
+	return &sqlMigrator{
+		lg:         lg.With(zap.String("schema", schema)),
+		db:         db,
+		dialect:    pgMigrationDialect{schema: schema, logPartitionBlockRange: max(logPartitionBlockRange, 1)},
+		migrations: migrations,
+	}, nil
+}
+
+// prepare creates the schema as well, since the connections resolve the
+// tables in it through the search path.
+func (d pgMigrationDialect) prepare(db *gorm.DB) error {
+	if d.schema != "" {
+		if err := db.Exec("CREATE SCHEMA IF NOT EXISTS " + pgx.Identifier{d.schema}.Sanitize()).Error; err != nil {
+			return err
+		}
+	}
+	return db.Exec(`CREATE TABLE IF NOT EXISTS "SchemaMigrations" (
+		version BIGINT NOT NULL PRIMARY KEY,
+		name VARCHAR(256) NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`).Error
+}
+
+func (d pgMigrationDialect) lock(tx *gorm.DB) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(?)", pgMigrationLockId).Error
+}
+
+// configure passes the block range of the log partitions to the migrations
+// which create them.
+func (d pgMigrationDialect) configure(tx *gorm.DB) error {
+	return tx.Exec("SELECT set_config('blocktasks.log_partition_block_range', ?, true)", strconv.FormatInt(d.logPartitionBlockRange, 10)).Error
+}
+
+func (d pgMigrationDialect) versionTableExists(db *gorm.DB) (bool, error) {
+	var exists bool
+	err := db.Raw(`SELECT to_regclass('"SchemaMigrations"') IS NOT NULL`).Scan(&exists).Error
+	return exists, err
+}
@@ -1,10 +1,15 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/waynewu411/blocktasks/pkg/config"
+	"github.com/waynewu411/blocktasks/pkg/metrics"
 	"go.uber.org/zap"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -16,9 +21,18 @@ type pgRepository struct {
 	lg  *zap.Logger
 	cfg config.PgConfig
 	db  *gorm.DB
-
-	taskDao TaskDao
-	logDao  LogDao
+	// conn is the connection of the transaction, nil outside a transaction
+	conn *sql.Conn
+
+	taskDao           TaskDao
+	logDao            LogDao
+	backfillChunkDao  BackfillChunkDao
+	deadLetterDao     DeadLetterDao
+	logPartitionDao   LogPartitionDao
+	blockDao          BlockDao
+	transactionDao    TransactionDao
+	processedRangeDao ProcessedRangeDao
+	notificationDao   NotificationDao
 }
 
 type customNamingStrategy struct {
@@ -27,17 +41,68 @@ type customNamingStrategy struct {
 }
 
 func NewPgRepository(lg *zap.Logger, cfg config.PgConfig) Repository {
+	db := openPgDB(lg, cfg)
+
+	migrator, err := newPgMigrator(lg, db, cfg.Schema, cfg.LogPartitionBlockRange)
+	if err != nil {
+		lg.Fatal("fail to load migrations", zap.Error(err))
+	}
+	ctx := context.Background()
+	if cfg.AutoMigrate {
+		if err := migrator.Up(ctx); err != nil {
+			lg.Fatal("fail to migrate postgres database", zap.Error(err))
+		}
+	}
+	if err := CheckSchemaVersion(ctx, migrator); err != nil {
+		lg.Fatal("fail to check database schema version", zap.Error(err))
+	}
+
+	pgRepository := &pgRepository{
+		lg:                lg,
+		cfg:               cfg,
+		db:                db,
+		taskDao:           NewTaskDao(db),
+		logDao:            NewLogDao(db, cfg.LogPartitionBlockRange),
+		backfillChunkDao:  NewBackfillChunkDao(db),
+		deadLetterDao:     NewDeadLetterDao(db),
+		logPartitionDao:   NewLogPartitionDao(db, cfg.LogPartitionBlockRange),
+		blockDao:          NewBlockDao(db),
+		transactionDao:    NewTransactionDao(db),
+		processedRangeDao: NewProcessedRangeDao(db),
+		notificationDao:   NewPgNotificationDao(db),
+	}
+
+	return pgRepository
+}
+
+// NewPgMigrator connects to the database without checking the schema
+// version, so that the schema can be migrated in either direction.
+func NewPgMigrator(lg *zap.Logger, cfg config.PgConfig) Migrator {
+	migrator, err := newPgMigrator(lg, openPgDB(lg, cfg), cfg.Schema, cfg.LogPartitionBlockRange)
+	if err != nil {
+		lg.Fatal("fail to load migrations", zap.Error(err))
+	}
+	return migrator
+}
+
+func openPgDB(lg *zap.Logger, cfg config.PgConfig) *gorm.DB {
 	gormCfg := &gorm.Config{
 		NamingStrategy: customNamingStrategy{
 			DbSchema: cfg.Schema,
 		},
+		TranslateError: true,
 	}
 
 	if cfg.DebugEnabled {
 		gormCfg.Logger = gormLogger.Default.LogMode(gormLogger.Info)
 	}
 
-	db, err := gorm.Open(postgres.Open(cfg.Url), gormCfg)
+	dsn, err := withSearchPath(cfg.Url, cfg.Schema)
+	if err != nil {
+		lg.Fatal("fail to parse postgres url", zap.Error(err))
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), gormCfg)
 	if err != nil {
 		lg.Fatal("fail to connect to postgres database", zap.Error(err))
 	}
@@ -57,30 +122,79 @@ func NewPgRepository(lg *zap.Logger, cfg config.PgConfig) Repository {
 	sqlDB.SetConnMaxLifetime(time.Duration(cfg.MaxConnLifeTime) * time.Second)
 	sqlDB.SetConnMaxIdleTime(time.Duration(cfg.MaxConnIdleTime) * time.Second)
 
-	pgRepository := &pgRepository{
-		lg:      lg,
-		cfg:     cfg,
-		db:      db,
-		taskDao: NewTaskDao(db),
-		logDao:  NewLogDao(db),
-	}
+	return db
+}
 
-	return pgRepository
+// withSearchPath makes every connection resolve the unqualified table names
+// in the configured schema.
+func withSearchPath(dsn string, schema string) (string, error) {
+	if schema == "" {
+		return dsn, nil
+	}
+	if !strings.HasPrefix(dsn, "postgres://") && !strings.HasPrefix(dsn, "postgresql://") {
+		return fmt.Sprintf("%s search_path=%s", dsn, schema), nil
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
 }
 
+// Transaction runs fn on a dedicated connection, so that the DAOs can reach
+// the underlying pgx connection of the transaction, e.g. for COPY.
 func (r *pgRepository) Transaction(fn func(Repository) error) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
-		txRepo := &pgRepository{
-			lg:      r.lg,
-			cfg:     r.cfg,
-			db:      tx,
-			taskDao: NewTaskDao(tx),
-			logDao:  NewLogDao(tx),
+	startedAt := time.Now()
+	err := r.transaction(fn)
+	metrics.ObserveDbTransaction(startedAt, err)
+	return err
+}
+
+func (r *pgRepository) transaction(fn func(Repository) error) error {
+	if r.conn != nil {
+		return r.db.Transaction(func(tx *gorm.DB) error {
+			return fn(r.withTx(tx, r.conn))
+		})
+	}
+	return r.db.Connection(func(connDB *gorm.DB) error {
+		conn, ok := connDB.Statement.ConnPool.(*sql.Conn)
+		if !ok {
+			return fmt.Errorf("%w: unexpected connection %T", ErrDatabase, connDB.Statement.ConnPool)
 		}
-		return fn(txRepo)
+		return connDB.Transaction(func(tx *gorm.DB) error {
+			return fn(r.withTx(tx, conn))
+		})
 	})
 }
 
+func (r *pgRepository) withTx(tx *gorm.DB, conn *sql.Conn) *pgRepository {
+	return &pgRepository{
+		lg:                r.lg,
+		cfg:               r.cfg,
+		db:                tx,
+		conn:              conn,
+		taskDao:           NewTaskDao(tx),
+		logDao:            newLogDao(tx, conn, r.cfg.LogPartitionBlockRange),
+		backfillChunkDao:  NewBackfillChunkDao(tx),
+		deadLetterDao:     NewDeadLetterDao(tx),
+		logPartitionDao:   NewLogPartitionDao(tx, r.cfg.LogPartitionBlockRange),
+		blockDao:          NewBlockDao(tx),
+		transactionDao:    NewTransactionDao(tx),
+		processedRangeDao: NewProcessedRangeDao(tx),
+		notificationDao:   NewPgNotificationDao(tx),
+	}
+}
+
+func (r *pgRepository) Ping(ctx context.Context) error {
+	if err := r.db.WithContext(ctx).Exec("SELECT 1").Error; err != nil {
+		return transformGormError(err)
+	}
+	return nil
+}
+
 func (r *pgRepository) TaskDao() TaskDao {
 	return r.taskDao
 }
@@ -89,6 +203,34 @@ func (r *pgRepository) LogDao() LogDao {
 	return r.logDao
 }
 
+func (r *pgRepository) BackfillChunkDao() BackfillChunkDao {
+	return r.backfillChunkDao
+}
+
+func (r *pgRepository) DeadLetterDao() DeadLetterDao {
+	return r.deadLetterDao
+}
+
+func (r *pgRepository) LogPartitionDao() LogPartitionDao {
+	return r.logPartitionDao
+}
+
+func (r *pgRepository) BlockDao() BlockDao {
+	return r.blockDao
+}
+
+func (r *pgRepository) TransactionDao() TransactionDao {
+	return r.transactionDao
+}
+
+func (r *pgRepository) ProcessedRangeDao() ProcessedRangeDao {
+	return r.processedRangeDao
+}
+
+func (r *pgRepository) NotificationDao() NotificationDao {
+	return r.notificationDao
+}
+
 func (ns customNamingStrategy) TableName(table string) string {
 	return fmt.Sprintf("%s.%s", ns.DbSchema, table)
 }
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultProcessedRangeQueryLimit = 100
+	MaxProcessedRangeQueryLimit     = 1000
+)
+
+// ProcessedRangeFilter selects processed ranges. Zero values do not filter.
+type ProcessedRangeFilter struct {
+	TaskName    string
+	ChainId     int64
+	BlockNumber int64 // ranges containing the block
+	InstanceId  string
+	FromTime    time.Time // committed at or after
+	ToTime      time.Time // committed at or before
+}
+
+type ProcessedRangeDao interface {
+	InsertProcessedRange(ctx context.Context, processedRange do.ProcessedRange) (do.ProcessedRange, error)
+	// QueryProcessedRanges returns the ranges matching the filter, the most
+	// recently committed first.
+	QueryProcessedRanges(ctx context.Context, filter ProcessedRangeFilter, limit int) ([]do.ProcessedRange, error)
+}
+
+type processedRangeDao struct {
+	db *gorm.DB
+}
+
+func NewProcessedRangeDao(db *gorm.DB) ProcessedRangeDao {
+	return &processedRangeDao{db: db}
+}
+
+func (p *processedRangeDao) InsertProcessedRange(ctx context.Context, processedRange do.ProcessedRange) (do.ProcessedRange, error) {
+	if err := p.db.WithContext(ctx).Create(&processedRange).Error; err != nil {
+		return do.ProcessedRange{}, transformGormError(err)
+	}
+	return processedRange, nil
+}
+
+func (p *processedRangeDao) QueryProcessedRanges(ctx context.Context, filter ProcessedRangeFilter, limit int) ([]do.ProcessedRange, error) {
+	db := p.db.WithContext(ctx).Model(&do.ProcessedRange{})
+	if filter.TaskName != "" {
+		db = db.Where("task_name = ?", filter.TaskName)
+	}
+	if filter.ChainId != 0 {
+		db = db.Where("chain_id = ?", filter.ChainId)
+	}
+	if filter.BlockNumber != 0 {
+		db = db.Where("from_block_number <= ? AND to_block_number >= ?", filter.BlockNumber, filter.BlockNumber)
+	}
+	if filter.InstanceId != "" {
+		db = db.Where("instance_id = ?", filter.InstanceId)
+	}
+	if !filter.FromTime.IsZero() {
+		db = db.Where("committed_at >= ?", filter.FromTime)
+	}
+	if !filter.ToTime.IsZero() {
+		db = db.Where("committed_at <= ?", filter.ToTime)
+	}
+
+	var processedRanges []do.ProcessedRange
+	err := db.Order("committed_at DESC, id DESC").Limit(processedRangeQueryLimit(limit)).Find(&processedRanges).Error
+	if err != nil {
+		return nil, transformGormError(err)
+	}
+	return processedRanges, nil
+}
+
+func processedRangeQueryLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultProcessedRangeQueryLimit
+	}
+	return min(limit, MaxProcessedRangeQueryLimit)
+}
@@ -1,8 +1,19 @@
 package repository
 
+import "context"
+
 type Repository interface {
 	Transaction(fn func(Repository) error) error
+	// Ping checks that the database answers queries.
+	Ping(ctx context.Context) error
 
 	TaskDao() TaskDao
 	LogDao() LogDao
+	BackfillChunkDao() BackfillChunkDao
+	DeadLetterDao() DeadLetterDao
+	LogPartitionDao() LogPartitionDao
+	BlockDao() BlockDao
+	TransactionDao() TransactionDao
+	ProcessedRangeDao() ProcessedRangeDao
+	NotificationDao() NotificationDao
 }
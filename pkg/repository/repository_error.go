@@ -2,6 +2,7 @@ package repository
 
 import (
 	"errors"
+	"fmt"
 
 	"gorm.io/gorm"
 )
@@ -9,6 +10,11 @@ import (
 var (
 	ErrRecordNotFound = errors.New("record not found")
 	ErrDuplicatedKey  = errors.New("duplicated key")
+	ErrDatabase       = errors.New("database error")
+	// ErrStaleFencingToken means another instance has taken over the task
+	ErrStaleFencingToken = errors.New("stale fencing token")
+	// ErrTaskRevised means an admin changed the task since it was read
+	ErrTaskRevised = errors.New("task revised")
 )
 
 func transformGormError(err error) error {
@@ -18,6 +24,6 @@ func transformGormError(err error) error {
 	case errors.Is(err, gorm.ErrDuplicatedKey):
 		return ErrDuplicatedKey
 	default:
-		return err
+		return fmt.Errorf("%w: %w", ErrDatabase, err)
 	}
 }
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// migrationDialect holds what the migrations need from a database.
+type migrationDialect interface {
+	// prepare creates the version table "SchemaMigrations" when missing.
+	prepare(db *gorm.DB) error
+	// lock serializes the migrations of concurrent instances within tx.
+	lock(tx *gorm.DB) error
+	// configure sets the settings which the migrations read within tx.
+	configure(tx *gorm.DB) error
+	versionTableExists(db *gorm.DB) (bool, error)
+}
+
+// sqlMigrator applies the migrations one per transaction, recording each
+// version in "SchemaMigrations" within the same transaction.
+type sqlMigrator struct {
+	lg         *zap.Logger
+	db         *gorm.DB
+	dialect    migrationDialect
+	migrations []migration
+}
+
+func (m *sqlMigrator) LatestVersion() int64 {
+	return int64(len(m.migrations))
+}
+
+func (m *sqlMigrator) Version(ctx context.Context) (int64, error) {
+	exists, err := m.dialect.versionTableExists(m.db.WithContext(ctx))
+	if err != nil {
+		return 0, transformGormError(err)
+	}
+	if !exists {
+		return 0, nil
+	}
+	return m.version(m.db.WithContext(ctx))
+}
+
+func (m *sqlMigrator) Up(ctx context.Context) error {
+	if err := m.dialect.prepare(m.db.WithContext(ctx)); err != nil {
+		return transformGormError(err)
+	}
+
+	for _, mg := range m.migrations {
+		err := m.lockedTransaction(ctx, func(tx *gorm.DB) error {
+			version, err := m.version(tx)
+			if err != nil {
+				return err
+			}
+			if version >= mg.Version {
+				return nil
+			}
+
+			if _, err := tx.Statement.ConnPool.ExecContext(ctx, mg.Up); err != nil {
+				return fmt.Errorf("fail to apply migration %d_%s: %w", mg.Version, mg.Name, err)
+			}
+			if err := tx.Exec(`INSERT INTO "SchemaMigrations" (version, name) VALUES (?, ?)`, mg.Version, mg.Name).Error; err != nil {
+				return transformGormError(err)
+			}
+
+			m.lg.Info("migration applied", zap.Int64("version", mg.Version), zap.String("name", mg.Name))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *sqlMigrator) Down(ctx context.Context, steps int64) error {
+	if err := m.dialect.prepare(m.db.WithContext(ctx)); err != nil {
+		return transformGormError(err)
+	}
+
+	for range steps {
+		reverted := false
+		err := m.lockedTransaction(ctx, func(tx *gorm.DB) error {
+			version, err := m.version(tx)
+			if err != nil {
+				return err
+			}
+			if version == 0 {
+				return nil
+			}
+			if version > m.LatestVersion() {
+				return fmt.Errorf("%w: migration %d is unknown to the binary", ErrSchemaAhead, version)
+			}
+
+			mg := m.migrations[version-1]
+			if _, err := tx.Statement.ConnPool.ExecContext(ctx, mg.Down); err != nil {
+				return fmt.Errorf("fail to revert migration %d_%s: %w", mg.Version, mg.Name, err)
+			}
+			if err := tx.Exec(`DELETE FROM "SchemaMigrations" WHERE version = ?`, mg.Version).Error; err != nil {
+				return transformGormError(err)
+			}
+
+			reverted = true
+			m.lg.Info("migration reverted", zap.Int64("version", mg.Version), zap.String("name", mg.Name))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if !reverted {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (m *sqlMigrator) lockedTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := m.dialect.lock(tx); err != nil {
+			return transformGormError(err)
+		}
+		if err := m.dialect.configure(tx); err != nil {
+			return transformGormError(err)
+		}
+		return fn(tx)
+	})
+}
+
+func (m *sqlMigrator) version(db *gorm.DB) (int64, error) {
+	var version int64
+	if err := db.Raw(`SELECT COALESCE(MAX(version), 0) FROM "SchemaMigrations"`).Scan(&version).Error; err != nil {
+		return 0, transformGormError(err)
+	}
+	return version, nil
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"embed"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/sqlite/*.sql
+var sqliteMigrationFS embed.FS
+
+// sqliteMigrationDialect needs no lock, since SQLite serializes the writing
+// transactions.
+type sqliteMigrationDialect struct{}
+
+func newSqliteMigrator(lg *zap.Logger, db *gorm.DB) (*sqlMigrator, error) {
+	migrations, err := loadMigrations(sqliteMigrationFS, "migrations/sqlite")
+	if err != nil {
+		return nil, err
+	}
+	return &sqlMigrator{
+		lg:         lg,
+		db:         db,
+		dialect:    sqliteMigrationDialect{},
+		migrations: migrations,
+	}, nil
+}
+
+func (sqliteMigrationDialect) prepare(db *gorm.DB) error {
+	return db.Exec(`CREATE TABLE IF NOT EXISTS "SchemaMigrations" (
+		version INTEGER NOT NULL PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`).Error
+}
+
+func (sqliteMigrationDialect) lock(tx *gorm.DB) error {
+	return nil
+}
+
+func (sqliteMigrationDialect) configure(tx *gorm.DB) error {
+	return nil
+}
+
+func (sqliteMigrationDialect) versionTableExists(db *gorm.DB) (bool, error) {
+	var count int64
+	err := db.Raw(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'SchemaMigrations'`).Scan(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,295 @@
+package repository
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/samber/lo"
+	"github.com/waynewu411/blocktasks/pkg/config"
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"github.com/waynewu411/blocktasks/pkg/metrics"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// sqliteRepository stores everything in a single SQLite file, so that the
+// tasks can run locally without Postgres. Logs are not partitioned.
+type sqliteRepository struct {
+	lg  *zap.Logger
+	cfg config.SqliteConfig
+	db  *gorm.DB
+
+	taskDao           TaskDao
+	logDao            LogDao
+	backfillChunkDao  BackfillChunkDao
+	deadLetterDao     DeadLetterDao
+	logPartitionDao   LogPartitionDao
+	blockDao          BlockDao
+	transactionDao    TransactionDao
+	processedRangeDao ProcessedRangeDao
+	notificationDao   NotificationDao
+}
+
+func NewSqliteRepository(lg *zap.Logger, cfg config.SqliteConfig) Repository {
+	db := openSqliteDB(lg, cfg)
+
+	migrator, err := newSqliteMigrator(lg, db)
+	if err != nil {
+		lg.Fatal("fail to load migrations", zap.Error(err))
+	}
+	ctx := context.Background()
+	if cfg.AutoMigrate {
+		if err := migrator.Up(ctx); err != nil {
+			lg.Fatal("fail to migrate sqlite database", zap.Error(err))
+		}
+	}
+	if err := CheckSchemaVersion(ctx, migrator); err != nil {
+		lg.Fatal("fail to check database schema version", zap.Error(err))
+	}
+
+	return newSqliteRepository(lg, cfg, db)
+}
+
+// NewSqliteMigrator opens the database without checking the schema version,
+// so that the schema can be migrated in either direction.
+func NewSqliteMigrator(lg *zap.Logger, cfg config.SqliteConfig) Migrator {
+	migrator, err := newSqliteMigrator(lg, openSqliteDB(lg, cfg))
+	if err != nil {
+		lg.Fatal("fail to load migrations", zap.Error(err))
+	}
+	return migrator
+}
+
+func newSqliteRepository(lg *zap.Logger, cfg config.SqliteConfig, db *gorm.DB) *sqliteRepository {
+	return &sqliteRepository{
+		lg:                lg,
+		cfg:               cfg,
+		db:                db,
+		taskDao:           &sqliteTaskDao{taskDao: &taskDao{db: db}},
+		logDao:            &sqliteLogDao{logDao: &logDao{db: db}},
+		backfillChunkDao:  NewBackfillChunkDao(db),
+		deadLetterDao:     NewDeadLetterDao(db),
+		logPartitionDao:   noopLogPartitionDao{},
+		blockDao:          NewBlockDao(db),
+		transactionDao:    NewTransactionDao(db),
+		processedRangeDao: NewProcessedRangeDao(db),
+		notificationDao:   noopNotificationDao{},
+	}
+}
+
+func openSqliteDB(lg *zap.Logger, cfg config.SqliteConfig) *gorm.DB {
+	gormCfg := &gorm.Config{
+		TranslateError: true,
+	}
+
+	if cfg.DebugEnabled {
+		gormCfg.Logger = gormLogger.Default.LogMode(gormLogger.Info)
+	}
+
+	db, err := gorm.Open(sqlite.Open(sqliteDSN(cfg.Path)), gormCfg)
+	if err != nil {
+		lg.Fatal("fail to open sqlite database", zap.String("path", cfg.Path), zap.Error(err))
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		lg.Fatal("fail to get sql db", zap.Error(err))
+	}
+
+	// SQLite has a single writer, and an in-memory database only lives on
+	// its connection
+	sqlDB.SetMaxOpenConns(1)
+
+	lg.Info("successfully opened sqlite database", zap.String("path", cfg.Path))
+
+	return db
+}
+
+// sqliteDSN waits for the lock of a busy database instead of failing, and
+// uses the write-ahead log so that reads do not block the writer.
+func sqliteDSN(path string) string {
+	pragmas := url.Values{}
+	pragmas.Add("_pragma", "busy_timeout(5000)")
+	pragmas.Add("_pragma", "journal_mode(WAL)")
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return path + separator + pragmas.Encode()
+}
+
+func (r *sqliteRepository) Transaction(fn func(Repository) error) error {
+	startedAt := time.Now()
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(newSqliteRepository(r.lg, r.cfg, tx))
+	})
+	metrics.ObserveDbTransaction(startedAt, err)
+	return err
+}
+
+func (r *sqliteRepository) Ping(ctx context.Context) error {
+	if err := r.db.WithContext(ctx).Exec("SELECT 1").Error; err != nil {
+		return transformGormError(err)
+	}
+	return nil
+}
+
+func (r *sqliteRepository) TaskDao() TaskDao {
+	return r.taskDao
+}
+
+func (r *sqliteRepository) LogDao() LogDao {
+	return r.logDao
+}
+
+func (r *sqliteRepository) BackfillChunkDao() BackfillChunkDao {
+	return r.backfillChunkDao
+}
+
+func (r *sqliteRepository) DeadLetterDao() DeadLetterDao {
+	return r.deadLetterDao
+}
+
+func (r *sqliteRepository) LogPartitionDao() LogPartitionDao {
+	return r.logPartitionDao
+}
+
+func (r *sqliteRepository) BlockDao() BlockDao {
+	return r.blockDao
+}
+
+func (r *sqliteRepository) TransactionDao() TransactionDao {
+	return r.transactionDao
+}
+
+func (r *sqliteRepository) ProcessedRangeDao() ProcessedRangeDao {
+	return r.processedRangeDao
+}
+
+func (r *sqliteRepository) NotificationDao() NotificationDao {
+	return r.notificationDao
+}
+
+// sqliteTaskDao reads the time with the functions of SQLite, and shares the
+// rest of taskDao.
+type sqliteTaskDao struct {
+	*taskDao
+}
+
+func (t *sqliteTaskDao) Now(ctx context.Context) (time.Time, error) {
+	var now string
+	err := t.db.WithContext(ctx).Raw(`SELECT strftime('%Y-%m-%d %H:%M:%f', 'now')`).Scan(&now).Error
+	if err != nil {
+		return time.Time{}, transformGormError(err)
+	}
+	return time.ParseInLocation("2006-01-02 15:04:05.000", now, time.UTC)
+}
+
+// sqliteLogDao inserts the logs with plain INSERT statements, and shares the
+// queries of logDao.
+type sqliteLogDao struct {
+	*logDao
+}
+
+// sqliteMaxLogsPerInsert keeps the bound parameters of each statement within
+// the limit of SQLite.
+const sqliteMaxLogsPerInsert = 500
+
+// InsertLogs replaces the stored logs which differ like logDao.InsertLogs,
+// comparing them in Go.
+func (e *sqliteLogDao) InsertLogs(ctx context.Context, logs []do.Log) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	err := e.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		changedAt := time.Now()
+		var newLogs []do.Log
+		pending := map[logIdentity]int{}
+		for _, chunk := range lo.Chunk(logs, sqliteMaxLogsPerInsert) {
+			stored, err := sqliteStoredLogs(tx, chunk)
+			if err != nil {
+				return err
+			}
+			for _, log := range chunk {
+				log, err := normalizeLog(log)
+				if err != nil {
+					return err
+				}
+				identity := newLogIdentity(log)
+				if i, ok := pending[identity]; ok {
+					newLogs[i] = log
+					continue
+				}
+				storedLog, ok := stored[identity]
+				if !ok {
+					pending[identity] = len(newLogs)
+					newLogs = append(newLogs, log)
+					continue
+				}
+				if err := replaceLog(tx, storedLog, log, changedAt); err != nil {
+					return err
+				}
+				stored[identity] = log
+			}
+		}
+		if len(newLogs) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&newLogs, sqliteMaxLogsPerInsert).Error
+	})
+	if err != nil {
+		return transformGormError(err)
+	}
+	return nil
+}
+
+// sqliteStoredLogs returns the stored logs of the transactions of the logs.
+func sqliteStoredLogs(tx *gorm.DB, logs []do.Log) (map[logIdentity]do.Log, error) {
+	stored := map[logIdentity]do.Log{}
+	for chainId, chainLogs := range lo.GroupBy(logs, func(log do.Log) int64 { return log.ChainId }) {
+		txnHashes, err := hexFilterValues(lo.Uniq(lo.Map(chainLogs, func(log do.Log, _ int) string { return log.TxnHash })))
+		if err != nil {
+			return nil, err
+		}
+		var storedLogs []do.Log
+		if err := tx.Where("chain_id = ? AND txn_hash IN ?", chainId, txnHashes).Find(&storedLogs).Error; err != nil {
+			return nil, err
+		}
+		for _, log := range storedLogs {
+			stored[newLogIdentity(log)] = log
+		}
+	}
+	return stored, nil
+}
+
+// replaceLog records the change of a stored log and updates it, unless the
+// new version agrees with it.
+func replaceLog(tx *gorm.DB, stored do.Log, log do.Log, changedAt time.Time) error {
+	change, changed := newLogChange(stored, log, changedAt)
+	if !changed {
+		return nil
+	}
+	if err := tx.Create(&change).Error; err != nil {
+		return err
+	}
+	metrics.ReorgedLogs.Inc()
+
+	hexValues, err := hexColumnValues(stored.TxnHash, log.BlockHash)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&do.Log{}).
+		Where("chain_id = ? AND block_number = ? AND txn_hash = ? AND log_index = ?", stored.ChainId, stored.BlockNumber, hexValues[0], stored.LogIndex).
+		Updates(map[string]any{
+			"block_number":    log.BlockNumber,
+			"block_hash":      hexValues[1],
+			"removed":         log.Removed,
+			"timestamp":       log.Timestamp,
+			"block_timestamp": log.BlockTimestamp,
+		}).Error
+}
@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/waynewu411/blocktasks/pkg/config"
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"go.uber.org/zap/zaptest"
+)
+
+func newTestSqliteConfig(t testing.TB) config.SqliteConfig {
+	return config.SqliteConfig{
+		Path:        filepath.Join(t.TempDir(), "blocktasks.db"),
+		AutoMigrate: true,
+	}
+}
+
+func TestSqliteRepository_Migrate(t *testing.T) {
+	cfg := newTestSqliteConfig(t)
+	cfg.AutoMigrate = false
+	migrator := NewSqliteMigrator(zaptest.NewLogger(t), cfg)
+	ctx := context.Background()
+
+	require.NoError(t, migrator.Up(ctx))
+	version, err := migrator.Version(ctx)
+	require.NoError(t, err)
+	require.Equal(t, migrator.LatestVersion(), version)
+
+	require.NoError(t, migrator.Down(ctx, version))
+	version, err = migrator.Version(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), version)
+	require.ErrorIs(t, CheckSchemaVersion(ctx, migrator), ErrSchemaBehind)
+}
+
+func TestSqliteRepository_Transaction(t *testing.T) {
+	repo := NewSqliteRepository(zaptest.NewLogger(t), newTestSqliteConfig(t))
+	ctx := context.Background()
+
+	log := do.Log{
+		ChainId:     1,
+		BlockNumber: 1000,
+		BlockHash:   "0x0123",
+		Data:        "0x",
+		Topics:      []string{"0x01", "0x02"},
+		TxnHash:     "0x0456",
+		LogIndex:    1,
+		Timestamp:   2000,
+	}
+	err := repo.Transaction(func(repo Repository) error {
+		if _, err := repo.TaskDao().InsertTask(ctx, do.Task{Name: "Task_1", LastProcessedBlockNumber: 1000}); err != nil {
+			return err
+		}
+		if err := repo.LogDao().InsertLogs(ctx, []do.Log{log}); err != nil {
+			return err
+		}
+		return errors.New("Revert")
+	})
+	require.Error(t, err)
+
+	_, err = repo.TaskDao().GetTask(ctx, "Task_1")
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	err = repo.Transaction(func(repo Repository) error {
+		if _, err := repo.TaskDao().InsertTask(ctx, do.Task{Name: "Task_1", LastProcessedBlockNumber: 1000}); err != nil {
+			return err
+		}
+		// inserting the same log twice is a no-op
+		return repo.LogDao().InsertLogs(ctx, []do.Log{log, log})
+	})
+	require.NoError(t, err)
+
+	task, err := repo.TaskDao().GetTask(ctx, "Task_1")
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), task.LastProcessedBlockNumber)
+
+	page, err := repo.LogDao().QueryLogs(ctx, LogFilter{ChainId: 1}, "", 0)
+	require.NoError(t, err)
+	require.Len(t, page.Logs, 1)
+	require.Equal(t, log.Topics, page.Logs[0].Topics)
+}
+
+func TestSqliteRepository_ReviseTask(t *testing.T) {
+	repo := NewSqliteRepository(zaptest.NewLogger(t), newTestSqliteConfig(t))
+	ctx := context.Background()
+
+	task, err := repo.TaskDao().InsertTask(ctx, do.Task{Name: "Task_1", LastProcessedBlockNumber: 1000, FencingToken: 1})
+	require.NoError(t, err)
+
+	revised := task
+	revised.LastProcessedBlockNumber = 500
+	revised.Paused = true
+	revised, err = repo.TaskDao().ReviseTask(ctx, revised)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), revised.Revision)
+
+	// the previous revision can neither be revised nor moved
+	_, err = repo.TaskDao().ReviseTask(ctx, task)
+	require.ErrorIs(t, err, ErrTaskRevised)
+	task.LastProcessedBlockNumber = 1001
+	_, err = repo.TaskDao().UpdateTask(ctx, task)
+	require.ErrorIs(t, err, ErrTaskRevised)
+	revised.FencingToken = 2
+	_, err = repo.TaskDao().UpdateTask(ctx, revised)
+	require.ErrorIs(t, err, ErrStaleFencingToken)
+
+	stored, err := repo.TaskDao().GetTask(ctx, "Task_1")
+	require.NoError(t, err)
+	require.Equal(t, int64(500), stored.LastProcessedBlockNumber)
+	require.True(t, stored.Paused)
+	require.Equal(t, int64(1), stored.Revision)
+}
+
+func TestSqliteRepository_QueryLogs(t *testing.T) {
+	repo := NewSqliteRepository(zaptest.NewLogger(t), newTestSqliteConfig(t))
+	chainId := insertTestLogs(t, repo)
+	ctx := context.Background()
+	start := time.Unix(1700000000, 0).UTC()
+
+	page, err := repo.LogDao().QueryLogs(ctx, LogFilter{ChainId: chainId}, "", 5)
+	require.NoError(t, err)
+	require.Len(t, page.Logs, 5)
+	page, err = repo.LogDao().QueryLogs(ctx, LogFilter{ChainId: chainId}, page.NextCursor, 10)
+	require.NoError(t, err)
+	require.Len(t, page.Logs, 7)
+	require.Empty(t, page.NextCursor)
+
+	tests := []struct {
+		name   string
+		filter LogFilter
+		count  int
+	}{
+		{"address", LogFilter{ChainId: chainId, Addresses: []string{"0xA1"}}, 4},
+		{"topic position", LogFilter{ChainId: chainId, Topics: [][]string{{}, {"0xf1", "0xF2"}}}, 8},
+		{"block range", LogFilter{ChainId: chainId, FromBlockNumber: 101, ToBlockNumber: 102}, 6},
+		{"time range", LogFilter{ChainId: chainId, FromTime: start.Add(2 * time.Second), ToTime: start.Add(2 * time.Second)}, 3},
+	}
+	for _, test := range tests {
+		page, err := repo.LogDao().QueryLogs(ctx, test.filter, "", 0)
+		require.NoError(t, err, test.name)
+		require.Len(t, page.Logs, test.count, test.name)
+	}
+}
+
+func TestSqliteRepository_QueryProcessedRanges(t *testing.T) {
+	repo := NewSqliteRepository(zaptest.NewLogger(t), newTestSqliteConfig(t))
+	ctx := context.Background()
+	committedAt := time.Unix(1700000000, 0).UTC()
+
+	for i, instanceId := range []string{"replica-1", "replica-2", "replica-1"} {
+		_, err := repo.ProcessedRangeDao().InsertProcessedRange(ctx, do.ProcessedRange{
+			TaskName:        "Task_1",
+			ChainId:         1,
+			FromBlockNumber: int64(i*10 + 1),
+			ToBlockNumber:   int64(i*10 + 10),
+			FromBlockHash:   "0x0A",
+			ToBlockHash:     "0x0b",
+			InstanceId:      instanceId,
+			CommittedAt:     committedAt.Add(time.Duration(i) * time.Minute),
+		})
+		require.NoError(t, err)
+	}
+
+	processedRanges, err := repo.ProcessedRangeDao().QueryProcessedRanges(ctx, ProcessedRangeFilter{TaskName: "Task_1"}, 0)
+	require.NoError(t, err)
+	require.Len(t, processedRanges, 3)
+	require.Equal(t, int64(21), processedRanges[0].FromBlockNumber)
+
+	processedRanges, err = repo.ProcessedRangeDao().QueryProcessedRanges(ctx, ProcessedRangeFilter{ChainId: 1, BlockNumber: 15}, 0)
+	require.NoError(t, err)
+	require.Len(t, processedRanges, 1)
+	require.Equal(t, "replica-2", processedRanges[0].InstanceId)
+	require.Equal(t, "0x0a", processedRanges[0].FromBlockHash)
+	require.Equal(t, "0x0b", processedRanges[0].ToBlockHash)
+
+	processedRanges, err = repo.ProcessedRangeDao().QueryProcessedRanges(ctx, ProcessedRangeFilter{InstanceId: "replica-1", ToTime: committedAt}, 0)
+	require.NoError(t, err)
+	require.Len(t, processedRanges, 1)
+	require.Equal(t, int64(1), processedRanges[0].FromBlockNumber)
+}
+
+func TestSqliteRepository_InsertLogsUpsert(t *testing.T) {
+	testLogUpsert(t, NewSqliteRepository(zaptest.NewLogger(t), newTestSqliteConfig(t)))
+}
+
+func TestSqliteRepository_QueryLogsTiebreak(t *testing.T) {
+	testLogPagesTiebreak(t, NewSqliteRepository(zaptest.NewLogger(t), newTestSqliteConfig(t)))
+}
+
+func TestSqliteRepository_Transactions(t *testing.T) {
+	testTransactions(t, NewSqliteRepository(zaptest.NewLogger(t), newTestSqliteConfig(t)))
+}
+
+func TestSqliteRepository_MigrateBinaryHashes(t *testing.T) {
+	lg := zaptest.NewLogger(t)
+	cfg := newTestSqliteConfig(t)
+	db := openSqliteDB(lg, cfg)
+	migrator, err := newSqliteMigrator(lg, db)
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, migrator.Up(ctx))
+	require.NoError(t, migrator.Down(ctx, 1))
+
+	// hashes stored as hex before, one of them with an odd number of digits
+	require.NoError(t, db.Exec(`INSERT INTO "Transactions" VALUES (1, '0xAB00', 100, '0xa', 0, 0, 0, '0xf0', '', '0', 21000, '1', '0x')`).Error)
+	require.NoError(t, migrator.Up(ctx))
+
+	txn, err := newSqliteRepository(lg, cfg, db).TransactionDao().GetTransaction(ctx, 1, "0xab00")
+	require.NoError(t, err)
+	require.Equal(t, "0x0a", txn.BlockHash)
+	require.Equal(t, "0xf0", txn.From)
+	require.Equal(t, "", txn.To)
+	require.Equal(t, "0x", txn.Input)
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/waynewu411/blocktasks/pkg/do"
 	"gorm.io/gorm"
@@ -10,9 +11,24 @@ import (
 
 type TaskDao interface {
 	InsertTask(ctx context.Context, task do.Task) (do.Task, error)
+	// UpdateTask moves the checkpoint of the task. It fails with
+	// ErrStaleFencingToken unless the fencing token of the task is current,
+	// and with ErrTaskRevised unless its revision is.
 	UpdateTask(ctx context.Context, task do.Task) (do.Task, error)
+	UpdateLease(ctx context.Context, task do.Task) (do.Task, error)
+	// ReviseTask writes the checkpoint and the pause of the task on behalf of
+	// an admin and increments its revision. It fails with ErrTaskRevised
+	// unless the revision of the task is current.
+	ReviseTask(ctx context.Context, task do.Task) (do.Task, error)
 	GetTask(ctx context.Context, name string) (do.Task, error)
 	GetTaskForUpdate(ctx context.Context, name string) (do.Task, error)
+	// GetTaskForShare locks the task against changes until the transaction
+	// ends, without blocking other shared locks.
+	GetTaskForShare(ctx context.Context, name string) (do.Task, error)
+	GetTasks(ctx context.Context) ([]do.Task, error)
+	// Now returns the time of the database, so that every instance sees a
+	// lease expire at the same time whatever its clock.
+	Now(ctx context.Context) (time.Time, error)
 }
 
 type taskDao struct {
@@ -31,12 +47,58 @@ func (t *taskDao) InsertTask(ctx context.Context, task do.Task) (do.Task, error)
 }
 
 func (t *taskDao) UpdateTask(ctx context.Context, task do.Task) (do.Task, error) {
-	if err := t.db.WithContext(ctx).Save(&task).Error; err != nil {
+	result := t.db.WithContext(ctx).Model(&do.Task{}).
+		Where("name = ? AND fencing_token = ? AND revision = ?", task.Name, task.FencingToken, task.Revision).
+		Updates(map[string]any{
+			"last_processed_block_number":    task.LastProcessedBlockNumber,
+			"last_processed_block_timestamp": task.LastProcessedBlockTimestamp,
+		})
+	if result.Error != nil {
+		return do.Task{}, transformGormError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		stored, err := t.GetTask(ctx, task.Name)
+		if err == nil && stored.FencingToken == task.FencingToken {
+			return do.Task{}, ErrTaskRevised
+		}
+		return do.Task{}, ErrStaleFencingToken
+	}
+	return task, nil
+}
+
+func (t *taskDao) UpdateLease(ctx context.Context, task do.Task) (do.Task, error) {
+	err := t.db.WithContext(ctx).Model(&do.Task{}).
+		Where("name = ?", task.Name).
+		Updates(map[string]any{
+			"owner":            task.Owner,
+			"lease_expires_at": task.LeaseExpiresAt,
+			"fencing_token":    task.FencingToken,
+		}).Error
+	if err != nil {
 		return do.Task{}, transformGormError(err)
 	}
 	return task, nil
 }
 
+func (t *taskDao) ReviseTask(ctx context.Context, task do.Task) (do.Task, error) {
+	result := t.db.WithContext(ctx).Model(&do.Task{}).
+		Where("name = ? AND revision = ?", task.Name, task.Revision).
+		Updates(map[string]any{
+			"last_processed_block_number":    task.LastProcessedBlockNumber,
+			"last_processed_block_timestamp": task.LastProcessedBlockTimestamp,
+			"paused":                         task.Paused,
+			"revision":                       task.Revision + 1,
+		})
+	if result.Error != nil {
+		return do.Task{}, transformGormError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return do.Task{}, ErrTaskRevised
+	}
+	task.Revision++
+	return task, nil
+}
+
 func (t *taskDao) GetTask(ctx context.Context, name string) (do.Task, error) {
 	var task do.Task
 	err := t.db.WithContext(ctx).Where("name = ?", name).First(&task).Error
@@ -56,3 +118,30 @@ func (t *taskDao) GetTaskForUpdate(ctx context.Context, name string) (do.Task, e
 	}
 	return task, nil
 }
+
+func (t *taskDao) GetTaskForShare(ctx context.Context, name string) (do.Task, error) {
+	var task do.Task
+	err := t.db.WithContext(ctx).Where("name = ?", name).
+		Clauses(clause.Locking{Strength: "SHARE"}).
+		First(&task).Error
+	if err != nil {
+		return do.Task{}, transformGormError(err)
+	}
+	return task, nil
+}
+
+func (t *taskDao) GetTasks(ctx context.Context) ([]do.Task, error) {
+	var tasks []do.Task
+	if err := t.db.WithContext(ctx).Order("name").Find(&tasks).Error; err != nil {
+		return nil, transformGormError(err)
+	}
+	return tasks, nil
+}
+
+func (t *taskDao) Now(ctx context.Context) (time.Time, error) {
+	var now time.Time
+	if err := t.db.WithContext(ctx).Raw(`SELECT now()`).Scan(&now).Error; err != nil {
+		return time.Time{}, transformGormError(err)
+	}
+	return now, nil
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TransactionDao interface {
+	// InsertTransactions stores the transactions, replacing the block of
+	// the transactions which were stored before, e.g. after a reorg.
+	InsertTransactions(ctx context.Context, txns []do.Transaction) error
+	GetTransaction(ctx context.Context, chainId int64, txnHash string) (do.Transaction, error)
+	// GetTransactionsByBlock returns the stored transactions of the block
+	// ordered by transaction index.
+	GetTransactionsByBlock(ctx context.Context, chainId int64, blockNumber int64) ([]do.Transaction, error)
+}
+
+type transactionDao struct {
+	db *gorm.DB
+}
+
+func NewTransactionDao(db *gorm.DB) TransactionDao {
+	return &transactionDao{db: db}
+}
+
+func (t *transactionDao) InsertTransactions(ctx context.Context, txns []do.Transaction) error {
+	if len(txns) == 0 {
+		return nil
+	}
+	err := t.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "chain_id"}, {Name: "txn_hash"}},
+			DoUpdates: clause.AssignmentColumns([]string{"block_number", "block_hash", "transaction_index"}),
+		}).
+		Create(&txns).Error
+	if err != nil {
+		return transformGormError(err)
+	}
+	return nil
+}
+
+func (t *transactionDao) GetTransaction(ctx context.Context, chainId int64, txnHash string) (do.Transaction, error) {
+	txnHashValue, err := do.HexToBytes(txnHash)
+	if err != nil {
+		return do.Transaction{}, fmt.Errorf("%w: %s: %w", ErrInvalidFilter, txnHash, err)
+	}
+	var txn do.Transaction
+	err = t.db.WithContext(ctx).
+		Where("chain_id = ? AND txn_hash = ?", chainId, txnHashValue).
+		First(&txn).Error
+	if err != nil {
+		return do.Transaction{}, transformGormError(err)
+	}
+	return txn, nil
+}
+
+func (t *transactionDao) GetTransactionsByBlock(ctx context.Context, chainId int64, blockNumber int64) ([]do.Transaction, error) {
+	var txns []do.Transaction
+	err := t.db.WithContext(ctx).
+		Where("chain_id = ? AND block_number = ?", chainId, blockNumber).
+		Order("transaction_index").
+		Find(&txns).Error
+	if err != nil {
+		return nil, transformGormError(err)
+	}
+	return txns, nil
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/waynewu411/blocktasks/pkg/do"
+)
+
+// testTransactions checks that transactions are stored and found by hash in
+// either case, with an empty recipient for contract creations.
+func testTransactions(t *testing.T, repo Repository) {
+	ctx := context.Background()
+	chainId := time.Now().UnixNano()
+	txns := []do.Transaction{
+		{ChainId: chainId, TxnHash: "0xab01", BlockNumber: 100, BlockHash: "0x0a", TxnIndex: 1, From: "0xf0", To: "0xa0", Value: "1", GasPrice: "1", Input: "0x"},
+		{ChainId: chainId, TxnHash: "0xab00", BlockNumber: 100, BlockHash: "0x0a", TxnIndex: 0, From: "0xf0", Value: "0", GasPrice: "1", Input: "0x6080"},
+	}
+	require.NoError(t, repo.TransactionDao().InsertTransactions(ctx, txns))
+
+	txn, err := repo.TransactionDao().GetTransaction(ctx, chainId, "0xAB00")
+	require.NoError(t, err)
+	require.Equal(t, txns[1], txn)
+
+	txns[1].BlockNumber = 101
+	txns[1].BlockHash = "0x0b"
+	require.NoError(t, repo.TransactionDao().InsertTransactions(ctx, txns[1:]))
+	byBlock, err := repo.TransactionDao().GetTransactionsByBlock(ctx, chainId, 100)
+	require.NoError(t, err)
+	require.Equal(t, txns[:1], byBlock)
+	txn, err = repo.TransactionDao().GetTransaction(ctx, chainId, "0xab00")
+	require.NoError(t, err)
+	require.Equal(t, "0x0b", txn.BlockHash)
+
+	_, err = repo.TransactionDao().GetTransaction(ctx, chainId, "0xab02")
+	require.ErrorIs(t, err, ErrRecordNotFound)
+	_, err = repo.TransactionDao().GetTransaction(ctx, chainId, "not a hash")
+	require.ErrorIs(t, err, ErrInvalidFilter)
+}
+
+func TestTransactionDao_Transactions(t *testing.T) {
+	testTransactions(t, newTestPgRepository(t))
+}
@@ -1,5 +1,16 @@
 package jsonrpc
 
+import "fmt"
+
+const (
+	ErrCodeParseError     int64 = -32700
+	ErrCodeInvalidRequest int64 = -32600
+	ErrCodeMethodNotFound int64 = -32601
+	ErrCodeInvalidParams  int64 = -32602
+	ErrCodeInternalError  int64 = -32603
+	ErrCodeLimitExceeded  int64 = -32005
+)
+
 type Request struct {
 	Method  string `json:"method"`
 	Params  []any  `json:"params"`
@@ -11,4 +22,15 @@ type Response[T any] struct {
 	JsonRpc string `json:"jsonrpc"`
 	Id      int64  `json:"id"`
 	Result  T      `json:"result"`
+	Error   *Error `json:"error,omitempty"`
+}
+
+type Error struct {
+	Code    int64  `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc error %d: %s", e.Code, e.Message)
 }
@@ -10,6 +10,7 @@
 package request
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "go.uber.org/mock/gomock"
@@ -40,16 +41,16 @@ func (m *MockRequest) EXPECT() *MockRequestMockRecorder {
 }
 
 // MakeRequest mocks base method.
-func (m *MockRequest) MakeRequest(method, apiUrl string, queryParams map[string]string, reqBody string) ([]byte, error) {
+func (m *MockRequest) MakeRequest(ctx context.Context, method, apiUrl string, queryParams map[string]string, reqBody string) ([]byte, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "MakeRequest", method, apiUrl, queryParams, reqBody)
+	ret := m.ctrl.Call(m, "MakeRequest", ctx, method, apiUrl, queryParams, reqBody)
 	ret0, _ := ret[0].([]byte)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // MakeRequest indicates an expected call of MakeRequest.
-func (mr *MockRequestMockRecorder) MakeRequest(method, apiUrl, queryParams, reqBody any) *gomock.Call {
+func (mr *MockRequestMockRecorder) MakeRequest(ctx, method, apiUrl, queryParams, reqBody any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MakeRequest", reflect.TypeOf((*MockRequest)(nil).MakeRequest), method, apiUrl, queryParams, reqBody)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MakeRequest", reflect.TypeOf((*MockRequest)(nil).MakeRequest), ctx, method, apiUrl, queryParams, reqBody)
 }
@@ -3,18 +3,32 @@ package request
 //go:generate mockgen -destination=mock_request.go -package=request github.com/waynewu411/blocktasks/pkg/request Request
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/waynewu411/blocktasks/pkg/httpclient"
 	"go.uber.org/zap"
 )
 
 type Request interface {
-	MakeRequest(method string, apiUrl string, queryParams map[string]string, reqBody string) ([]byte, error)
+	MakeRequest(ctx context.Context, method string, apiUrl string, queryParams map[string]string, reqBody string) ([]byte, error)
+}
+
+// StatusError is returned when the server responds with a non-200 status.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	RetryAfter time.Duration // zero when the server did not send Retry-After
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("failed to make request: %s", e.Status)
 }
 
 type request struct {
@@ -48,7 +62,7 @@ func NewRequest(lg *zap.Logger, opts ...Option) Request {
 	return r
 }
 
-func (t *request) MakeRequest(method string, apiUrl string, queryParams map[string]string, reqBody string) ([]byte, error) {
+func (t *request) MakeRequest(ctx context.Context, method string, apiUrl string, queryParams map[string]string, reqBody string) ([]byte, error) {
 	requestUrl, err := url.Parse(apiUrl)
 	if err != nil {
 		return nil, err
@@ -60,7 +74,7 @@ func (t *request) MakeRequest(method string, apiUrl string, queryParams map[stri
 	}
 	requestUrl.RawQuery = query.Encode()
 
-	req, err := http.NewRequest(method, requestUrl.String(), strings.NewReader(reqBody))
+	req, err := http.NewRequestWithContext(ctx, method, requestUrl.String(), strings.NewReader(reqBody))
 	if err != nil {
 		return nil, err
 	}
@@ -77,7 +91,11 @@ func (t *request) MakeRequest(method string, apiUrl string, queryParams map[stri
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to make request: %s", resp.Status)
+		statusErr := &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+		if seconds, err := strconv.ParseInt(resp.Header.Get("Retry-After"), 10, 64); err == nil {
+			statusErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		return nil, statusErr
 	}
 
 	return io.ReadAll(resp.Body)
@@ -0,0 +1,77 @@
+package retry
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/waynewu411/blocktasks/pkg/repository"
+	"github.com/waynewu411/blocktasks/pkg/request"
+	"github.com/waynewu411/blocktasks/pkg/request/jsonrpc"
+)
+
+type Class int
+
+const (
+	ClassTransientRpc Class = iota
+	ClassRateLimit
+	ClassPermanentRpc
+	ClassDatabase
+	ClassPermanentDatabase
+)
+
+func (c Class) String() string {
+	switch c {
+	case ClassTransientRpc:
+		return "transient_rpc"
+	case ClassRateLimit:
+		return "rate_limit"
+	case ClassPermanentRpc:
+		return "permanent_rpc"
+	case ClassDatabase:
+		return "database"
+	case ClassPermanentDatabase:
+		return "permanent_database"
+	default:
+		return "unknown"
+	}
+}
+
+// Classify tells how an error should be retried. Errors that are not
+// recognised are treated as transient, so they are retried.
+func Classify(err error) Class {
+	switch {
+	case errors.Is(err, repository.ErrStaleFencingToken),
+		errors.Is(err, repository.ErrTaskRevised),
+		errors.Is(err, repository.ErrDuplicatedKey),
+		errors.Is(err, repository.ErrRecordNotFound):
+		return ClassPermanentDatabase
+	case errors.Is(err, repository.ErrDatabase):
+		return ClassDatabase
+	}
+
+	var statusErr *request.StatusError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.StatusCode == http.StatusTooManyRequests:
+			return ClassRateLimit
+		case statusErr.StatusCode == http.StatusRequestTimeout || statusErr.StatusCode >= http.StatusInternalServerError:
+			return ClassTransientRpc
+		default:
+			return ClassPermanentRpc
+		}
+	}
+
+	var rpcErr *jsonrpc.Error
+	if errors.As(err, &rpcErr) {
+		switch rpcErr.Code {
+		case jsonrpc.ErrCodeLimitExceeded, http.StatusTooManyRequests:
+			return ClassRateLimit
+		case jsonrpc.ErrCodeInvalidRequest, jsonrpc.ErrCodeMethodNotFound, jsonrpc.ErrCodeInvalidParams:
+			return ClassPermanentRpc
+		default:
+			return ClassTransientRpc
+		}
+	}
+
+	return ClassTransientRpc
+}
@@ -0,0 +1,87 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/waynewu411/blocktasks/pkg/config"
+	"github.com/waynewu411/blocktasks/pkg/metrics"
+	"github.com/waynewu411/blocktasks/pkg/request"
+)
+
+// Policy retries an operation with exponential backoff and jitter until it
+// succeeds, fails permanently, or runs out of attempts or time.
+type Policy struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	Jitter          float64 // randomization factor between 0 and 1
+	MaxElapsedTime  time.Duration
+	MaxAttempts     int64
+}
+
+func NewPolicy(cfg config.RetryConfig, maxAttempts int64) Policy {
+	return Policy{
+		InitialInterval: time.Duration(cfg.InitialInterval) * time.Millisecond,
+		MaxInterval:     time.Duration(cfg.MaxInterval) * time.Millisecond,
+		Multiplier:      cfg.Multiplier,
+		Jitter:          cfg.Jitter,
+		MaxElapsedTime:  time.Duration(cfg.MaxElapsedTime) * time.Millisecond,
+		MaxAttempts:     maxAttempts,
+	}
+}
+
+// Do calls fn until it returns nil and returns the last error otherwise.
+// Permanent errors are not retried, and a rate limited request waits at
+// least as long as the server asked for.
+func (p Policy) Do(ctx context.Context, fn func() error) error {
+	start := time.Now()
+	interval := p.InitialInterval
+	for attempt := int64(1); ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		class := Classify(err)
+		if class == ClassPermanentRpc || class == ClassPermanentDatabase {
+			return err
+		}
+		if p.MaxAttempts > 0 && attempt >= p.MaxAttempts {
+			return err
+		}
+
+		delay := p.jitter(interval)
+		var statusErr *request.StatusError
+		if class == ClassRateLimit && errors.As(err, &statusErr) && statusErr.RetryAfter > delay {
+			delay = statusErr.RetryAfter
+		}
+		if p.MaxElapsedTime > 0 && time.Since(start)+delay > p.MaxElapsedTime {
+			return err
+		}
+
+		metrics.Retries.WithLabelValues(class.String()).Inc()
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+
+		interval = time.Duration(float64(interval) * max(p.Multiplier, 1))
+		if p.MaxInterval > 0 && interval > p.MaxInterval {
+			interval = p.MaxInterval
+		}
+	}
+}
+
+func (p Policy) jitter(interval time.Duration) time.Duration {
+	if p.Jitter <= 0 || interval <= 0 {
+		return interval
+	}
+	delta := p.Jitter * float64(interval)
+	return time.Duration(float64(interval) - delta + rand.Float64()*2*delta)
+}
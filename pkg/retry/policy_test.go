@@ -0,0 +1,115 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/waynewu411/blocktasks/pkg/repository"
+	"github.com/waynewu411/blocktasks/pkg/request"
+	"github.com/waynewu411/blocktasks/pkg/request/jsonrpc"
+)
+
+func testPolicy() Policy {
+	return Policy{
+		InitialInterval: time.Millisecond,
+		MaxInterval:     4 * time.Millisecond,
+		Multiplier:      2,
+		Jitter:          0.5,
+		MaxAttempts:     5,
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err   error
+		class Class
+	}{
+		{&request.StatusError{StatusCode: http.StatusTooManyRequests}, ClassRateLimit},
+		{&request.StatusError{StatusCode: http.StatusBadGateway}, ClassTransientRpc},
+		{&request.StatusError{StatusCode: http.StatusUnauthorized}, ClassPermanentRpc},
+		{&jsonrpc.Error{Code: jsonrpc.ErrCodeLimitExceeded}, ClassRateLimit},
+		{&jsonrpc.Error{Code: jsonrpc.ErrCodeInvalidParams}, ClassPermanentRpc},
+		{&jsonrpc.Error{Code: -32000}, ClassTransientRpc},
+		{fmt.Errorf("%w: connection reset", repository.ErrDatabase), ClassDatabase},
+		{repository.ErrStaleFencingToken, ClassPermanentDatabase},
+		{errors.New("unexpected EOF"), ClassTransientRpc},
+	}
+	for _, test := range tests {
+		require.Equal(t, test.class, Classify(test.err), test.err.Error())
+	}
+}
+
+func TestPolicy_DoRetriesUntilSuccess(t *testing.T) {
+	attempts := 0
+	err := testPolicy().Do(context.Background(), func() error {
+		attempts++
+		if attempts < 3 {
+			return &request.StatusError{StatusCode: http.StatusServiceUnavailable}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, attempts)
+}
+
+func TestPolicy_DoStopsOnPermanentError(t *testing.T) {
+	attempts := 0
+	err := testPolicy().Do(context.Background(), func() error {
+		attempts++
+		return &jsonrpc.Error{Code: jsonrpc.ErrCodeInvalidParams}
+	})
+	require.Error(t, err)
+	require.Equal(t, 1, attempts)
+}
+
+func TestPolicy_DoStopsAfterMaxAttempts(t *testing.T) {
+	attempts := 0
+	err := testPolicy().Do(context.Background(), func() error {
+		attempts++
+		return fmt.Errorf("%w: deadlock detected", repository.ErrDatabase)
+	})
+	require.ErrorIs(t, err, repository.ErrDatabase)
+	require.Equal(t, 5, attempts)
+}
+
+func TestPolicy_DoStopsAfterMaxElapsedTime(t *testing.T) {
+	policy := testPolicy()
+	policy.MaxAttempts = 0
+	policy.MaxElapsedTime = 20 * time.Millisecond
+
+	start := time.Now()
+	err := policy.Do(context.Background(), func() error {
+		return errors.New("timeout")
+	})
+	require.Error(t, err)
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func TestPolicy_DoHonoursRetryAfter(t *testing.T) {
+	attempts := 0
+	start := time.Now()
+	err := testPolicy().Do(context.Background(), func() error {
+		attempts++
+		if attempts == 1 {
+			return &request.StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: 50 * time.Millisecond}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestPolicy_DoStopsWhenContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := testPolicy().Do(ctx, func() error {
+		return errors.New("timeout")
+	})
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/waynewu411/blocktasks/pkg/chain"
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"github.com/waynewu411/blocktasks/pkg/tasks"
+	"go.uber.org/zap"
+)
+
+const maxAdminBodyBytes = 1 << 10
+
+const (
+	adminTaskStatusActive = "active"
+	adminTaskStatusPaused = "paused"
+)
+
+// WithTaskChain lets the admin API read the chain of the task, to report its
+// lag and to move its checkpoint.
+func WithTaskChain(taskName string, c chain.Chain) ServerOption {
+	return func(s *Server) {
+		s.taskChains[taskName] = c
+	}
+}
+
+func (s *Server) adminRoutes() {
+	if s.cfg.AdminToken == "" {
+		return
+	}
+	s.mux.Handle("GET /v1/admin/tasks", s.admin(s.handleAdminGetTasks))
+	s.mux.Handle("POST /v1/admin/tasks/{name}/pause", s.admin(s.handlePauseTask))
+	s.mux.Handle("POST /v1/admin/tasks/{name}/resume", s.admin(s.handleResumeTask))
+	s.mux.Handle("POST /v1/admin/tasks/{name}/checkpoint", s.admin(s.handleMoveCheckpoint))
+	s.mux.Handle("POST /v1/admin/tasks/{name}/rescan", s.admin(s.handleRescanBlocks))
+}
+
+// admin only lets through the requests bearing the admin token.
+func (s *Server) admin(handler http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.AdminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			s.writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		handler(w, r)
+	})
+}
+
+type adminTask struct {
+	do.Task
+	Status            string             `json:"status"`
+	LatestBlockNumber *int64             `json:"latest_block_number,omitempty"` // latest safe block of the chain
+	Lag               *int64             `json:"lag,omitempty"`                 // blocks between the latest safe block and the checkpoint
+	Rescans           []do.BackfillChunk `json:"rescans"`
+}
+
+type adminTasksResponse struct {
+	Tasks []adminTask `json:"tasks"`
+}
+
+// handleAdminGetTasks returns the tasks with their status, their lag behind
+// the chain when the chain of the task is known, and their rescans.
+func (s *Server) handleAdminGetTasks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	taskList, err := s.repo.TaskDao().GetTasks(ctx)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	latestBlockNumbers := map[int64]int64{}
+	response := adminTasksResponse{Tasks: []adminTask{}}
+	for _, task := range taskList {
+		status := adminTask{Task: task, Status: adminTaskStatusActive, Rescans: []do.BackfillChunk{}}
+		if task.Paused {
+			status.Status = adminTaskStatusPaused
+		}
+
+		rescans, err := tasks.GetRescans(ctx, s.repo, task.Name)
+		if err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+		if rescans != nil {
+			status.Rescans = rescans
+		}
+
+		if c, ok := s.taskChains[task.Name]; ok {
+			latestBlockNumber, ok := latestBlockNumbers[c.GetChainId()]
+			if !ok {
+				// the lag is left out rather than failing the whole list
+				if block, err := c.GetBlockByNumber(ctx, chain.BlockNumberSafe, false); err != nil {
+					s.lg.Error("fail to get latest confirmed block", zap.String("name", task.Name), zap.Error(err))
+				} else {
+					latestBlockNumber, ok = block.BlockNumber, true
+					latestBlockNumbers[c.GetChainId()] = latestBlockNumber
+				}
+			}
+			if ok {
+				lag := max(latestBlockNumber-task.LastProcessedBlockNumber, 0)
+				status.LatestBlockNumber = &latestBlockNumber
+				status.Lag = &lag
+			}
+		}
+
+		response.Tasks = append(response.Tasks, status)
+	}
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+// handlePauseTask stops the monitor of the task before its next range.
+//
+//	POST /v1/admin/tasks/base-log-monitor/pause
+func (s *Server) handlePauseTask(w http.ResponseWriter, r *http.Request) {
+	task, err := tasks.PauseTask(r.Context(), s.repo, r.PathValue("name"))
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	s.lg.Info("task paused", zap.Any("task", task))
+	s.writeJSON(w, http.StatusOK, task)
+}
+
+// handleResumeTask lets the monitor of the task continue from its checkpoint.
+//
+//	POST /v1/admin/tasks/base-log-monitor/resume
+func (s *Server) handleResumeTask(w http.ResponseWriter, r *http.Request) {
+	task, err := tasks.ResumeTask(r.Context(), s.repo, r.PathValue("name"))
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	s.lg.Info("task resumed", zap.Any("task", task))
+	s.writeJSON(w, http.StatusOK, task)
+}
+
+type checkpointRequest struct {
+	BlockNumber *int64 `json:"block_number"`
+}
+
+// handleMoveCheckpoint rewinds or fast-forwards the checkpoint of the task,
+// so that its monitor continues after the block.
+//
+//	POST /v1/admin/tasks/base-log-monitor/checkpoint {"block_number": 25000000}
+func (s *Server) handleMoveCheckpoint(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	var req checkpointRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	if req.BlockNumber == nil || *req.BlockNumber < 0 {
+		s.writeError(w, r, fmt.Errorf("%w: block_number is required", errBadRequest))
+		return
+	}
+	c, ok := s.taskChains[name]
+	if !ok {
+		s.writeError(w, r, fmt.Errorf("%w: no chain for task %s", errBadRequest, name))
+		return
+	}
+
+	// the timestamp of the checkpoint is that of the block
+	block, err := c.GetBlockByNumber(r.Context(), *req.BlockNumber, false)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	task, err := tasks.MoveCheckpoint(r.Context(), s.repo, name, block)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	s.lg.Info("task checkpoint moved", zap.Any("task", task))
+	s.writeJSON(w, http.StatusOK, task)
+}
+
+type rescanRequest struct {
+	FromBlockNumber *int64 `json:"from_block_number"`
+	ToBlockNumber   *int64 `json:"to_block_number"`
+}
+
+// handleRescanBlocks has the monitor of the task fetch and store the blocks
+// again, which must be behind its checkpoint. The rescan runs before the next
+// range of the monitor and is listed with the task until then.
+//
+//	POST /v1/admin/tasks/base-log-monitor/rescan {"from_block_number": 100, "to_block_number": 200}
+func (s *Server) handleRescanBlocks(w http.ResponseWriter, r *http.Request) {
+	var req rescanRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	if req.FromBlockNumber == nil || req.ToBlockNumber == nil {
+		s.writeError(w, r, fmt.Errorf("%w: from_block_number and to_block_number are required", errBadRequest))
+		return
+	}
+
+	rescan, err := tasks.RescanBlocks(r.Context(), s.repo, r.PathValue("name"), *req.FromBlockNumber, *req.ToBlockNumber)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	s.lg.Info("task rescan requested", zap.String("name", r.PathValue("name")), zap.Any("rescan", rescan))
+	s.writeJSON(w, http.StatusAccepted, rescan)
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdminBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("%w: %w", errBadRequest, err)
+	}
+	return nil
+}
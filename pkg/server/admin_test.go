@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/waynewu411/blocktasks/pkg/do"
+)
+
+func adminRequest(t *testing.T, s *Server, method string, target string, body string, v any) int {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	s.Handler().ServeHTTP(recorder, req)
+	if v != nil {
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), v))
+	}
+	return recorder.Code
+}
+
+func TestServer_AdminAuthorization(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	for _, authorization := range []string{"", "Bearer wrong", testAdminToken} {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/v1/admin/tasks/base-log-monitor/pause", nil)
+		req.Header.Set("Authorization", authorization)
+		s.Handler().ServeHTTP(recorder, req)
+		require.Equal(t, http.StatusUnauthorized, recorder.Code, authorization)
+	}
+
+	// not served without a token
+	s.cfg.AdminToken = ""
+	s.mux = http.NewServeMux()
+	s.routes()
+	require.Equal(t, http.StatusNotFound, adminRequest(t, s, http.MethodGet, "/v1/admin/tasks", "", nil))
+}
+
+func TestServer_AdminTasks(t *testing.T) {
+	s, repo := newTestServer(t, WithTaskChain("base-log-monitor", &fakeUpstream{}))
+	ctx := context.Background()
+
+	var task do.Task
+	require.Equal(t, http.StatusOK, adminRequest(t, s, http.MethodPost, "/v1/admin/tasks/base-log-monitor/pause", "", &task))
+	require.True(t, task.Paused)
+	require.Equal(t, int64(1), task.Revision)
+
+	require.Equal(t, http.StatusOK, adminRequest(t, s, http.MethodPost, "/v1/admin/tasks/base-log-monitor/checkpoint", `{"block_number": 101}`, &task))
+	require.Equal(t, int64(101), task.LastProcessedBlockNumber)
+	require.Equal(t, int64(1700000202000), task.LastProcessedBlockTimestamp)
+	require.True(t, task.Paused)
+
+	var response adminTasksResponse
+	require.Equal(t, http.StatusAccepted, adminRequest(t, s, http.MethodPost, "/v1/admin/tasks/base-log-monitor/rescan", `{"from_block_number": 100, "to_block_number": 101}`, nil))
+	require.Equal(t, http.StatusOK, adminRequest(t, s, http.MethodGet, "/v1/admin/tasks", "", &response))
+	require.Len(t, response.Tasks, 1)
+	require.Equal(t, adminTaskStatusPaused, response.Tasks[0].Status)
+	require.Equal(t, int64(108), *response.Tasks[0].LatestBlockNumber)
+	require.Equal(t, int64(7), *response.Tasks[0].Lag)
+	require.Equal(t, []do.BackfillChunk{{
+		TaskName:                 "base-log-monitor-rescan",
+		FromBlockNumber:          100,
+		ToBlockNumber:            101,
+		LastProcessedBlockNumber: 99,
+	}}, response.Tasks[0].Rescans)
+
+	require.Equal(t, http.StatusOK, adminRequest(t, s, http.MethodPost, "/v1/admin/tasks/base-log-monitor/resume", "", &task))
+	require.False(t, task.Paused)
+	stored, err := repo.TaskDao().GetTask(ctx, "base-log-monitor")
+	require.NoError(t, err)
+	require.Equal(t, task, stored)
+}
+
+func TestServer_AdminBadRequest(t *testing.T) {
+	s, _ := newTestServer(t, WithTaskChain("base-log-monitor", &fakeUpstream{}))
+
+	tests := []struct {
+		name   string
+		target string
+		body   string
+		status int
+	}{
+		{"unknown task", "/v1/admin/tasks/unknown/pause", "", http.StatusNotFound},
+		{"no block", "/v1/admin/tasks/base-log-monitor/checkpoint", `{}`, http.StatusBadRequest},
+		{"unknown field", "/v1/admin/tasks/base-log-monitor/checkpoint", `{"block": 1}`, http.StatusBadRequest},
+		{"no chain", "/v1/admin/tasks/other/checkpoint", `{"block_number": 1}`, http.StatusBadRequest},
+		{"rescan past checkpoint", "/v1/admin/tasks/base-log-monitor/rescan", `{"from_block_number": 100, "to_block_number": 104}`, http.StatusBadRequest},
+		{"rescan inverted", "/v1/admin/tasks/base-log-monitor/rescan", `{"from_block_number": 101, "to_block_number": 100}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var response errorResponse
+			require.Equal(t, tt.status, adminRequest(t, s, http.MethodPost, tt.target, tt.body, &response))
+			require.NotEmpty(t, response.Error)
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/waynewu411/blocktasks/pkg/api"
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"github.com/waynewu411/blocktasks/pkg/repository"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcService implements api.BlocktasksServiceServer on the repository, as
+// the HTTP handlers do.
+type grpcService struct {
+	api.UnimplementedBlocktasksServiceServer
+	s *Server
+}
+
+func (s *Server) newGrpcServer() *grpc.Server {
+	grpcServer := grpc.NewServer()
+	api.RegisterBlocktasksServiceServer(grpcServer, &grpcService{s: s})
+	return grpcServer
+}
+
+func (g *grpcService) QueryLogs(ctx context.Context, req *api.QueryLogsRequest) (*api.QueryLogsResponse, error) {
+	page, err := g.s.repo.LogDao().QueryLogs(ctx, newRepositoryLogFilter(req.GetFilter()), req.GetCursor(), int(req.GetLimit()))
+	if err != nil {
+		return nil, g.grpcError("QueryLogs", err)
+	}
+
+	response := &api.QueryLogsResponse{NextCursor: page.NextCursor}
+	for _, log := range page.Logs {
+		response.Logs = append(response.Logs, newApiLog(log))
+	}
+	return response, nil
+}
+
+// SubscribeLogs streams as the HTTP streams do. A slow client is held back
+// by the flow control of its stream.
+func (g *grpcService) SubscribeLogs(req *api.SubscribeLogsRequest, stream grpc.ServerStreamingServer[api.SubscribeLogsResponse]) error {
+	filter := newRepositoryLogFilter(req.GetFilter())
+	if filter.ChainId == 0 {
+		return status.Error(codes.InvalidArgument, "chain_id is required")
+	}
+	if _, err := repository.DecodeLogCursor(req.GetCursor()); err != nil {
+		return g.grpcError("SubscribeLogs", err)
+	}
+
+	err := g.s.streamLogs(stream.Context(), filter, req.GetCursor(),
+		func(message streamMessage) error {
+			return stream.Send(&api.SubscribeLogsResponse{Log: newApiLog(message.Log), Cursor: message.Cursor})
+		},
+		func() error {
+			return nil
+		},
+	)
+	if errors.Is(err, context.Canceled) {
+		return status.Error(codes.Canceled, "subscription closed")
+	}
+	return g.grpcError("SubscribeLogs", err)
+}
+
+func (g *grpcService) GetTaskStatus(ctx context.Context, req *api.GetTaskStatusRequest) (*api.GetTaskStatusResponse, error) {
+	task, err := g.s.repo.TaskDao().GetTask(ctx, req.GetName())
+	if err != nil {
+		return nil, g.grpcError("GetTaskStatus", err)
+	}
+
+	response := &api.GetTaskStatusResponse{
+		Name:                     task.Name,
+		LastProcessedBlockNumber: task.LastProcessedBlockNumber,
+		Owner:                    task.Owner,
+		FencingToken:             task.FencingToken,
+	}
+	if task.LastProcessedBlockTimestamp != 0 {
+		response.LastProcessedBlockTime = timestamppb.New(time.UnixMilli(task.LastProcessedBlockTimestamp))
+	}
+	if !task.LeaseExpiresAt.IsZero() {
+		response.LeaseExpiresAt = timestamppb.New(task.LeaseExpiresAt)
+	}
+	return response, nil
+}
+
+// grpcError maps the errors of the repository to status codes, as
+// writeError does to HTTP status codes.
+func (g *grpcService) grpcError(method string, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, errBadRequest),
+		errors.Is(err, repository.ErrInvalidFilter),
+		errors.Is(err, repository.ErrInvalidCursor):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, repository.ErrRecordNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	default:
+		g.s.lg.Error("fail to handle grpc request", zap.String("method", method), zap.Error(err))
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+func newRepositoryLogFilter(filter *api.LogFilter) repository.LogFilter {
+	logFilter := repository.LogFilter{
+		ChainId:         filter.GetChainId(),
+		Addresses:       filter.GetAddresses(),
+		FromBlockNumber: filter.GetFromBlockNumber(),
+		ToBlockNumber:   filter.GetToBlockNumber(),
+		TxnHash:         filter.GetTxnHash(),
+	}
+	for _, topics := range filter.GetTopics() {
+		logFilter.Topics = append(logFilter.Topics, topics.GetValues())
+	}
+	if filter.GetFromTime() != nil {
+		logFilter.FromTime = filter.GetFromTime().AsTime()
+	}
+	if filter.GetToTime() != nil {
+		logFilter.ToTime = filter.GetToTime().AsTime()
+	}
+	return logFilter
+}
+
+func newApiLog(log do.Log) *api.Log {
+	return &api.Log{
+		ChainId:          log.ChainId,
+		BlockNumber:      log.BlockNumber,
+		BlockHash:        log.BlockHash,
+		Address:          log.Address,
+		Data:             log.Data,
+		Topics:           log.Topics,
+		TxnHash:          log.TxnHash,
+		TransactionIndex: log.TxnIndex,
+		LogIndex:         log.LogIndex,
+		Removed:          log.Removed,
+		BlockTimestamp:   timestamppb.New(log.BlockTimestamp),
+	}
+}
+
+// serveGrpc serves the gRPC API on GrpcAddress until ctx is done, then stops
+// the server gracefully within ShutdownTimeout.
+func (s *Server) serveGrpc(ctx context.Context) error {
+	listener, err := (&net.ListenConfig{}).Listen(ctx, "tcp", s.cfg.GrpcAddress)
+	if err != nil {
+		s.lg.Error("fail to listen", zap.String("address", s.cfg.GrpcAddress), zap.Error(err))
+		return err
+	}
+
+	grpcServer := s.newGrpcServer()
+	errCh := make(chan error, 1)
+	go func() {
+		s.lg.Info("grpc server started", zap.String("address", s.cfg.GrpcAddress))
+		errCh <- grpcServer.Serve(listener)
+	}()
+
+	select {
+	case err := <-errCh:
+		s.lg.Error("fail to serve grpc", zap.String("address", s.cfg.GrpcAddress), zap.Error(err))
+		return fmt.Errorf("grpc server stopped: %w", err)
+	case <-ctx.Done():
+	}
+
+	s.cancelStreams()
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Duration(s.cfg.ShutdownTimeout) * time.Second):
+		grpcServer.Stop()
+	}
+	s.lg.Info("grpc server stopped")
+
+	return ctx.Err()
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/waynewu411/blocktasks/pkg/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestGrpcClient(t *testing.T, s *Server) api.BlocktasksServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := s.newGrpcServer()
+	go grpcServer.Serve(listener)
+	t.Cleanup(func() {
+		s.cancelStreams()
+		grpcServer.Stop()
+	})
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	return api.NewBlocktasksServiceClient(conn)
+}
+
+func TestGrpc_QueryLogs(t *testing.T) {
+	s, _ := newTestServer(t)
+	client := newTestGrpcClient(t, s)
+	ctx := context.Background()
+
+	filter := &api.LogFilter{ChainId: 1, Topics: []*api.TopicFilter{{}, {Values: []string{"0xf1", "0xf2"}}}}
+	var logs []*api.Log
+	cursor := ""
+	for {
+		response, err := client.QueryLogs(ctx, &api.QueryLogsRequest{Filter: filter, Cursor: cursor, Limit: 3})
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(response.GetLogs()), 3)
+		logs = append(logs, response.GetLogs()...)
+		if response.GetNextCursor() == "" {
+			break
+		}
+		cursor = response.GetNextCursor()
+	}
+	require.Len(t, logs, 8)
+	require.Equal(t, int64(100), logs[0].GetBlockNumber())
+	require.Equal(t, int64(1), logs[0].GetLogIndex())
+	require.Equal(t, []string{"0xf0", "0xf1"}, logs[0].GetTopics())
+	require.Equal(t, int64(1700000000), logs[0].GetBlockTimestamp().GetSeconds())
+
+	_, err := client.QueryLogs(ctx, &api.QueryLogsRequest{Filter: filter, Cursor: "bad"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGrpc_SubscribeLogs(t *testing.T) {
+	s, repo := newTestServer(t)
+	client := newTestGrpcClient(t, s)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	filter := &api.LogFilter{ChainId: 1, Addresses: []string{"0xa0"}, FromBlockNumber: 103}
+	stream, err := client.SubscribeLogs(ctx, &api.SubscribeLogsRequest{Filter: filter})
+	require.NoError(t, err)
+
+	// the logs already indexed, then the ones committed later
+	var last *api.SubscribeLogsResponse
+	for _, logIndex := range []int64{0, 2} {
+		last, err = stream.Recv()
+		require.NoError(t, err)
+		require.Equal(t, int64(103), last.GetLog().GetBlockNumber())
+		require.Equal(t, logIndex, last.GetLog().GetLogIndex())
+	}
+	commitTestLog(t, s, repo, 104)
+	response, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, int64(104), response.GetLog().GetBlockNumber())
+	cancel()
+
+	// resuming after the last log received
+	commitTestLog(t, s, repo, 105)
+	stream, err = client.SubscribeLogs(context.Background(), &api.SubscribeLogsRequest{Filter: filter, Cursor: response.GetCursor()})
+	require.NoError(t, err)
+	response, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, int64(105), response.GetLog().GetBlockNumber())
+
+	// the stream ends on shutdown
+	s.cancelStreams()
+	_, err = stream.Recv()
+	require.Equal(t, codes.Canceled, status.Code(err))
+
+	stream, err = client.SubscribeLogs(context.Background(), &api.SubscribeLogsRequest{Filter: &api.LogFilter{}})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGrpc_GetTaskStatus(t *testing.T) {
+	s, _ := newTestServer(t)
+	client := newTestGrpcClient(t, s)
+
+	response, err := client.GetTaskStatus(context.Background(), &api.GetTaskStatusRequest{Name: "base-log-monitor"})
+	require.NoError(t, err)
+	require.Equal(t, int64(103), response.GetLastProcessedBlockNumber())
+	require.Nil(t, response.GetLeaseExpiresAt())
+
+	_, err = client.GetTaskStatus(context.Background(), &api.GetTaskStatusRequest{Name: "unknown"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
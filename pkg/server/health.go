@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/waynewu411/blocktasks/pkg/chain"
+	"go.uber.org/zap"
+)
+
+const (
+	checkStatusOk   = "ok"
+	checkStatusFail = "fail"
+)
+
+type check struct {
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+type chainCheck struct {
+	ChainId int64 `json:"chain_id"`
+	check
+	LatestBlockNumber int64 `json:"latest_block_number"` // latest safe block
+}
+
+type taskCheck struct {
+	Name                     string `json:"name"`
+	Status                   string `json:"status"`
+	Error                    string `json:"error,omitempty"`
+	Paused                   bool   `json:"paused"`
+	LastProcessedBlockNumber int64  `json:"last_processed_block_number"`
+	Lag                      int64  `json:"lag"`
+	MaxLag                   int64  `json:"max_lag"`
+}
+
+type healthResponse struct {
+	Status string `json:"status"`
+}
+
+type readyResponse struct {
+	Status   string       `json:"status"`
+	Database check        `json:"database"`
+	Chains   []chainCheck `json:"chains"`
+	Tasks    []taskCheck  `json:"tasks"`
+}
+
+// handleHealthz tells that the process serves requests.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, healthResponse{Status: checkStatusOk})
+}
+
+// handleReadyz checks the database, the chains of the tasks, and that no task
+// which is not paused lags more than ReadyMaxLag blocks behind the latest
+// safe block of its chain. It answers 503 when a check fails.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	response := readyResponse{Status: checkStatusOk}
+
+	chains := map[int64]chain.Chain{}
+	for _, c := range s.taskChains {
+		chains[c.GetChainId()] = c
+	}
+	response.Chains = make([]chainCheck, 0, len(chains))
+	for _, chainId := range slices.Sorted(maps.Keys(chains)) {
+		response.Chains = append(response.Chains, chainCheck{ChainId: chainId})
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1 + len(response.Chains))
+	go func() {
+		defer wg.Done()
+		response.Database = s.check(ctx, s.repo.Ping)
+	}()
+	for i := range response.Chains {
+		go func() {
+			defer wg.Done()
+			chainCheck := &response.Chains[i]
+			chainCheck.check = s.check(ctx, func(ctx context.Context) error {
+				block, err := chains[chainCheck.ChainId].GetBlockByNumber(ctx, chain.BlockNumberSafe, false)
+				chainCheck.LatestBlockNumber = block.BlockNumber
+				return err
+			})
+		}()
+	}
+	wg.Wait()
+
+	chainChecks := map[int64]chainCheck{}
+	for _, chainCheck := range response.Chains {
+		chainChecks[chainCheck.ChainId] = chainCheck
+	}
+	response.Tasks = make([]taskCheck, 0, len(s.taskChains))
+	for _, name := range slices.Sorted(maps.Keys(s.taskChains)) {
+		chainCheck := chainChecks[s.taskChains[name].GetChainId()]
+		response.Tasks = append(response.Tasks, s.checkTask(ctx, name, response.Database, chainCheck))
+	}
+
+	status := http.StatusOK
+	failed := response.Database.Status != checkStatusOk ||
+		slices.ContainsFunc(response.Chains, func(c chainCheck) bool { return c.Status != checkStatusOk }) ||
+		slices.ContainsFunc(response.Tasks, func(t taskCheck) bool { return t.Status != checkStatusOk })
+	if failed {
+		response.Status = checkStatusFail
+		status = http.StatusServiceUnavailable
+		s.lg.Warn("not ready", zap.Any("response", response))
+	}
+	s.writeJSON(w, status, response)
+}
+
+func (s *Server) checkTask(ctx context.Context, name string, database check, chainStatus chainCheck) taskCheck {
+	result := taskCheck{Name: name, Status: checkStatusFail, MaxLag: s.cfg.ReadyMaxLag}
+	if database.Status != checkStatusOk {
+		result.Error = "database unavailable"
+		return result
+	}
+	task, err := s.repo.TaskDao().GetTask(ctx, name)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Paused = task.Paused
+	result.LastProcessedBlockNumber = task.LastProcessedBlockNumber
+	if chainStatus.Status != checkStatusOk {
+		result.Error = "chain unavailable"
+		return result
+	}
+
+	result.Lag = max(chainStatus.LatestBlockNumber-task.LastProcessedBlockNumber, 0)
+	// a paused task is expected to fall behind
+	if !task.Paused && s.cfg.ReadyMaxLag > 0 && result.Lag > s.cfg.ReadyMaxLag {
+		result.Error = fmt.Sprintf("lag of %d blocks exceeds %d", result.Lag, s.cfg.ReadyMaxLag)
+		return result
+	}
+	result.Status = checkStatusOk
+	return result
+}
+
+// check runs fn within ReadyTimeout.
+func (s *Server) check(ctx context.Context, fn func(ctx context.Context) error) check {
+	if s.cfg.ReadyTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(s.cfg.ReadyTimeout)*time.Second)
+		defer cancel()
+	}
+
+	startedAt := time.Now()
+	err := fn(ctx)
+	result := check{Status: checkStatusOk, LatencyMs: time.Since(startedAt).Milliseconds()}
+	if err != nil {
+		result.Status = checkStatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/waynewu411/blocktasks/pkg/tasks"
+)
+
+func TestServer_Healthz(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	var response healthResponse
+	require.Equal(t, http.StatusOK, get(t, s, "/healthz", &response))
+	require.Equal(t, checkStatusOk, response.Status)
+}
+
+func TestServer_Readyz(t *testing.T) {
+	s, repo := newTestServer(t, WithTaskChain("base-log-monitor", &fakeUpstream{}))
+	ctx := context.Background()
+
+	// the task at block 103 is 5 blocks behind the safe block 108
+	var response readyResponse
+	s.cfg.ReadyMaxLag = 5
+	require.Equal(t, http.StatusOK, get(t, s, "/readyz", &response))
+	require.Equal(t, checkStatusOk, response.Status)
+	require.Equal(t, checkStatusOk, response.Database.Status)
+	require.Len(t, response.Chains, 1)
+	require.Equal(t, int64(1), response.Chains[0].ChainId)
+	require.Equal(t, int64(108), response.Chains[0].LatestBlockNumber)
+	require.Equal(t, []taskCheck{{
+		Name:                     "base-log-monitor",
+		Status:                   checkStatusOk,
+		LastProcessedBlockNumber: 103,
+		Lag:                      5,
+		MaxLag:                   5,
+	}}, response.Tasks)
+
+	s.cfg.ReadyMaxLag = 4
+	response = readyResponse{}
+	require.Equal(t, http.StatusServiceUnavailable, get(t, s, "/readyz", &response))
+	require.Equal(t, checkStatusFail, response.Status)
+	require.Equal(t, checkStatusFail, response.Tasks[0].Status)
+	require.Equal(t, "lag of 5 blocks exceeds 4", response.Tasks[0].Error)
+
+	// a paused task is ready whatever its lag
+	_, err := tasks.PauseTask(ctx, repo, "base-log-monitor")
+	require.NoError(t, err)
+	response = readyResponse{}
+	require.Equal(t, http.StatusOK, get(t, s, "/readyz", &response))
+	require.True(t, response.Tasks[0].Paused)
+
+	// a task not created yet is not ready
+	s, _ = newTestServer(t, WithTaskChain("other", &fakeUpstream{}))
+	response = readyResponse{}
+	require.Equal(t, http.StatusServiceUnavailable, get(t, s, "/readyz", &response))
+	require.Equal(t, checkStatusOk, response.Chains[0].Status)
+	require.Equal(t, "other", response.Tasks[0].Name)
+	require.Equal(t, checkStatusFail, response.Tasks[0].Status)
+}
+
+func TestServer_Metrics(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	recorder := httptest.NewRecorder()
+	s.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "blocktasks_reorged_logs_total")
+
+	// not served when disabled
+	s.cfg.MetricsEnabled = false
+	s.mux = http.NewServeMux()
+	s.routes()
+	recorder = httptest.NewRecorder()
+	s.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
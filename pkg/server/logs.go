@@ -0,0 +1,118 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"github.com/waynewu411/blocktasks/pkg/repository"
+)
+
+type logsResponse struct {
+	Logs       []do.Log `json:"logs"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// handleGetLogs returns a page of logs. Addresses and the alternatives of a
+// topic are given as repeated or comma separated values.
+//
+//	GET /v1/logs?chain_id=8453&address=0x..&topic0=0x..,0x..&from_block=1&to_block=2
+//	    &from_time=2024-01-01T00:00:00Z&to_time=..&txn_hash=0x..&cursor=..&limit=100
+func (s *Server) handleGetLogs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := parseLogFilter(query)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	limit, err := parseInt(query, "limit")
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	page, err := s.repo.LogDao().QueryLogs(r.Context(), filter, query.Get("cursor"), int(limit))
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	logs := page.Logs
+	if logs == nil {
+		logs = []do.Log{}
+	}
+	s.writeJSON(w, http.StatusOK, logsResponse{Logs: logs, NextCursor: page.NextCursor})
+}
+
+func parseLogFilter(query url.Values) (repository.LogFilter, error) {
+	var filter repository.LogFilter
+	var err error
+	if filter.ChainId, err = parseInt(query, "chain_id"); err != nil {
+		return filter, err
+	}
+	filter.Addresses = parseList(query, "address")
+	for i := 0; i < 4; i++ {
+		topics := parseList(query, fmt.Sprintf("topic%d", i))
+		if len(topics) > 0 {
+			filter.Topics = append(filter.Topics, make([][]string, i+1-len(filter.Topics))...)
+			filter.Topics[i] = topics
+		}
+	}
+	if filter.FromBlockNumber, err = parseInt(query, "from_block"); err != nil {
+		return filter, err
+	}
+	if filter.ToBlockNumber, err = parseInt(query, "to_block"); err != nil {
+		return filter, err
+	}
+	if filter.FromTime, err = parseTime(query, "from_time"); err != nil {
+		return filter, err
+	}
+	if filter.ToTime, err = parseTime(query, "to_time"); err != nil {
+		return filter, err
+	}
+	filter.TxnHash = query.Get("txn_hash")
+	return filter, nil
+}
+
+// parseList returns the values of a repeated parameter, splitting comma
+// separated values.
+func parseList(query url.Values, name string) []string {
+	var values []string
+	for _, value := range query[name] {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+func parseInt(query url.Values, name string) (int64, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid %s %q", errBadRequest, name, value)
+	}
+	return n, nil
+}
+
+// parseTime accepts RFC3339 times.
+func parseTime(query url.Values, name string) (time.Time, error) {
+	value := query.Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid %s %q", errBadRequest, name, value)
+	}
+	return t, nil
+}
@@ -0,0 +1,437 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/waynewu411/blocktasks/pkg/chain"
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"github.com/waynewu411/blocktasks/pkg/repository"
+	"github.com/waynewu411/blocktasks/pkg/request/jsonrpc"
+	"github.com/waynewu411/blocktasks/pkg/tasks"
+	"go.uber.org/zap"
+)
+
+const maxRpcBodyBytes = 1 << 20
+
+// RpcChain is a chain whose JSON-RPC requests are answered from the logs
+// indexed by a task, and otherwise by its provider.
+type RpcChain struct {
+	TaskName        string
+	Chain           chain.Chain
+	Addresses       []string // the addresses whose logs are indexed, empty when every log is
+	FromBlockNumber int64    // none of the addresses emitted logs before this block
+}
+
+// getLogsFilter is the filter object of eth_getLogs. The address and the
+// topics are kept raw to be forwarded as given.
+type getLogsFilter struct {
+	FromBlock string            `json:"fromBlock,omitempty"`
+	ToBlock   string            `json:"toBlock,omitempty"`
+	Address   json.RawMessage   `json:"address,omitempty"`
+	Topics    []json.RawMessage `json:"topics,omitempty"`
+	BlockHash string            `json:"blockHash,omitempty"`
+}
+
+type rpcLog struct {
+	Address          string   `json:"address"`
+	Topics           []string `json:"topics"`
+	Data             string   `json:"data"`
+	BlockNumber      string   `json:"blockNumber"`
+	BlockHash        string   `json:"blockHash"`
+	BlockTimestamp   string   `json:"blockTimestamp"`
+	TransactionHash  string   `json:"transactionHash"`
+	TransactionIndex string   `json:"transactionIndex"`
+	LogIndex         string   `json:"logIndex"`
+	Removed          bool     `json:"removed"`
+}
+
+var errTooManyLogs = errors.New("too many logs")
+
+// handleRpc answers a JSON-RPC request or batch for the chain.
+//
+//	POST /v1/rpc/8453
+//	{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[{"fromBlock":"0x1","toBlock":"latest","address":"0x.."}]}
+func (s *Server) handleRpc(w http.ResponseWriter, r *http.Request) {
+	chainId, err := strconv.ParseInt(r.PathValue("chain_id"), 10, 64)
+	rpcChain, ok := s.rpcChains[chainId]
+	if err != nil || !ok {
+		s.writeJSON(w, http.StatusNotFound, errorResponse{Error: fmt.Sprintf("unknown chain %s", r.PathValue("chain_id"))})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRpcBodyBytes))
+	if err != nil {
+		s.writeJSON(w, http.StatusOK, rpcErrorResponse(0, &jsonrpc.Error{Code: jsonrpc.ErrCodeInvalidRequest, Message: err.Error()}))
+		return
+	}
+
+	body = bytes.TrimSpace(body)
+	if !bytes.HasPrefix(body, []byte("[")) {
+		var req jsonrpc.Request
+		if err := json.Unmarshal(body, &req); err != nil {
+			s.writeJSON(w, http.StatusOK, rpcErrorResponse(0, &jsonrpc.Error{Code: jsonrpc.ErrCodeParseError, Message: err.Error()}))
+			return
+		}
+		s.writeJSON(w, http.StatusOK, s.call(r.Context(), rpcChain, req))
+		return
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		s.writeJSON(w, http.StatusOK, rpcErrorResponse(0, &jsonrpc.Error{Code: jsonrpc.ErrCodeParseError, Message: err.Error()}))
+		return
+	}
+	if len(batch) == 0 {
+		s.writeJSON(w, http.StatusOK, rpcErrorResponse(0, &jsonrpc.Error{Code: jsonrpc.ErrCodeInvalidRequest, Message: "empty batch"}))
+		return
+	}
+	responses := make([]jsonrpc.Response[any], 0, len(batch))
+	for _, message := range batch {
+		var req jsonrpc.Request
+		if err := json.Unmarshal(message, &req); err != nil {
+			responses = append(responses, rpcErrorResponse(0, &jsonrpc.Error{Code: jsonrpc.ErrCodeInvalidRequest, Message: err.Error()}))
+			continue
+		}
+		responses = append(responses, s.call(r.Context(), rpcChain, req))
+	}
+	s.writeJSON(w, http.StatusOK, responses)
+}
+
+func (s *Server) call(ctx context.Context, rpcChain RpcChain, req jsonrpc.Request) jsonrpc.Response[any] {
+	var result any
+	var err error
+	switch req.Method {
+	case "eth_chainId":
+		result = toQuantity(rpcChain.Chain.GetChainId())
+	case "eth_blockNumber":
+		result, err = s.blockNumber(ctx, rpcChain)
+	case "eth_getLogs":
+		result, err = s.getLogs(ctx, rpcChain, req.Params)
+	default:
+		err = &jsonrpc.Error{Code: jsonrpc.ErrCodeMethodNotFound, Message: fmt.Sprintf("the method %s does not exist/is not available", req.Method)}
+	}
+
+	var rpcErr *jsonrpc.Error
+	switch {
+	case err == nil:
+		return jsonrpc.Response[any]{JsonRpc: "2.0", Id: req.Id, Result: result}
+	case errors.As(err, &rpcErr):
+		return rpcErrorResponse(req.Id, rpcErr)
+	case errors.Is(err, errBadRequest), errors.Is(err, repository.ErrInvalidFilter):
+		return rpcErrorResponse(req.Id, &jsonrpc.Error{Code: jsonrpc.ErrCodeInvalidParams, Message: err.Error()})
+	case errors.Is(err, errTooManyLogs):
+		return rpcErrorResponse(req.Id, &jsonrpc.Error{
+			Code:    jsonrpc.ErrCodeLimitExceeded,
+			Message: fmt.Sprintf("query returned more than %d results", s.cfg.RpcMaxLogs),
+		})
+	default:
+		s.lg.Error("fail to handle rpc request", zap.Int64("chainId", rpcChain.Chain.GetChainId()), zap.String("method", req.Method), zap.Error(err))
+		return rpcErrorResponse(req.Id, &jsonrpc.Error{Code: jsonrpc.ErrCodeInternalError, Message: "internal error"})
+	}
+}
+
+func rpcErrorResponse(id int64, err *jsonrpc.Error) jsonrpc.Response[any] {
+	return jsonrpc.Response[any]{JsonRpc: "2.0", Id: id, Error: err}
+}
+
+// blockNumber returns the checkpoint of the task, the last block whose logs
+// are indexed.
+func (s *Server) blockNumber(ctx context.Context, rpcChain RpcChain) (string, error) {
+	task, err := s.repo.TaskDao().GetTask(ctx, rpcChain.TaskName)
+	if err != nil {
+		return "", err
+	}
+	return toQuantity(task.LastProcessedBlockNumber), nil
+}
+
+// getLogs answers the blocks up to the checkpoint of the task from the index
+// when they are indexed for the addresses, and the blocks past it from the
+// provider. A filter by block hash is forwarded to the provider.
+func (s *Server) getLogs(ctx context.Context, rpcChain RpcChain, params []any) ([]any, error) {
+	if len(params) != 1 {
+		return nil, fmt.Errorf("%w: expected 1 filter, got %d params", errBadRequest, len(params))
+	}
+	var filter getLogsFilter
+	if err := remarshal(params[0], &filter); err != nil {
+		return nil, fmt.Errorf("%w: invalid filter: %v", errBadRequest, err)
+	}
+	if filter.BlockHash != "" {
+		logs, err := s.getUpstreamLogs(ctx, rpcChain, filter)
+		if err != nil {
+			return nil, err
+		}
+		return s.limitLogs(logs)
+	}
+
+	logFilter, err := newLogFilter(rpcChain.Chain.GetChainId(), filter)
+	if err != nil {
+		return nil, err
+	}
+	if logFilter.FromBlockNumber, err = s.resolveBlock(ctx, rpcChain, filter.FromBlock); err != nil {
+		return nil, err
+	}
+	if logFilter.ToBlockNumber, err = s.resolveBlock(ctx, rpcChain, filter.ToBlock); err != nil {
+		return nil, err
+	}
+	fromBlockNumber, toBlockNumber := logFilter.FromBlockNumber, logFilter.ToBlockNumber
+	if fromBlockNumber > toBlockNumber {
+		return []any{}, nil
+	}
+
+	indexedTo, err := s.indexedTo(ctx, rpcChain, logFilter.Addresses, fromBlockNumber, toBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	logs := []any{}
+	if localFrom := max(fromBlockNumber, rpcChain.FromBlockNumber); localFrom <= indexedTo {
+		logFilter.FromBlockNumber = localFrom
+		logFilter.ToBlockNumber = indexedTo
+		err := s.repo.LogDao().IterateLogs(ctx, logFilter, func(log do.Log) error {
+			if s.cfg.RpcMaxLogs > 0 && int64(len(logs)) >= s.cfg.RpcMaxLogs {
+				return errTooManyLogs
+			}
+			logs = append(logs, newRpcLog(log))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if indexedTo < toBlockNumber {
+		filter.FromBlock = toQuantity(max(fromBlockNumber, indexedTo+1))
+		filter.ToBlock = toQuantity(toBlockNumber)
+		upstreamLogs, err := s.getUpstreamLogs(ctx, rpcChain, filter)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, upstreamLogs...)
+	}
+
+	return s.limitLogs(logs)
+}
+
+// limitLogs fails a result of more than RpcMaxLogs logs, which holds for the
+// logs of the provider too.
+func (s *Server) limitLogs(logs []any) ([]any, error) {
+	if s.cfg.RpcMaxLogs > 0 && int64(len(logs)) > s.cfg.RpcMaxLogs {
+		return nil, errTooManyLogs
+	}
+	return logs, nil
+}
+
+// indexedTo returns the last block of the range whose logs of the addresses
+// are all indexed, or fromBlockNumber-1 when the range is not indexed from
+// its start. The index holds the blocks of the ranges processed by the task
+// and its backfills and rescans up to its checkpoint, but not the blocks
+// skipped by moving the checkpoint forward, nor those of open dead letters or
+// of unfinished backfill chunks.
+func (s *Server) indexedTo(ctx context.Context, rpcChain RpcChain, addresses []string, fromBlockNumber int64, toBlockNumber int64) (int64, error) {
+	if len(rpcChain.Addresses) > 0 {
+		if len(addresses) == 0 {
+			return fromBlockNumber - 1, nil
+		}
+		for _, address := range addresses {
+			if !slices.ContainsFunc(rpcChain.Addresses, func(a string) bool { return strings.EqualFold(a, address) }) {
+				return fromBlockNumber - 1, nil
+			}
+		}
+	}
+
+	task, err := s.repo.TaskDao().GetTask(ctx, rpcChain.TaskName)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return fromBlockNumber - 1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	indexedTo := min(toBlockNumber, task.LastProcessedBlockNumber)
+	// no logs before the first block of the addresses
+	startBlockNumber := max(fromBlockNumber, rpcChain.FromBlockNumber)
+	if startBlockNumber > indexedTo {
+		return indexedTo, nil
+	}
+	// a range containing block 0 cannot be told from any range
+	if startBlockNumber == 0 {
+		return fromBlockNumber - 1, nil
+	}
+
+	processedTo, err := s.processedTo(ctx, rpcChain, startBlockNumber, indexedTo)
+	if err != nil {
+		return 0, err
+	}
+	if processedTo < startBlockNumber {
+		return fromBlockNumber - 1, nil
+	}
+	indexedTo = processedTo
+
+	deadLetters, err := s.repo.DeadLetterDao().GetDeadLetters(ctx, rpcChain.TaskName, do.DeadLetterStatusOpen)
+	if err != nil {
+		return 0, err
+	}
+	for _, deadLetter := range deadLetters {
+		if deadLetter.FromBlockNumber <= indexedTo && deadLetter.ToBlockNumber >= startBlockNumber {
+			return fromBlockNumber - 1, nil
+		}
+	}
+
+	chunks, err := tasks.GetChunks(ctx, s.repo, rpcChain.TaskName)
+	if err != nil {
+		return 0, err
+	}
+	for _, chunk := range chunks {
+		if !chunk.Completed && chunk.LastProcessedBlockNumber < indexedTo && chunk.ToBlockNumber >= startBlockNumber {
+			return fromBlockNumber - 1, nil
+		}
+	}
+
+	return indexedTo, nil
+}
+
+// processedTo returns the last block up to toBlockNumber which the ranges
+// processed by the task cover without a gap from fromBlockNumber, or
+// fromBlockNumber-1 when no range contains it.
+func (s *Server) processedTo(ctx context.Context, rpcChain RpcChain, fromBlockNumber int64, toBlockNumber int64) (int64, error) {
+	processedTo := fromBlockNumber - 1
+	for processedTo < toBlockNumber {
+		next := processedTo
+		for _, taskName := range tasks.ProcessingTaskNames(rpcChain.TaskName) {
+			processedRanges, err := s.repo.ProcessedRangeDao().QueryProcessedRanges(ctx, repository.ProcessedRangeFilter{
+				TaskName:    taskName,
+				BlockNumber: processedTo + 1,
+			}, repository.MaxProcessedRangeQueryLimit)
+			if err != nil {
+				return 0, err
+			}
+			for _, processedRange := range processedRanges {
+				next = max(next, processedRange.ToBlockNumber)
+			}
+		}
+		if next == processedTo {
+			break
+		}
+		processedTo = next
+	}
+	return min(processedTo, toBlockNumber), nil
+}
+
+func (s *Server) getUpstreamLogs(ctx context.Context, rpcChain RpcChain, filter getLogsFilter) ([]any, error) {
+	result, err := rpcChain.Chain.Call(ctx, "eth_getLogs", []any{filter})
+	if err != nil {
+		return nil, err
+	}
+	var logs []json.RawMessage
+	if err := json.Unmarshal(result, &logs); err != nil {
+		return nil, err
+	}
+	upstreamLogs := make([]any, 0, len(logs))
+	for _, log := range logs {
+		upstreamLogs = append(upstreamLogs, log)
+	}
+	return upstreamLogs, nil
+}
+
+// resolveBlock returns the number of a block parameter, asking the provider
+// for the blocks of the tags other than earliest.
+func (s *Server) resolveBlock(ctx context.Context, rpcChain RpcChain, value string) (int64, error) {
+	var blockNumber int64
+	switch value {
+	case "earliest":
+		return 0, nil
+	case "", "latest", "pending":
+		blockNumber = chain.BlockNumberLatest
+	case "safe":
+		blockNumber = chain.BlockNumberSafe
+	case "finalized":
+		blockNumber = chain.BlockNumberFinalized
+	default:
+		n, err := strconv.ParseInt(strings.TrimPrefix(value, "0x"), 16, 64)
+		if err != nil || !strings.HasPrefix(value, "0x") || n < 0 {
+			return 0, fmt.Errorf("%w: invalid block %q", errBadRequest, value)
+		}
+		return n, nil
+	}
+
+	block, err := rpcChain.Chain.GetBlockByNumber(ctx, blockNumber, false)
+	if err != nil {
+		return 0, err
+	}
+	return block.BlockNumber, nil
+}
+
+// newLogFilter returns the filter of the addresses and the topics, given as
+// a value or a list of alternatives each, a null topic matching any. The logs
+// removed by a reorg are left out, as they are no longer on the chain.
+func newLogFilter(chainId int64, filter getLogsFilter) (repository.LogFilter, error) {
+	logFilter := repository.LogFilter{ChainId: chainId, Removed: new(bool)}
+	var err error
+	if logFilter.Addresses, err = parseRpcList(filter.Address); err != nil {
+		return logFilter, fmt.Errorf("%w: invalid address: %v", errBadRequest, err)
+	}
+	for i, topic := range filter.Topics {
+		topics, err := parseRpcList(topic)
+		if err != nil {
+			return logFilter, fmt.Errorf("%w: invalid topic %d: %v", errBadRequest, i, err)
+		}
+		logFilter.Topics = append(logFilter.Topics, topics)
+	}
+	return logFilter, nil
+}
+
+// parseRpcList parses null, a string or a list of strings.
+func parseRpcList(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err == nil {
+		return []string{value}, nil
+	}
+	var values []string
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func newRpcLog(log do.Log) rpcLog {
+	topics := log.Topics
+	if topics == nil {
+		topics = []string{}
+	}
+	return rpcLog{
+		Address:          log.Address,
+		Topics:           topics,
+		Data:             log.Data,
+		BlockNumber:      toQuantity(log.BlockNumber),
+		BlockHash:        log.BlockHash,
+		BlockTimestamp:   toQuantity(log.Timestamp / 1000),
+		TransactionHash:  log.TxnHash,
+		TransactionIndex: toQuantity(log.TxnIndex),
+		LogIndex:         toQuantity(log.LogIndex),
+		Removed:          log.Removed,
+	}
+}
+
+func toQuantity(n int64) string {
+	return "0x" + strconv.FormatInt(n, 16)
+}
+
+// remarshal converts a decoded JSON value into v.
+func remarshal(value any, v any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
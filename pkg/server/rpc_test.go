@@ -0,0 +1,285 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/waynewu411/blocktasks/pkg/chain"
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"github.com/waynewu411/blocktasks/pkg/request/jsonrpc"
+	"github.com/waynewu411/blocktasks/pkg/tasks"
+)
+
+// fakeUpstream is a provider at block 110 which answers eth_getLogs with one
+// log at the first block of the filter.
+type fakeUpstream struct {
+	mu      sync.Mutex
+	filters []getLogsFilter
+}
+
+func (u *fakeUpstream) GetChainId() int64 {
+	return 1
+}
+
+func (u *fakeUpstream) GetBlockByNumber(ctx context.Context, blockNumber int64, fullTxns bool) (chain.Block, error) {
+	switch blockNumber {
+	case chain.BlockNumberLatest:
+		return chain.Block{BlockNumber: 110}, nil
+	case chain.BlockNumberSafe:
+		return chain.Block{BlockNumber: 108}, nil
+	case chain.BlockNumberFinalized:
+		return chain.Block{BlockNumber: 105}, nil
+	}
+	if blockNumber >= 0 && blockNumber <= 110 {
+		return chain.Block{BlockNumber: blockNumber, Timestamp: 1700000000000 + blockNumber*2000}, nil
+	}
+	return chain.Block{}, chain.ErrBlockNotFound
+}
+
+func (u *fakeUpstream) GetBlockByTimestamp(ctx context.Context, timestamp int64) (chain.Block, error) {
+	return chain.Block{}, chain.ErrBlockNotFound
+}
+
+func (u *fakeUpstream) GetBlocks(ctx context.Context, fromBlockNumber int64, toBlockNumber int64, fullTxns bool, includeLogs bool, addresses []string, topics []string) ([]chain.Block, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (u *fakeUpstream) GetTransactionReceipts(ctx context.Context, txnHashes []string) (map[string]chain.Receipt, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (u *fakeUpstream) Call(ctx context.Context, method string, params []any) (json.RawMessage, error) {
+	if method != "eth_getLogs" {
+		return nil, &jsonrpc.Error{Code: jsonrpc.ErrCodeMethodNotFound, Message: method}
+	}
+	var filter getLogsFilter
+	if err := remarshal(params[0], &filter); err != nil {
+		return nil, err
+	}
+	u.mu.Lock()
+	u.filters = append(u.filters, filter)
+	u.mu.Unlock()
+	return json.RawMessage(`[{"address":"0xa0","blockNumber":"` + filter.FromBlock + `","logIndex":"0x0","topics":[]}]`), nil
+}
+
+func (u *fakeUpstream) calls() []getLogsFilter {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.filters
+}
+
+func newTestRpcServer(t *testing.T) (*Server, *fakeUpstream) {
+	t.Helper()
+
+	upstream := &fakeUpstream{}
+	s, repo := newTestServer(t, WithRpcChain(RpcChain{
+		TaskName:        "base-log-monitor",
+		Chain:           upstream,
+		Addresses:       []string{"0xa0", "0xa1"},
+		FromBlockNumber: 90,
+	}))
+	_, err := repo.ProcessedRangeDao().InsertProcessedRange(context.Background(), do.ProcessedRange{
+		TaskName:        "base-log-monitor",
+		ChainId:         1,
+		FromBlockNumber: 90,
+		ToBlockNumber:   103,
+		CommittedAt:     time.Now(),
+	})
+	require.NoError(t, err)
+	return s, upstream
+}
+
+func rpc[T any](t *testing.T, s *Server, method string, params ...any) jsonrpc.Response[T] {
+	t.Helper()
+
+	body, err := json.Marshal(jsonrpc.Request{JsonRpc: "2.0", Id: 7, Method: method, Params: params})
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	s.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/rpc/1", strings.NewReader(string(body))))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var response jsonrpc.Response[T]
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	require.Equal(t, int64(7), response.Id)
+	return response
+}
+
+func TestServer_RpcGetLogsFromIndex(t *testing.T) {
+	s, upstream := newTestRpcServer(t)
+
+	response := rpc[[]rpcLog](t, s, "eth_getLogs", map[string]any{
+		"fromBlock": "0x65",
+		"toBlock":   "0x66",
+		"address":   "0xA1",
+		"topics":    []any{nil, []string{"0xf1", "0xf2"}},
+	})
+	require.Nil(t, response.Error)
+	require.Len(t, response.Result, 2)
+	require.Equal(t, rpcLog{
+		Address:          "0xa1",
+		Topics:           []string{"0xf0", "0xf1"},
+		Data:             "0x",
+		BlockNumber:      "0x65",
+		BlockHash:        "0x00000065",
+		BlockTimestamp:   "0x6553f102",
+		TransactionHash:  "0x0000000000000065",
+		TransactionIndex: "0x0",
+		LogIndex:         "0x1",
+	}, response.Result[0])
+	require.Equal(t, "0x66", response.Result[1].BlockNumber)
+
+	// before the first block of the contracts nothing needs to be indexed
+	response = rpc[[]rpcLog](t, s, "eth_getLogs", map[string]any{"fromBlock": "earliest", "toBlock": "0x64", "address": []string{"0xa0"}})
+	require.Nil(t, response.Error)
+	require.Len(t, response.Result, 2)
+
+	require.Empty(t, upstream.calls())
+}
+
+func TestServer_RpcGetLogsPastCheckpoint(t *testing.T) {
+	s, upstream := newTestRpcServer(t)
+
+	response := rpc[[]rpcLog](t, s, "eth_getLogs", map[string]any{"fromBlock": "0x67", "address": "0xa0"})
+	require.Nil(t, response.Error)
+	require.Len(t, response.Result, 3)
+	require.Equal(t, "0x67", response.Result[1].BlockNumber)
+	require.Equal(t, "0x68", response.Result[2].BlockNumber) // from the provider
+
+	require.Equal(t, []getLogsFilter{{
+		FromBlock: "0x68",
+		ToBlock:   "0x6e",
+		Address:   json.RawMessage(`"0xa0"`),
+	}}, upstream.calls())
+}
+
+func TestServer_RpcGetLogsLimit(t *testing.T) {
+	s, upstream := newTestRpcServer(t)
+	s.cfg.RpcMaxLogs = 8
+
+	// the 8 logs of the index are within the limit, but not with the log of
+	// the provider
+	filter := map[string]any{"fromBlock": "0x64", "toBlock": "0x67", "address": "0xa0"}
+	require.Nil(t, rpc[[]rpcLog](t, s, "eth_getLogs", filter).Error)
+	filter["toBlock"] = "latest"
+	require.Equal(t, jsonrpc.ErrCodeLimitExceeded, rpc[any](t, s, "eth_getLogs", filter).Error.Code)
+	require.Len(t, upstream.calls(), 1)
+}
+
+func TestServer_RpcGetLogsNotIndexed(t *testing.T) {
+	for name, filter := range map[string]map[string]any{
+		"any address":         {"fromBlock": "0x64", "toBlock": "0x65"},
+		"unmonitored address": {"fromBlock": "0x64", "toBlock": "0x65", "address": []string{"0xa0", "0xb0"}},
+		"block hash":          {"blockHash": "0x00000064", "address": "0xa0"},
+	} {
+		s, upstream := newTestRpcServer(t)
+		response := rpc[[]rpcLog](t, s, "eth_getLogs", filter)
+		require.Nil(t, response.Error, name)
+		require.Len(t, response.Result, 1, name)
+		require.Len(t, upstream.calls(), 1, name)
+	}
+
+	// a dead-lettered range has no logs in the index
+	s, upstream := newTestRpcServer(t)
+	_, err := s.repo.DeadLetterDao().RecordDeadLetter(context.Background(), do.DeadLetter{
+		TaskName:        "base-log-monitor",
+		FromBlockNumber: 101,
+		ToBlockNumber:   101,
+		Status:          do.DeadLetterStatusOpen,
+	})
+	require.NoError(t, err)
+	response := rpc[[]rpcLog](t, s, "eth_getLogs", map[string]any{"fromBlock": "0x64", "toBlock": "0x66", "address": "0xa0"})
+	require.Nil(t, response.Error)
+	require.Equal(t, "0x64", upstream.calls()[0].FromBlock)
+}
+
+func TestServer_RpcGetLogsRemoved(t *testing.T) {
+	s, upstream := newTestRpcServer(t)
+	require.NoError(t, s.repo.LogDao().InsertLogs(context.Background(), []do.Log{{
+		ChainId:     1,
+		BlockNumber: 101,
+		BlockHash:   "0x0b",
+		Address:     "0xa0",
+		Data:        "0x",
+		TxnHash:     "0x0c",
+		LogIndex:    5,
+		Removed:     true,
+	}}))
+
+	response := rpc[[]rpcLog](t, s, "eth_getLogs", map[string]any{"fromBlock": "0x65", "toBlock": "0x65", "address": "0xa0"})
+	require.Nil(t, response.Error)
+	require.Len(t, response.Result, 2)
+	for _, log := range response.Result {
+		require.False(t, log.Removed)
+	}
+	require.Empty(t, upstream.calls())
+}
+
+func TestServer_RpcGetLogsNotProcessed(t *testing.T) {
+	ctx := context.Background()
+	s, upstream := newTestRpcServer(t)
+
+	// the blocks skipped by moving the checkpoint forward are not indexed
+	_, err := tasks.MoveCheckpoint(ctx, s.repo, "base-log-monitor", chain.Block{BlockNumber: 108})
+	require.NoError(t, err)
+	response := rpc[[]rpcLog](t, s, "eth_getLogs", map[string]any{"fromBlock": "0x66", "toBlock": "0x6a", "address": "0xa0"})
+	require.Nil(t, response.Error)
+	require.Equal(t, "0x68", upstream.calls()[0].FromBlock)
+
+	// until a backfill processes them
+	_, err = s.repo.ProcessedRangeDao().InsertProcessedRange(ctx, do.ProcessedRange{
+		TaskName:        "base-log-monitor-backfill",
+		ChainId:         1,
+		FromBlockNumber: 104,
+		ToBlockNumber:   108,
+		CommittedAt:     time.Now(),
+	})
+	require.NoError(t, err)
+	response = rpc[[]rpcLog](t, s, "eth_getLogs", map[string]any{"fromBlock": "0x66", "toBlock": "0x6a", "address": "0xa0"})
+	require.Nil(t, response.Error)
+	require.Len(t, upstream.calls(), 1)
+
+	// an unfinished backfill chunk has no logs in the index
+	require.NoError(t, s.repo.BackfillChunkDao().InsertChunks(ctx, []do.BackfillChunk{{
+		TaskName:                 "base-log-monitor-backfill",
+		FromBlockNumber:          104,
+		ToBlockNumber:            108,
+		LastProcessedBlockNumber: 105,
+	}}))
+	response = rpc[[]rpcLog](t, s, "eth_getLogs", map[string]any{"fromBlock": "0x66", "toBlock": "0x6a", "address": "0xa0"})
+	require.Nil(t, response.Error)
+	require.Equal(t, "0x66", upstream.calls()[1].FromBlock)
+}
+
+func TestServer_RpcMethods(t *testing.T) {
+	s, _ := newTestRpcServer(t)
+
+	require.Equal(t, "0x1", rpc[string](t, s, "eth_chainId").Result)
+	require.Equal(t, "0x67", rpc[string](t, s, "eth_blockNumber").Result)
+
+	require.Equal(t, jsonrpc.ErrCodeMethodNotFound, rpc[any](t, s, "eth_sendRawTransaction", "0x00").Error.Code)
+	require.Equal(t, jsonrpc.ErrCodeInvalidParams, rpc[any](t, s, "eth_getLogs").Error.Code)
+	require.Equal(t, jsonrpc.ErrCodeInvalidParams, rpc[any](t, s, "eth_getLogs", map[string]any{"fromBlock": "100"}).Error.Code)
+	require.Equal(t, jsonrpc.ErrCodeLimitExceeded, rpc[any](t, s, "eth_getLogs", map[string]any{"fromBlock": "0x64", "toBlock": "0x67", "address": []string{"0xa0", "0xa1"}}).Error.Code)
+}
+
+func TestServer_RpcBatch(t *testing.T) {
+	s, _ := newTestRpcServer(t)
+
+	recorder := httptest.NewRecorder()
+	body := `[{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]},{"jsonrpc":"2.0","id":2,"method":"eth_blockNumber"}]`
+	s.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/rpc/1", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `[{"jsonrpc":"2.0","id":1,"result":"0x1"},{"jsonrpc":"2.0","id":2,"result":"0x67"}]`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	s.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/rpc/8453", strings.NewReader(body)))
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/waynewu411/blocktasks/pkg/chain"
+	"github.com/waynewu411/blocktasks/pkg/config"
+	"github.com/waynewu411/blocktasks/pkg/repository"
+	"github.com/waynewu411/blocktasks/pkg/tasks"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// Server serves what the tasks indexed over HTTP, reading it through the
+// repository.
+type Server struct {
+	lg         *zap.Logger
+	cfg        config.ServerConfig
+	repo       repository.Repository
+	mux        *http.ServeMux
+	rpcChains  map[int64]RpcChain
+	taskChains map[string]chain.Chain
+	committed  *signal
+	// streams is cancelled on shutdown to end the streams, which would
+	// otherwise hold the shutdown until its timeout
+	streams       context.Context
+	cancelStreams context.CancelFunc
+}
+
+type ServerOption func(*Server)
+
+// WithRpcChain serves the JSON-RPC endpoint of the chain from the logs
+// indexed by the task.
+func WithRpcChain(rpcChain RpcChain) ServerOption {
+	return func(s *Server) {
+		s.rpcChains[rpcChain.Chain.GetChainId()] = rpcChain
+	}
+}
+
+func NewServer(lg *zap.Logger, cfg config.ServerConfig, repo repository.Repository, opts ...ServerOption) *Server {
+	s := &Server{
+		lg:         lg,
+		cfg:        cfg,
+		repo:       repo,
+		mux:        http.NewServeMux(),
+		rpcChains:  map[int64]RpcChain{},
+		taskChains: map[string]chain.Chain{},
+		committed:  newSignal(),
+	}
+	s.streams, s.cancelStreams = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.routes()
+	return s
+}
+
+func (s *Server) routes() {
+	s.mux.HandleFunc("GET /healthz", s.handleHealthz)
+	s.mux.HandleFunc("GET /readyz", s.handleReadyz)
+	if s.cfg.MetricsEnabled {
+		s.mux.Handle("GET /metrics", promhttp.Handler())
+	}
+	s.mux.HandleFunc("GET /v1/logs", s.handleGetLogs)
+	s.mux.HandleFunc("GET /v1/logs/stream", s.handleStreamLogs)
+	s.mux.Handle("GET /v1/logs/ws", s.websocketHandler())
+	s.mux.HandleFunc("GET /v1/tasks", s.handleGetTasks)
+	s.mux.HandleFunc("GET /v1/tasks/{name}", s.handleGetTask)
+	s.mux.HandleFunc("POST /v1/rpc/{chain_id}", s.handleRpc)
+	s.adminRoutes()
+}
+
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// Start serves HTTP, and gRPC when enabled, until ctx is done, then waits up
+// to ShutdownTimeout for the requests in flight.
+func (s *Server) Start(ctx context.Context) error {
+	if !s.cfg.GrpcEnabled {
+		return s.serveHttp(ctx)
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		return s.serveHttp(ctx)
+	})
+	g.Go(func() error {
+		return s.serveGrpc(ctx)
+	})
+	return g.Wait()
+}
+
+func (s *Server) serveHttp(ctx context.Context) error {
+	httpServer := &http.Server{
+		Addr:         s.cfg.Address,
+		Handler:      s.Handler(),
+		ReadTimeout:  time.Duration(s.cfg.ReadTimeout) * time.Second,
+		WriteTimeout: time.Duration(s.cfg.WriteTimeout) * time.Second,
+	}
+	httpServer.RegisterOnShutdown(s.cancelStreams)
+
+	errCh := make(chan error, 1)
+	go func() {
+		s.lg.Info("server started", zap.String("address", s.cfg.Address))
+		errCh <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		s.lg.Error("fail to serve", zap.String("address", s.cfg.Address), zap.Error(err))
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(s.cfg.ShutdownTimeout)*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		s.lg.Error("fail to shut down server", zap.Error(err))
+		return err
+	}
+	s.lg.Info("server stopped")
+
+	return ctx.Err()
+}
+
+var errBadRequest = errors.New("bad request")
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.lg.Error("fail to write response", zap.Error(err))
+	}
+}
+
+// writeError maps the errors of the repository to status codes. Only the
+// errors of the client are described in the response.
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, errBadRequest),
+		errors.Is(err, repository.ErrInvalidFilter),
+		errors.Is(err, repository.ErrInvalidCursor),
+		errors.Is(err, tasks.ErrInvalidRange):
+		s.writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+	case errors.Is(err, repository.ErrRecordNotFound):
+		s.writeJSON(w, http.StatusNotFound, errorResponse{Error: err.Error()})
+	case errors.Is(err, repository.ErrTaskRevised):
+		s.writeJSON(w, http.StatusConflict, errorResponse{Error: err.Error()})
+	default:
+		s.lg.Error("fail to handle request", zap.String("method", r.Method), zap.String("path", r.URL.Path), zap.Error(err))
+		s.writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "internal error"})
+	}
+}
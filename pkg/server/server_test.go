@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/waynewu411/blocktasks/pkg/config"
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"github.com/waynewu411/blocktasks/pkg/repository"
+	"go.uber.org/zap/zaptest"
+)
+
+const testAdminToken = "secret"
+
+// newTestServer serves 3 logs in each of blocks 100 to 103 on chain 1,
+// alternating between 2 addresses.
+func newTestServer(t *testing.T, opts ...ServerOption) (*Server, repository.Repository) {
+	t.Helper()
+
+	repo := repository.NewMemoryRepository()
+	ctx := context.Background()
+	start := time.Unix(1700000000, 0).UTC()
+	var logs []do.Log
+	for blockNumber := int64(100); blockNumber < 104; blockNumber++ {
+		blockTime := start.Add(time.Duration(blockNumber-100) * 2 * time.Second)
+		for logIndex := int64(0); logIndex < 3; logIndex++ {
+			logs = append(logs, do.Log{
+				ChainId:        1,
+				BlockNumber:    blockNumber,
+				BlockHash:      fmt.Sprintf("0x%08x", blockNumber),
+				Address:        fmt.Sprintf("0xa%d", logIndex%2),
+				Data:           "0x",
+				Topics:         []string{"0xf0", fmt.Sprintf("0xf%d", logIndex)},
+				TxnHash:        fmt.Sprintf("0x%016x", blockNumber),
+				LogIndex:       logIndex,
+				Timestamp:      blockTime.UnixMilli(),
+				BlockTimestamp: blockTime,
+			})
+		}
+	}
+	require.NoError(t, repo.LogDao().InsertLogs(ctx, logs))
+	_, err := repo.TaskDao().InsertTask(ctx, do.Task{Name: "base-log-monitor", LastProcessedBlockNumber: 103})
+	require.NoError(t, err)
+
+	return NewServer(zaptest.NewLogger(t), config.ServerConfig{RpcMaxLogs: 10, AdminToken: testAdminToken, MetricsEnabled: true}, repo, opts...), repo
+}
+
+func get(t *testing.T, s *Server, target string, v any) int {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	s.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+	require.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	if v != nil {
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), v))
+	}
+	return recorder.Code
+}
+
+func TestServer_GetLogs(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		target string
+		count  int
+	}{
+		{"chain", "/v1/logs?chain_id=1", 12},
+		{"other chain", "/v1/logs?chain_id=2", 0},
+		{"address", "/v1/logs?chain_id=1&address=0xA1", 4},
+		{"addresses", "/v1/logs?address=0xa0,0xa1", 12},
+		{"topic position", "/v1/logs?topic1=0xf1&topic1=0xf2", 8},
+		{"block range", "/v1/logs?from_block=101&to_block=102", 6},
+		{"time range", "/v1/logs?from_time=2023-11-14T22:13:22Z&to_time=2023-11-14T22:13:22Z", 3},
+		{"txn hash", "/v1/logs?txn_hash=0x0000000000000067", 3},
+	}
+	for _, test := range tests {
+		var response logsResponse
+		require.Equal(t, http.StatusOK, get(t, s, test.target, &response), test.name)
+		require.Len(t, response.Logs, test.count, test.name)
+	}
+}
+
+func TestServer_GetLogsPagination(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	var logs []do.Log
+	target := "/v1/logs?chain_id=1&limit=5"
+	for {
+		var response logsResponse
+		require.Equal(t, http.StatusOK, get(t, s, target, &response))
+		logs = append(logs, response.Logs...)
+		if response.NextCursor == "" {
+			break
+		}
+		target = "/v1/logs?chain_id=1&limit=5&cursor=" + response.NextCursor
+	}
+
+	require.Len(t, logs, 12)
+	require.Equal(t, int64(103), logs[11].BlockNumber)
+	require.Equal(t, []string{"0xf0", "0xf2"}, logs[11].Topics)
+}
+
+func TestServer_GetLogsBadRequest(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	for _, target := range []string{
+		"/v1/logs?chain_id=base",
+		"/v1/logs?from_time=yesterday",
+		"/v1/logs?cursor=invalid",
+		"/v1/logs?address=0xzz",
+	} {
+		var response errorResponse
+		require.Equal(t, http.StatusBadRequest, get(t, s, target, &response), target)
+		require.NotEmpty(t, response.Error, target)
+	}
+}
+
+func TestServer_GetTasks(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	var response tasksResponse
+	require.Equal(t, http.StatusOK, get(t, s, "/v1/tasks", &response))
+	require.Len(t, response.Tasks, 1)
+	require.Equal(t, int64(103), response.Tasks[0].LastProcessedBlockNumber)
+
+	var task do.Task
+	require.Equal(t, http.StatusOK, get(t, s, "/v1/tasks/base-log-monitor", &task))
+	require.Equal(t, "base-log-monitor", task.Name)
+
+	require.Equal(t, http.StatusNotFound, get(t, s, "/v1/tasks/unknown", nil))
+}
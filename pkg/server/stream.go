@@ -0,0 +1,222 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"github.com/waynewu411/blocktasks/pkg/repository"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+)
+
+const streamPageSize = 500
+
+// signal wakes up all the goroutines waiting on it.
+type signal struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+func newSignal() *signal {
+	return &signal{ch: make(chan struct{})}
+}
+
+// wait returns a channel which is closed on the next broadcast.
+func (s *signal) wait() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ch
+}
+
+func (s *signal) broadcast() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	close(s.ch)
+	s.ch = make(chan struct{})
+}
+
+// NotifyLogs wakes up the streams when a monitor of this instance commits a
+// range. Logs committed elsewhere are found by polling.
+func (s *Server) NotifyLogs(notification repository.LogNotification) {
+	s.committed.broadcast()
+}
+
+type streamMessage struct {
+	Cursor string `json:"cursor"`
+	Log    do.Log `json:"log"`
+}
+
+// parseStream returns the filter and the cursor of a stream. A chain is
+// required, since the logs are streamed in the order of the chain, block
+// number and log index.
+func parseStream(r *http.Request, cursor string) (repository.LogFilter, string, error) {
+	filter, err := parseLogFilter(r.URL.Query())
+	if err != nil {
+		return filter, "", err
+	}
+	if filter.ChainId == 0 {
+		return filter, "", fmt.Errorf("%w: chain_id is required", errBadRequest)
+	}
+	if _, err := repository.DecodeLogCursor(cursor); err != nil {
+		return filter, "", err
+	}
+	return filter, cursor, nil
+}
+
+// streamLogs sends the logs matching the filter after the cursor, then the
+// logs committed later, until ctx is done or send fails. A page is only
+// read once the client received the previous one, so a slow client holds
+// up its own stream rather than the memory of the server. idle is called
+// when the stream caught up and no log was committed for a poll interval.
+func (s *Server) streamLogs(ctx context.Context, filter repository.LogFilter, cursor string, send func(streamMessage) error, idle func() error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(s.streams, cancel)
+	defer stop()
+
+	pollInterval := time.Duration(max(s.cfg.StreamPollInterval, 1)) * time.Second
+	for {
+		// taken before the query, so that a commit during the query is not missed
+		committed := s.committed.wait()
+
+		page, err := s.repo.LogDao().QueryLogs(ctx, filter, cursor, streamPageSize)
+		if err != nil {
+			return err
+		}
+		for _, log := range page.Logs {
+			cursor = repository.NewLogCursor(log).Encode()
+			if err := send(streamMessage{Cursor: cursor, Log: log}); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor != "" {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-committed:
+		case <-time.After(pollInterval):
+			if err := idle(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// streamWriteDeadline returns the deadline of the next message of a stream,
+// none when StreamWriteTimeout is 0.
+func (s *Server) streamWriteDeadline() time.Time {
+	if s.cfg.StreamWriteTimeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(time.Duration(s.cfg.StreamWriteTimeout) * time.Second)
+}
+
+// handleStreamLogs streams the logs as Server-Sent Events, taking the same
+// filters as /v1/logs. Each event carries the cursor of its log as id, so a
+// client reconnecting with Last-Event-ID resumes after the last log it got.
+//
+//	GET /v1/logs/stream?chain_id=8453&address=0x..&cursor=..
+func (s *Server) handleStreamLogs(w http.ResponseWriter, r *http.Request) {
+	cursor := r.URL.Query().Get("cursor")
+	if lastEventId := r.Header.Get("Last-Event-ID"); lastEventId != "" {
+		cursor = lastEventId
+	}
+	filter, cursor, err := parseStream(r, cursor)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	controller := http.NewResponseController(w)
+	write := func(format string, args ...any) error {
+		if err := controller.SetWriteDeadline(s.streamWriteDeadline()); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return err
+		}
+		return controller.Flush()
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := write(": subscribed\n\n"); err != nil {
+		return
+	}
+
+	err = s.streamLogs(r.Context(), filter, cursor,
+		func(message streamMessage) error {
+			data, err := json.Marshal(message.Log)
+			if err != nil {
+				return err
+			}
+			return write("id: %s\nevent: log\ndata: %s\n\n", message.Cursor, data)
+		},
+		func() error {
+			return write(": ping\n\n")
+		},
+	)
+	s.lg.Debug("log stream closed", zap.String("transport", "sse"), zap.Error(err))
+}
+
+// websocketHandler streams the logs over a WebSocket as JSON messages of the
+// log and its cursor, taking the same filters as /v1/logs. The client only
+// receives, and resumes with the cursor of the last message it got.
+//
+//	GET /v1/logs/ws?chain_id=8453&address=0x..&cursor=..
+func (s *Server) websocketHandler() http.Handler {
+	server := websocket.Server{Handler: s.handleWebsocket}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// reject a bad filter before the upgrade, with a status the client sees
+		if _, _, err := parseStream(r, r.URL.Query().Get("cursor")); err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+		server.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleWebsocket(ws *websocket.Conn) {
+	defer ws.Close()
+
+	filter, cursor, err := parseStream(ws.Request(), ws.Request().URL.Query().Get("cursor"))
+	if err != nil {
+		return
+	}
+
+	// the connection is hijacked, so a closed connection is only seen by
+	// reading from it
+	ctx, cancel := context.WithCancel(ws.Request().Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		var discard []byte
+		for {
+			if err := websocket.Message.Receive(ws, &discard); err != nil {
+				return
+			}
+		}
+	}()
+
+	err = s.streamLogs(ctx, filter, cursor,
+		func(message streamMessage) error {
+			if err := ws.SetWriteDeadline(s.streamWriteDeadline()); err != nil {
+				return err
+			}
+			return websocket.JSON.Send(ws, message)
+		},
+		func() error {
+			return nil
+		},
+	)
+	s.lg.Debug("log stream closed", zap.String("transport", "websocket"), zap.Error(err))
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"github.com/waynewu411/blocktasks/pkg/repository"
+	"golang.org/x/net/websocket"
+)
+
+// commitTestLog stores a log of 0xa0 in the block and wakes up the streams,
+// as a monitor committing the block would.
+func commitTestLog(t *testing.T, s *Server, repo repository.Repository, blockNumber int64) {
+	t.Helper()
+
+	require.NoError(t, repo.LogDao().InsertLogs(context.Background(), []do.Log{{
+		ChainId:        1,
+		BlockNumber:    blockNumber,
+		BlockHash:      "0x01",
+		Address:        "0xa0",
+		Data:           "0x",
+		Topics:         []string{"0xf0"},
+		TxnHash:        fmt.Sprintf("0x%016x", blockNumber),
+		Timestamp:      time.Now().UnixMilli(),
+		BlockTimestamp: time.Now(),
+	}}))
+	s.NotifyLogs(repository.LogNotification{ChainId: 1, FromBlockNumber: blockNumber, ToBlockNumber: blockNumber})
+}
+
+type sseEvent struct {
+	id  string
+	log do.Log
+}
+
+// readEvent returns the next log event, skipping the comments.
+func readEvent(t *testing.T, scanner *bufio.Scanner) sseEvent {
+	t.Helper()
+
+	var event sseEvent
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.log))
+		case line == "" && event.id != "":
+			return event
+		}
+	}
+	require.NoError(t, scanner.Err())
+	require.Fail(t, "stream closed")
+	return event
+}
+
+func subscribe(t *testing.T, url string, lastEventId string) (*http.Response, *bufio.Scanner) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	if lastEventId != "" {
+		req.Header.Set("Last-Event-ID", lastEventId)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	return resp, bufio.NewScanner(resp.Body)
+}
+
+func TestServer_StreamLogs(t *testing.T) {
+	s, repo := newTestServer(t)
+	httpServer := httptest.NewServer(s.Handler())
+	defer httpServer.Close()
+	defer s.cancelStreams()
+	url := httpServer.URL + "/v1/logs/stream?chain_id=1&address=0xa0"
+
+	// the stored logs, then the committed ones in order
+	_, scanner := subscribe(t, url, "")
+	var events []sseEvent
+	for i := 0; i < 8; i++ {
+		events = append(events, readEvent(t, scanner))
+	}
+	require.Equal(t, int64(100), events[0].log.BlockNumber)
+	require.Equal(t, int64(103), events[7].log.BlockNumber)
+
+	commitTestLog(t, s, repo, 104)
+	commitTestLog(t, s, repo, 105)
+	require.Equal(t, int64(104), readEvent(t, scanner).log.BlockNumber)
+	require.Equal(t, int64(105), readEvent(t, scanner).log.BlockNumber)
+
+	// a reconnecting client resumes after the last event it got
+	_, scanner = subscribe(t, url, events[6].id)
+	event := readEvent(t, scanner)
+	require.Equal(t, events[7], event)
+	require.Equal(t, int64(104), readEvent(t, scanner).log.BlockNumber)
+
+	// streams end on shutdown
+	s.cancelStreams()
+	for scanner.Scan() {
+	}
+}
+
+func TestServer_StreamLogsBadRequest(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	for _, target := range []string{
+		"/v1/logs/stream?address=0xa0",
+		"/v1/logs/stream?chain_id=1&cursor=invalid",
+		"/v1/logs/ws?address=0xa0",
+	} {
+		var response errorResponse
+		require.Equal(t, http.StatusBadRequest, get(t, s, target, &response), target)
+	}
+}
+
+func TestServer_WebsocketLogs(t *testing.T) {
+	s, repo := newTestServer(t)
+	httpServer := httptest.NewServer(s.Handler())
+	defer httpServer.Close()
+	defer s.cancelStreams()
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/v1/logs/ws?chain_id=1&topic1=0xf1"
+
+	ws, err := websocket.Dial(url, "", httpServer.URL)
+	require.NoError(t, err)
+	defer ws.Close()
+
+	var messages []streamMessage
+	for i := 0; i < 4; i++ {
+		var message streamMessage
+		require.NoError(t, websocket.JSON.Receive(ws, &message))
+		messages = append(messages, message)
+	}
+	for i, message := range messages {
+		require.Equal(t, int64(100+i), message.Log.BlockNumber)
+		require.Equal(t, int64(1), message.Log.LogIndex)
+	}
+
+	// resume after the second message
+	resumed, err := websocket.Dial(url+"&cursor="+messages[1].Cursor, "", httpServer.URL)
+	require.NoError(t, err)
+	defer resumed.Close()
+	var message streamMessage
+	require.NoError(t, websocket.JSON.Receive(resumed, &message))
+	require.Equal(t, messages[2], message)
+
+	// logs not matching the filter are not sent
+	commitTestLog(t, s, repo, 104)
+	require.NoError(t, repo.LogDao().InsertLogs(context.Background(), []do.Log{{
+		ChainId:     1,
+		BlockNumber: 105,
+		BlockHash:   "0x01",
+		Address:     "0xa1",
+		Data:        "0x",
+		Topics:      []string{"0xf0", "0xf1"},
+		TxnHash:     "0x03",
+	}}))
+	s.NotifyLogs(repository.LogNotification{ChainId: 1, FromBlockNumber: 105, ToBlockNumber: 105})
+	require.NoError(t, websocket.JSON.Receive(ws, &message))
+	require.Equal(t, int64(105), message.Log.BlockNumber)
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/waynewu411/blocktasks/pkg/do"
+)
+
+type tasksResponse struct {
+	Tasks []do.Task `json:"tasks"`
+}
+
+// handleGetTasks returns the checkpoints of all the tasks.
+func (s *Server) handleGetTasks(w http.ResponseWriter, r *http.Request) {
+	tasks, err := s.repo.TaskDao().GetTasks(r.Context())
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	if tasks == nil {
+		tasks = []do.Task{}
+	}
+	s.writeJSON(w, http.StatusOK, tasksResponse{Tasks: tasks})
+}
+
+func (s *Server) handleGetTask(w http.ResponseWriter, r *http.Request) {
+	task, err := s.repo.TaskDao().GetTask(r.Context(), r.PathValue("name"))
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, task)
+}
@@ -0,0 +1,279 @@
+package tasks
+
+import (
+	"context"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/waynewu411/blocktasks/pkg/chain"
+	"github.com/waynewu411/blocktasks/pkg/config"
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"github.com/waynewu411/blocktasks/pkg/metrics"
+	"github.com/waynewu411/blocktasks/pkg/repository"
+	"github.com/waynewu411/blocktasks/pkg/retry"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// Backfill indexes the logs of a fixed block range. The range is split into
+// chunks which are processed by concurrent workers, and each chunk keeps its
+// own checkpoint so that a restart only resumes the unfinished chunks.
+type Backfill struct {
+	baseTask
+	instanceId  string
+	cfg         config.EventMonitorConfig
+	repo        repository.Repository
+	chain       chain.Chain
+	retryPolicy retry.Policy
+
+	planned         bool // whether chunks are planned for the range below
+	fromBlockNumber int64
+	toBlockNumber   int64
+
+	fencedTaskName string // task whose fencing token the commits check
+	fencingToken   int64
+}
+
+type BackfillOption func(*Backfill)
+
+// WithChunkPlanning plans chunks for the blocks of the range which are not
+// covered by the persisted chunks. Without it only the persisted chunks are
+// processed.
+func WithChunkPlanning(fromBlockNumber int64, toBlockNumber int64) BackfillOption {
+	return func(b *Backfill) {
+		b.planned = true
+		b.fromBlockNumber = fromBlockNumber
+		b.toBlockNumber = toBlockNumber
+	}
+}
+
+// WithFencing makes each commit check that the task still has the fencing
+// token, so that an instance which lost the lease of the task stops writing.
+func WithFencing(taskName string, fencingToken int64) BackfillOption {
+	return func(b *Backfill) {
+		b.fencedTaskName = taskName
+		b.fencingToken = fencingToken
+	}
+}
+
+func NewBackfill(lg *zap.Logger, name string, instanceId string, cfg config.EventMonitorConfig, repo repository.Repository, chain chain.Chain, opts ...BackfillOption) Task {
+	b := &Backfill{
+		baseTask: baseTask{
+			lg:   lg,
+			name: name,
+		},
+		instanceId:  instanceId,
+		cfg:         cfg,
+		repo:        repo,
+		chain:       chain,
+		retryPolicy: retry.NewPolicy(cfg.RetryConfig, cfg.MaxBlockRetries),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+func (b *Backfill) Start(ctx context.Context) error {
+	chunks, err := b.planChunks(ctx)
+	if err != nil {
+		b.lg.Error("fail to plan chunks", zap.String("name", b.name), zap.Error(err))
+		return err
+	}
+
+	pendingChunks := lo.Filter(chunks, func(chunk do.BackfillChunk, _ int) bool {
+		return !chunk.Completed
+	})
+	b.lg.Info(
+		"backfill started",
+		zap.String("name", b.name),
+		zap.Int("chunks", len(chunks)),
+		zap.Int("pendingChunks", len(pendingChunks)),
+	)
+
+	workers := b.cfg.BackfillConfig.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(int(workers))
+	for _, chunk := range pendingChunks {
+		eg.Go(func() error {
+			return b.processChunk(egCtx, chunk)
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		b.lg.Error("backfill failed", zap.String("name", b.name), zap.Error(err))
+		return err
+	}
+
+	b.lg.Info("backfill completed", zap.String("name", b.name))
+
+	return nil
+}
+
+// planChunks returns the persisted chunks of the task, after splitting the
+// blocks of the planned range which no chunk covers into new chunks. Chunks
+// are never removed, so a range narrowed in the config is only reported, and
+// a new chunk size only applies to new chunks.
+func (b *Backfill) planChunks(ctx context.Context) ([]do.BackfillChunk, error) {
+	chunks, err := b.repo.BackfillChunkDao().GetChunks(ctx, b.name)
+	if err != nil {
+		return nil, err
+	}
+	if !b.planned {
+		return chunks, nil
+	}
+
+	var newChunks []do.BackfillChunk
+	if len(chunks) == 0 {
+		newChunks = b.splitChunks(b.fromBlockNumber, b.toBlockNumber)
+	} else {
+		plannedFrom, plannedTo := chunks[0].FromBlockNumber, chunks[len(chunks)-1].ToBlockNumber
+		if b.fromBlockNumber > plannedFrom || b.toBlockNumber < plannedTo {
+			b.lg.Warn(
+				"backfill range narrowed, planned chunks are kept",
+				zap.String("name", b.name),
+				zap.Int64("fromBlockNumber", b.fromBlockNumber),
+				zap.Int64("toBlockNumber", b.toBlockNumber),
+				zap.Int64("plannedFromBlockNumber", plannedFrom),
+				zap.Int64("plannedToBlockNumber", plannedTo),
+			)
+		}
+		newChunks = append(
+			b.splitChunks(b.fromBlockNumber, min(b.toBlockNumber, plannedFrom-1)),
+			b.splitChunks(max(b.fromBlockNumber, plannedTo+1), b.toBlockNumber)...,
+		)
+	}
+	if len(newChunks) == 0 {
+		return chunks, nil
+	}
+
+	b.lg.Info("backfill chunks planned", zap.String("name", b.name), zap.Int("chunks", len(newChunks)))
+	if err := b.repo.BackfillChunkDao().InsertChunks(ctx, newChunks); err != nil {
+		return nil, err
+	}
+
+	return b.repo.BackfillChunkDao().GetChunks(ctx, b.name)
+}
+
+func (b *Backfill) splitChunks(fromBlockNumber int64, toBlockNumber int64) []do.BackfillChunk {
+	chunkSize := b.cfg.BackfillConfig.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = toBlockNumber - fromBlockNumber + 1
+	}
+
+	var chunks []do.BackfillChunk
+	for i := fromBlockNumber; i <= toBlockNumber; i += chunkSize {
+		j := min(i+chunkSize-1, toBlockNumber)
+		chunks = append(chunks, do.BackfillChunk{
+			TaskName:                 b.name,
+			FromBlockNumber:          i,
+			ToBlockNumber:            j,
+			LastProcessedBlockNumber: i - 1,
+		})
+	}
+	return chunks
+}
+
+func (b *Backfill) processChunk(ctx context.Context, chunk do.BackfillChunk) error {
+	b.lg.Debug("processing chunk", zap.String("name", b.name), zap.Any("chunk", chunk))
+
+	queryMaxBlocks := max(b.cfg.QueryMaxBlocks, 1)
+	for chunk.LastProcessedBlockNumber < chunk.ToBlockNumber {
+		i := chunk.LastProcessedBlockNumber + 1
+		j := min(i+queryMaxBlocks-1, chunk.ToBlockNumber)
+
+		var blocks []chain.Block
+		startedAt := time.Now()
+		err := b.retryPolicy.Do(ctx, func() error {
+			var err error
+			blocks, err = queryBlocks(ctx, b.chain, b.cfg, i, j)
+			if err != nil {
+				b.lg.Error(
+					"fail to get blocks",
+					zap.String("name", b.name),
+					zap.Int64("fromBlockNumber", i),
+					zap.Int64("toBlockNumber", j),
+					zap.Stringer("class", retry.Classify(err)),
+					zap.Error(err),
+				)
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		err = b.retryPolicy.Do(ctx, func() error {
+			var err error
+			chunk, err = b.commitBlocks(ctx, chunk, blocks, j, startedAt)
+			return err
+		})
+		if err != nil {
+			b.lg.Error("fail to commit blocks", zap.String("name", b.name), zap.Int64("toBlockNumber", j), zap.Error(err))
+			return err
+		}
+	}
+
+	b.lg.Debug("chunk completed", zap.String("name", b.name), zap.Any("chunk", chunk))
+
+	return nil
+}
+
+func (b *Backfill) commitBlocks(ctx context.Context, chunk do.BackfillChunk, blocks []chain.Block, toBlockNumber int64, startedAt time.Time) (do.BackfillChunk, error) {
+	next := chunk
+	next.LastProcessedBlockNumber = toBlockNumber
+	next.Completed = toBlockNumber == chunk.ToBlockNumber
+
+	logs := 0
+	txErr := b.repo.Transaction(func(repo repository.Repository) error {
+		var err error
+		logs, err = storeBlocks(ctx, repo, b.cfg, b.chain.GetChainId(), blocks)
+		if err != nil {
+			return err
+		}
+		processedRange := newProcessedRange(b.name, b.instanceId, b.cfg, b.chain.GetChainId(), blocks, logs, startedAt)
+		if _, err := repo.ProcessedRangeDao().InsertProcessedRange(ctx, processedRange); err != nil {
+			return err
+		}
+		if next, err = repo.BackfillChunkDao().UpdateChunk(ctx, next); err != nil {
+			return err
+		}
+		// checked last, so that the task is only locked for the commit
+		return b.checkFencingToken(ctx, repo)
+	})
+	if txErr != nil {
+		return chunk, txErr
+	}
+	metrics.LogsInserted.WithLabelValues(b.name).Add(float64(logs))
+	metrics.RangesProcessed.WithLabelValues(b.name).Inc()
+
+	b.lg.Debug(
+		"blocks backfilled",
+		zap.String("name", b.name),
+		zap.Int64("fromBlockNumber", chunk.LastProcessedBlockNumber+1),
+		zap.Int64("toBlockNumber", toBlockNumber),
+		zap.Int("logs", logs),
+	)
+
+	return next, nil
+}
+
+// checkFencingToken takes a shared lock on the fenced task, so that the lease
+// cannot move until the chunk is committed, while the other workers commit
+// theirs.
+func (b *Backfill) checkFencingToken(ctx context.Context, repo repository.Repository) error {
+	if b.fencedTaskName == "" {
+		return nil
+	}
+	task, err := repo.TaskDao().GetTaskForShare(ctx, b.fencedTaskName)
+	if err != nil {
+		return err
+	}
+	if task.FencingToken != b.fencingToken {
+		return repository.ErrStaleFencingToken
+	}
+	return nil
+}
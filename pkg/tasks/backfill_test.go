@@ -0,0 +1,168 @@
+package tasks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+	"github.com/waynewu411/blocktasks/pkg/config"
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"github.com/waynewu411/blocktasks/pkg/repository"
+	"go.uber.org/zap/zaptest"
+)
+
+func newTestBackfillConfig() config.EventMonitorConfig {
+	cfg := newTestMonitorConfig()
+	cfg.BackfillConfig = config.BackfillConfig{
+		Enabled:         true,
+		FromBlockNumber: 1,
+		ToBlockNumber:   25,
+		ChunkSize:       10,
+		Workers:         1,
+	}
+	return cfg
+}
+
+func chunkRanges(chunks []do.BackfillChunk) [][2]int64 {
+	ranges := make([][2]int64, 0, len(chunks))
+	for _, chunk := range chunks {
+		ranges = append(ranges, [2]int64{chunk.FromBlockNumber, chunk.ToBlockNumber})
+	}
+	return ranges
+}
+
+func TestBackfill_PlanChunks(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository()
+	cfg := newTestBackfillConfig()
+	plan := func(fromBlockNumber int64, toBlockNumber int64) [][2]int64 {
+		t.Helper()
+		b := NewBackfill(zaptest.NewLogger(t), "backfill", "", cfg, repo, newFakeChain(0), WithChunkPlanning(fromBlockNumber, toBlockNumber)).(*Backfill)
+		chunks, err := b.planChunks(ctx)
+		require.NoError(t, err)
+		return chunkRanges(chunks)
+	}
+
+	require.Equal(t, [][2]int64{{1, 10}, {11, 20}, {21, 25}}, plan(1, 25))
+
+	// the planned chunks are kept when the range is narrowed
+	require.Equal(t, [][2]int64{{1, 10}, {11, 20}, {21, 25}}, plan(5, 15))
+
+	// a wider range adds chunks on both sides, of the new size
+	cfg.BackfillConfig.ChunkSize = 4
+	require.Equal(t, [][2]int64{{0, 0}, {1, 10}, {11, 20}, {21, 25}, {26, 29}, {30, 30}}, plan(0, 30))
+
+	// the chunks are only processed without planning, e.g. for rescans
+	b := NewBackfill(zaptest.NewLogger(t), "backfill", "", cfg, repo, newFakeChain(0)).(*Backfill)
+	chunks, err := b.planChunks(ctx)
+	require.NoError(t, err)
+	require.Len(t, chunks, 6)
+}
+
+func TestBackfill_Resume(t *testing.T) {
+	ctx := context.Background()
+	chain := newFakeChain(15)
+	repo := repository.NewMemoryRepository()
+	cfg := newTestBackfillConfig()
+	cfg.QueryMaxBlocks = 3
+	newBackfill := func() Task {
+		return NewBackfill(zaptest.NewLogger(t), "backfill", "", cfg, repo, chain, WithChunkPlanning(1, 25))
+	}
+
+	// blocks past 15 are missing, which fails the chunk from 11 at block 14
+	require.Error(t, newBackfill().Start(ctx))
+	chunks, err := repo.BackfillChunkDao().GetChunks(ctx, "backfill")
+	require.NoError(t, err)
+	require.True(t, chunks[0].Completed)
+	require.Equal(t, int64(13), chunks[1].LastProcessedBlockNumber)
+	require.Len(t, storedLogs(t, repo), 13)
+
+	// a restart resumes the unfinished chunks after their checkpoint
+	chain.mine(10)
+	require.NoError(t, newBackfill().Start(ctx))
+	calls := chain.calls()
+	require.Contains(t, calls, [2]int64{14, 16})
+	require.Equal(t, 1, lo.Count(calls, [2]int64{1, 3}))
+	require.Equal(t, 1, lo.Count(calls, [2]int64{11, 13}))
+	chunks, err = repo.BackfillChunkDao().GetChunks(ctx, "backfill")
+	require.NoError(t, err)
+	for _, chunk := range chunks {
+		require.True(t, chunk.Completed)
+	}
+
+	logs := storedLogs(t, repo)
+	require.Len(t, logs, 25)
+	for i, log := range logs {
+		require.Equal(t, int64(i+1), log.BlockNumber)
+	}
+}
+
+func TestBackfill_Fencing(t *testing.T) {
+	ctx := context.Background()
+	chain := newFakeChain(30)
+	repo := repository.NewMemoryRepository()
+	m := newTestLogMonitor(t, newTestBackfillConfig(), repo, chain)
+
+	// another instance took over the task since the token was read
+	backfill := NewBackfill(zaptest.NewLogger(t), "backfill", "", m.cfg, repo, chain, WithChunkPlanning(1, 25), WithFencing(m.name, m.fencingToken-1))
+	require.ErrorIs(t, backfill.Start(ctx), repository.ErrStaleFencingToken)
+	require.Empty(t, storedLogs(t, repo))
+}
+
+func TestLogMonitor_BackfillHandoff(t *testing.T) {
+	ctx := context.Background()
+	chain := newFakeChain(40)
+	repo := repository.NewMemoryRepository()
+
+	// the live checkpoint at block 5 is inside the backfill range
+	_, err := repo.TaskDao().InsertTask(ctx, do.Task{
+		Name:                        TaskBaseLogMonitor,
+		LastProcessedBlockNumber:    5,
+		LastProcessedBlockTimestamp: fakeGenesisTime + 5*fakeBlockTime,
+	})
+	require.NoError(t, err)
+	m := newTestLogMonitor(t, newTestBackfillConfig(), repo, chain)
+
+	// the live monitor continues after the backfill range right away
+	backfill, err := m.backfill(ctx)
+	require.NoError(t, err)
+	requireCheckpoint(t, repo, m, 25)
+	require.NoError(t, m.poll(ctx))
+	requireCheckpoint(t, repo, m, 38)
+	require.Len(t, storedLogs(t, repo), 13)
+
+	// while the backfill fills the blocks behind it
+	require.NoError(t, backfill.Start(ctx))
+	logs := storedLogs(t, repo)
+	require.Len(t, logs, 38)
+	for i, log := range logs {
+		require.Equal(t, int64(i+1), log.BlockNumber)
+	}
+}
+
+func TestLogMonitor_BackfillToCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	chain := newFakeChain(40)
+	repo := repository.NewMemoryRepository()
+	cfg := newTestBackfillConfig()
+	cfg.BackfillConfig.ToBlockNumber = 0
+	cfg.StartBlockNumber = 21
+	m := newTestLogMonitor(t, cfg, repo, chain)
+
+	// without an end block the backfill ends at the live checkpoint
+	_, err := m.backfill(ctx)
+	require.NoError(t, err)
+	chunks, err := repo.BackfillChunkDao().GetChunks(ctx, "base-log-monitor-backfill")
+	require.NoError(t, err)
+	require.Equal(t, [][2]int64{{1, 10}, {11, 20}}, chunkRanges(chunks))
+
+	// and is not extended over the blocks the live monitor processed since
+	require.NoError(t, m.poll(ctx))
+	requireCheckpoint(t, repo, m, 38)
+	_, err = m.backfill(ctx)
+	require.NoError(t, err)
+	chunks, err = repo.BackfillChunkDao().GetChunks(ctx, "base-log-monitor-backfill")
+	require.NoError(t, err)
+	require.Equal(t, [][2]int64{{1, 10}, {11, 20}}, chunkRanges(chunks))
+}
@@ -0,0 +1,46 @@
+package tasks
+
+import (
+	"math"
+	"slices"
+
+	"github.com/samber/lo"
+	"github.com/waynewu411/blocktasks/pkg/config"
+)
+
+// monitoredAddresses returns the monitored addresses of the contracts that
+// exist by toBlockNumber. includeLogs is false when contracts are configured
+// but none is deployed yet, since an empty address filter matches every log.
+func monitoredAddresses(cfg config.EventMonitorConfig, toBlockNumber int64) (addresses []string, includeLogs bool) {
+	if len(cfg.MonitoredContracts) == 0 {
+		return cfg.MonitoredContractAddresses, true
+	}
+
+	addresses = slices.Clone(cfg.MonitoredContractAddresses)
+	for _, contract := range cfg.MonitoredContracts {
+		if contract.DeployBlockNumber <= toBlockNumber {
+			addresses = append(addresses, contract.Address)
+		}
+	}
+
+	return addresses, len(addresses) > 0
+}
+
+// earliestDeployBlockNumber returns the first block at which any monitored
+// contract exists, or 0 when an address is monitored without a deploy block.
+func earliestDeployBlockNumber(cfg config.EventMonitorConfig) int64 {
+	if len(cfg.MonitoredContractAddresses) > 0 || len(cfg.MonitoredContracts) == 0 {
+		return 0
+	}
+
+	return lo.MinBy(cfg.MonitoredContracts, func(a config.ContractConfig, b config.ContractConfig) bool {
+		return a.DeployBlockNumber < b.DeployBlockNumber
+	}).DeployBlockNumber
+}
+
+// IndexedContracts returns the addresses whose logs the task stores, empty
+// when every log is stored, and the first block with logs of any of them.
+func IndexedContracts(cfg config.EventMonitorConfig) (addresses []string, fromBlockNumber int64) {
+	addresses, _ = monitoredAddresses(cfg, math.MaxInt64)
+	return addresses, earliestDeployBlockNumber(cfg)
+}
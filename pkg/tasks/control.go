@@ -0,0 +1,112 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/waynewu411/blocktasks/pkg/chain"
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"github.com/waynewu411/blocktasks/pkg/repository"
+)
+
+// ErrInvalidRange is returned when a rescan is requested for blocks which are
+// not behind the checkpoint.
+var ErrInvalidRange = errors.New("invalid block range")
+
+// The changes below are made on behalf of an admin while the monitor of the
+// task runs. Each increments the revision of the task, which fails the range
+// the monitor is committing, and the monitor takes the change before its next
+// range.
+
+// PauseTask stops the monitor of the task from processing blocks.
+func PauseTask(ctx context.Context, repo repository.Repository, name string) (do.Task, error) {
+	return reviseTask(ctx, repo, name, func(task *do.Task) {
+		task.Paused = true
+	})
+}
+
+// ResumeTask lets the monitor of the task process blocks again, from its
+// checkpoint.
+func ResumeTask(ctx context.Context, repo repository.Repository, name string) (do.Task, error) {
+	return reviseTask(ctx, repo, name, func(task *do.Task) {
+		task.Paused = false
+	})
+}
+
+// MoveCheckpoint rewinds or fast-forwards the checkpoint of the task to the
+// block, so that its monitor continues with the next block.
+func MoveCheckpoint(ctx context.Context, repo repository.Repository, name string, block chain.Block) (do.Task, error) {
+	return reviseTask(ctx, repo, name, func(task *do.Task) {
+		task.LastProcessedBlockNumber = block.BlockNumber
+		task.LastProcessedBlockTimestamp = block.Timestamp
+	})
+}
+
+// RescanBlocks has the monitor of the task fetch and store the blocks again
+// before its next range. Unlike a rewind, the checkpoint is kept, so only
+// these blocks are processed again.
+func RescanBlocks(ctx context.Context, repo repository.Repository, name string, fromBlockNumber int64, toBlockNumber int64) (do.BackfillChunk, error) {
+	task, err := repo.TaskDao().GetTask(ctx, name)
+	if err != nil {
+		return do.BackfillChunk{}, err
+	}
+	if fromBlockNumber < 0 || fromBlockNumber > toBlockNumber || toBlockNumber > task.LastProcessedBlockNumber {
+		return do.BackfillChunk{}, fmt.Errorf("%w: blocks %d to %d, checkpoint %d", ErrInvalidRange, fromBlockNumber, toBlockNumber, task.LastProcessedBlockNumber)
+	}
+
+	// a chunk rescanned before is reset
+	return repo.BackfillChunkDao().UpdateChunk(ctx, do.BackfillChunk{
+		TaskName:                 rescanTaskName(name),
+		FromBlockNumber:          fromBlockNumber,
+		ToBlockNumber:            toBlockNumber,
+		LastProcessedBlockNumber: fromBlockNumber - 1,
+	})
+}
+
+// GetRescans returns the ranges requested with RescanBlocks.
+func GetRescans(ctx context.Context, repo repository.Repository, name string) ([]do.BackfillChunk, error) {
+	return repo.BackfillChunkDao().GetChunks(ctx, rescanTaskName(name))
+}
+
+// GetChunks returns the backfill and rescan chunks of the task.
+func GetChunks(ctx context.Context, repo repository.Repository, name string) ([]do.BackfillChunk, error) {
+	var chunks []do.BackfillChunk
+	for _, chunkTaskName := range []string{backfillTaskName(name), rescanTaskName(name)} {
+		taskChunks, err := repo.BackfillChunkDao().GetChunks(ctx, chunkTaskName)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, taskChunks...)
+	}
+	return chunks, nil
+}
+
+// ProcessingTaskNames returns the names under which the ranges processed for
+// the task are recorded: its own, and those of its backfills and rescans.
+func ProcessingTaskNames(name string) []string {
+	return []string{name, backfillTaskName(name), rescanTaskName(name)}
+}
+
+func rescanTaskName(name string) string {
+	return fmt.Sprintf("%s-rescan", name)
+}
+
+func backfillTaskName(name string) string {
+	return fmt.Sprintf("%s-backfill", name)
+}
+
+func reviseTask(ctx context.Context, repo repository.Repository, name string, fn func(task *do.Task)) (do.Task, error) {
+	var task do.Task
+	err := repo.Transaction(func(repo repository.Repository) error {
+		var err error
+		task, err = repo.TaskDao().GetTaskForUpdate(ctx, name)
+		if err != nil {
+			return err
+		}
+		fn(&task)
+		task, err = repo.TaskDao().ReviseTask(ctx, task)
+		return err
+	})
+	return task, err
+}
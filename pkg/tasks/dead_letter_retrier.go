@@ -0,0 +1,116 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/waynewu411/blocktasks/pkg/chain"
+	"github.com/waynewu411/blocktasks/pkg/config"
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"github.com/waynewu411/blocktasks/pkg/repository"
+	"github.com/waynewu411/blocktasks/pkg/retry"
+	"go.uber.org/zap"
+)
+
+// DeadLetterRetrier processes the open dead letters of a task once, marking
+// each range resolved when its logs are stored. The task checkpoint is left
+// untouched.
+type DeadLetterRetrier struct {
+	baseTask
+	instanceId  string
+	cfg         config.EventMonitorConfig
+	repo        repository.Repository
+	chain       chain.Chain
+	retryPolicy retry.Policy
+}
+
+func NewDeadLetterRetrier(lg *zap.Logger, name string, instanceId string, cfg config.EventMonitorConfig, repo repository.Repository, chain chain.Chain) Task {
+	return &DeadLetterRetrier{
+		baseTask: baseTask{
+			lg:   lg,
+			name: name,
+		},
+		instanceId:  instanceId,
+		cfg:         cfg,
+		repo:        repo,
+		chain:       chain,
+		retryPolicy: retry.NewPolicy(cfg.RetryConfig, cfg.MaxBlockRetries),
+	}
+}
+
+func (r *DeadLetterRetrier) Start(ctx context.Context) error {
+	deadLetters, err := r.repo.DeadLetterDao().GetDeadLetters(ctx, r.name, do.DeadLetterStatusOpen)
+	if err != nil {
+		r.lg.Error("fail to get dead letters", zap.String("name", r.name), zap.Error(err))
+		return err
+	}
+
+	r.lg.Info("retrying dead letters", zap.String("name", r.name), zap.Int("deadLetters", len(deadLetters)))
+
+	failed := 0
+	for _, deadLetter := range deadLetters {
+		if err := r.retryDeadLetter(ctx, deadLetter); err != nil {
+			failed++
+			r.lg.Error("dead letter still failing", zap.String("name", r.name), zap.Any("deadLetter", deadLetter), zap.Error(err))
+			continue
+		}
+		r.lg.Info("dead letter resolved", zap.String("name", r.name), zap.Any("deadLetter", deadLetter))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d dead letters still failing", failed, len(deadLetters))
+	}
+
+	return nil
+}
+
+func (r *DeadLetterRetrier) retryDeadLetter(ctx context.Context, deadLetter do.DeadLetter) error {
+	attempts := int64(0)
+	err := r.retryPolicy.Do(ctx, func() error {
+		attempts++
+		startedAt := time.Now()
+		addresses, includeLogs := monitoredAddresses(r.cfg, deadLetter.ToBlockNumber)
+		blocks, err := r.chain.GetBlocks(
+			ctx,
+			deadLetter.FromBlockNumber,
+			deadLetter.ToBlockNumber,
+			r.cfg.StoreTransactions,
+			includeLogs,
+			addresses,
+			[]string{},
+		)
+		if err != nil {
+			return err
+		}
+
+		return r.repo.Transaction(func(repo repository.Repository) error {
+			logs, err := storeBlocks(ctx, repo, r.cfg, r.chain.GetChainId(), blocks)
+			if err != nil {
+				return err
+			}
+			if len(blocks) > 0 {
+				processedRange := newProcessedRange(r.name, r.instanceId, r.cfg, r.chain.GetChainId(), blocks, logs, startedAt)
+				if _, err := repo.ProcessedRangeDao().InsertProcessedRange(ctx, processedRange); err != nil {
+					return err
+				}
+			}
+			resolved := deadLetter
+			resolved.Status = do.DeadLetterStatusResolved
+			_, err = repo.DeadLetterDao().UpdateDeadLetter(ctx, resolved)
+			return err
+		})
+	})
+	if err == nil {
+		return nil
+	}
+
+	deadLetter.Error = err.Error()
+	deadLetter.Attempts += attempts
+	deadLetter.LastFailedAt = time.Now()
+	if _, updateErr := r.repo.DeadLetterDao().UpdateDeadLetter(ctx, deadLetter); updateErr != nil {
+		r.lg.Error("fail to update dead letter", zap.String("name", r.name), zap.Any("deadLetter", deadLetter), zap.Error(updateErr))
+	}
+
+	return err
+}
@@ -0,0 +1,240 @@
+package tasks
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/waynewu411/blocktasks/pkg/chain"
+)
+
+const (
+	fakeChainId       int64 = 31337
+	fakeGenesisTime   int64 = 1700000000000 // in milliseconds
+	fakeBlockTime     int64 = 2000          // in milliseconds
+	fakeContract            = "0x00000000000000000000000000000000000000aa"
+	fakeTransferTopic       = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+	fakeOtherContract       = "0x00000000000000000000000000000000000000bb"
+)
+
+// fakeChain is a scripted chain.Chain. Every block holds one transaction
+// emitting a log from each of two contracts. Blocks are mined on demand, the
+// safe block is moved explicitly, and a reorg re-mines blocks on a new fork
+// with different hashes.
+type fakeChain struct {
+	mu     sync.Mutex
+	blocks []chain.Block // canonical blocks by number, starting at 0
+	safe   int64
+	fork   int64
+	// getBlocksErrs are returned by the next calls of GetBlocks
+	getBlocksErrs []error
+	// getBlocksCalls records the ranges of the calls of GetBlocks
+	getBlocksCalls [][2]int64
+	// onGetBlocks, when set, is called at the start of GetBlocks, outside
+	// the lock, e.g. to hold a query back
+	onGetBlocks func(fromBlockNumber int64, toBlockNumber int64)
+}
+
+func newFakeChain(head int64) *fakeChain {
+	c := &fakeChain{}
+	c.mine(head + 1)
+	c.safe = head
+	return c
+}
+
+// mine appends count blocks to the current fork.
+func (c *fakeChain) mine(count int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for range count {
+		c.blocks = append(c.blocks, c.newBlock(int64(len(c.blocks))))
+	}
+}
+
+// reorg replaces the blocks from blockNumber on with blocks of a new fork.
+func (c *fakeChain) reorg(blockNumber int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.fork++
+	for i := blockNumber; i < int64(len(c.blocks)); i++ {
+		c.blocks[i] = c.newBlock(i)
+	}
+}
+
+func (c *fakeChain) setSafe(blockNumber int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.safe = blockNumber
+}
+
+// failGetBlocks makes the next calls of GetBlocks fail with the errors.
+func (c *fakeChain) failGetBlocks(errs ...error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.getBlocksErrs = append(c.getBlocksErrs, errs...)
+}
+
+// calls returns the ranges queried by GetBlocks in block order, since
+// concurrent queries are made in any order.
+func (c *fakeChain) calls() [][2]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	calls := slices.Clone(c.getBlocksCalls)
+	slices.SortFunc(calls, func(a, b [2]int64) int {
+		return cmp.Or(cmp.Compare(a[0], b[0]), cmp.Compare(a[1], b[1]))
+	})
+	return calls
+}
+
+func (c *fakeChain) newBlock(blockNumber int64) chain.Block {
+	blockHash := fakeHash(c.fork, blockNumber)
+	parentHash := ""
+	if blockNumber > 0 {
+		parentHash = c.blocks[blockNumber-1].BlockHash
+	}
+	txnHash := fakeHash(c.fork+0x80, blockNumber)
+	block := chain.Block{
+		ChainId:     fakeChainId,
+		BlockNumber: blockNumber,
+		BlockHash:   blockHash,
+		ParentHash:  parentHash,
+		Timestamp:   fakeGenesisTime + blockNumber*fakeBlockTime,
+		TxnHashes:   []string{txnHash},
+		Txns: []chain.Txn{{
+			BlockHash:   blockHash,
+			BlockNumber: fmt.Sprintf("0x%x", blockNumber),
+			TxnHash:     txnHash,
+			Type:        "0x2",
+			Nonce:       fmt.Sprintf("0x%x", blockNumber),
+			From:        fakeOtherContract,
+			To:          fakeContract,
+			Value:       "0x0",
+			Gas:         "0x5208",
+			GasPrice:    "0x1",
+			Input:       "0x",
+		}},
+	}
+	for i, address := range []string{fakeContract, fakeOtherContract} {
+		block.Logs = append(block.Logs, chain.Log{
+			Address:     address,
+			BlockNumber: blockNumber,
+			BlockHash:   blockHash,
+			Data:        fmt.Sprintf("0x%064x", c.fork),
+			Topics:      []string{fakeTransferTopic},
+			TxnHash:     txnHash,
+			LogIndex:    int64(i),
+		})
+	}
+	return block
+}
+
+func fakeHash(fork int64, blockNumber int64) string {
+	return fmt.Sprintf("0x%02x%062x", fork, blockNumber)
+}
+
+func (c *fakeChain) GetChainId() int64 {
+	return fakeChainId
+}
+
+func (c *fakeChain) GetBlockByNumber(ctx context.Context, blockNumber int64, fullTxns bool) (chain.Block, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch blockNumber {
+	case chain.BlockNumberSafe, chain.BlockNumberFinalized:
+		blockNumber = c.safe
+	case chain.BlockNumberLatest:
+		blockNumber = int64(len(c.blocks)) - 1
+	}
+	if blockNumber < 0 || blockNumber >= int64(len(c.blocks)) {
+		return chain.Block{}, chain.ErrBlockNotFound
+	}
+	return withoutDetails(c.blocks[blockNumber], fullTxns, false, nil), nil
+}
+
+func (c *fakeChain) GetBlockByTimestamp(ctx context.Context, timestamp int64) (chain.Block, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, block := range c.blocks {
+		if block.Timestamp >= timestamp {
+			return withoutDetails(block, false, false, nil), nil
+		}
+	}
+	return chain.Block{}, chain.ErrBlockNotFound
+}
+
+func (c *fakeChain) GetBlocks(ctx context.Context, fromBlockNumber int64, toBlockNumber int64, fullTxns bool, includeLogs bool, addresses []string, topics []string) ([]chain.Block, error) {
+	if c.onGetBlocks != nil {
+		c.onGetBlocks(fromBlockNumber, toBlockNumber)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.getBlocksCalls = append(c.getBlocksCalls, [2]int64{fromBlockNumber, toBlockNumber})
+	if len(c.getBlocksErrs) > 0 {
+		err := c.getBlocksErrs[0]
+		c.getBlocksErrs = c.getBlocksErrs[1:]
+		return nil, err
+	}
+
+	var blocks []chain.Block
+	for i := fromBlockNumber; i <= toBlockNumber && i < int64(len(c.blocks)); i++ {
+		blocks = append(blocks, withoutDetails(c.blocks[i], fullTxns, includeLogs, addresses))
+	}
+	return blocks, nil
+}
+
+func (c *fakeChain) GetTransactionReceipts(ctx context.Context, txnHashes []string) (map[string]chain.Receipt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	receipts := map[string]chain.Receipt{}
+	for _, block := range c.blocks {
+		for _, txn := range block.Txns {
+			if slices.Contains(txnHashes, txn.TxnHash) {
+				receipts[txn.TxnHash] = chain.Receipt{
+					TxnHash:     txn.TxnHash,
+					TxnIndex:    txn.TxnIndex,
+					BlockNumber: block.BlockNumber,
+					BlockHash:   block.BlockHash,
+					Logs:        block.Logs,
+				}
+			}
+		}
+	}
+	return receipts, nil
+}
+
+func (c *fakeChain) Call(ctx context.Context, method string, params []any) (json.RawMessage, error) {
+	return nil, fmt.Errorf("fake chain does not serve %s", method)
+}
+
+// withoutDetails returns the block as the RPC would, with the transactions
+// only when fullTxns is set, and the logs of the addresses, if any, only
+// when includeLogs is set.
+func withoutDetails(block chain.Block, fullTxns bool, includeLogs bool, addresses []string) chain.Block {
+	if !fullTxns {
+		block.Txns = nil
+	}
+	logs := block.Logs
+	block.Logs = nil
+	if includeLogs {
+		for _, log := range logs {
+			if len(addresses) == 0 || slices.ContainsFunc(addresses, func(address string) bool {
+				return strings.EqualFold(address, log.Address)
+			}) {
+				block.Logs = append(block.Logs, log)
+			}
+		}
+	}
+	return block
+}
@@ -0,0 +1,148 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/waynewu411/blocktasks/pkg/config"
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"github.com/waynewu411/blocktasks/pkg/repository"
+	"go.uber.org/zap"
+)
+
+// ErrLeaseLost is the cause of the context cancellation when another
+// instance takes over the task.
+var ErrLeaseLost = errors.New("lease lost")
+
+// lease lets a single instance own a task. The owner renews the lease with
+// heartbeats and a standby takes over once the lease expires. Every takeover
+// increments the fencing token checked by TaskDao.UpdateTask, so that a
+// stale owner can no longer move the checkpoint.
+type lease struct {
+	lg         *zap.Logger
+	name       string
+	instanceId string
+	cfg        config.LeaseConfig
+	repo       repository.Repository
+}
+
+func newLease(lg *zap.Logger, name string, instanceId string, cfg config.LeaseConfig, repo repository.Repository) *lease {
+	return &lease{lg: lg, name: name, instanceId: instanceId, cfg: cfg, repo: repo}
+}
+
+func (l *lease) duration() time.Duration {
+	return time.Duration(l.cfg.LeaseDuration) * time.Second
+}
+
+func (l *lease) heartbeatInterval() time.Duration {
+	return time.Duration(max(l.cfg.HeartbeatInterval, 1)) * time.Second
+}
+
+// tryAcquire takes the lease when it is free, expired or was held by this
+// instance before a restart, and returns the task with the current owner.
+func (l *lease) tryAcquire(ctx context.Context) (do.Task, error) {
+	return l.update(ctx, func(task *do.Task, now time.Time) bool {
+		if task.Owner != l.instanceId && task.Owner != "" && now.Before(task.LeaseExpiresAt) {
+			return false
+		}
+		task.Owner = l.instanceId
+		task.FencingToken++
+		task.LeaseExpiresAt = now.Add(l.duration())
+		return true
+	})
+}
+
+// renew extends the lease while this instance still holds the fencing token,
+// and returns the task with the current owner.
+func (l *lease) renew(ctx context.Context, fencingToken int64) (do.Task, error) {
+	return l.update(ctx, func(task *do.Task, now time.Time) bool {
+		if task.Owner != l.instanceId || task.FencingToken != fencingToken {
+			return false
+		}
+		task.LeaseExpiresAt = now.Add(l.duration())
+		return true
+	})
+}
+
+// update locks the task row and writes the lease when fn changed it. The
+// expiry is computed and compared with the time of the database, since the
+// clocks of the instances may differ.
+func (l *lease) update(ctx context.Context, fn func(task *do.Task, now time.Time) bool) (do.Task, error) {
+	var task do.Task
+	err := l.repo.Transaction(func(repo repository.Repository) error {
+		var err error
+		task, err = repo.TaskDao().GetTaskForUpdate(ctx, l.name)
+		if err != nil {
+			return err
+		}
+		now, err := repo.TaskDao().Now(ctx)
+		if err != nil {
+			return err
+		}
+		if !fn(&task, now) {
+			return nil
+		}
+		task, err = repo.TaskDao().UpdateLease(ctx, task)
+		return err
+	})
+	return task, err
+}
+
+// acquire blocks as a standby until the lease is taken.
+func (l *lease) acquire(ctx context.Context) (do.Task, error) {
+	ticker := time.NewTicker(l.heartbeatInterval())
+	defer ticker.Stop()
+
+	for {
+		task, err := l.tryAcquire(ctx)
+		if err != nil {
+			l.lg.Error("fail to acquire lease", zap.String("name", l.name), zap.Error(err))
+		} else if task.Owner == l.instanceId {
+			l.lg.Info("lease acquired", zap.String("name", l.name), zap.Any("task", task))
+			return task, nil
+		} else {
+			l.lg.Debug("standing by", zap.String("name", l.name), zap.String("owner", task.Owner))
+		}
+
+		select {
+		case <-ctx.Done():
+			return do.Task{}, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// keepAlive renews the lease until ctx is done. It cancels ctx with
+// ErrLeaseLost when another instance took over, or when the lease could not
+// be renewed before it expired.
+func (l *lease) keepAlive(ctx context.Context, cancel context.CancelCauseFunc, fencingToken int64) {
+	ticker := time.NewTicker(l.heartbeatInterval())
+	defer ticker.Stop()
+
+	renewedAt := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		task, err := l.renew(ctx, fencingToken)
+		switch {
+		case err == nil && task.FencingToken == fencingToken:
+			renewedAt = time.Now()
+			continue
+		case err == nil:
+			l.lg.Warn("lease taken over", zap.String("name", l.name), zap.String("owner", task.Owner))
+		case time.Since(renewedAt) < l.duration():
+			l.lg.Error("fail to renew lease", zap.String("name", l.name), zap.Error(err))
+			continue
+		default:
+			l.lg.Error("lease expired without renewal", zap.String("name", l.name), zap.Error(err))
+		}
+
+		cancel(ErrLeaseLost)
+		return
+	}
+}
@@ -0,0 +1,134 @@
+package tasks
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/waynewu411/blocktasks/pkg/config"
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"github.com/waynewu411/blocktasks/pkg/repository"
+	"go.uber.org/zap/zaptest"
+)
+
+func newTestLeaseConfig() config.LeaseConfig {
+	return config.LeaseConfig{
+		Enabled:           true,
+		LeaseDuration:     60,
+		HeartbeatInterval: 1,
+	}
+}
+
+// testLeaseRepositories returns the repositories the lease is tested with,
+// the memory one and a SQLite one, which reads the time of the database.
+func testLeaseRepositories(t *testing.T) map[string]repository.Repository {
+	return map[string]repository.Repository{
+		"memory": repository.NewMemoryRepository(),
+		"sqlite": repository.NewSqliteRepository(zaptest.NewLogger(t), config.SqliteConfig{
+			Path:        filepath.Join(t.TempDir(), "blocktasks.db"),
+			AutoMigrate: true,
+		}),
+	}
+}
+
+// expireLease moves the expiry of the lease into the past, as if its owner
+// stopped renewing it a lease duration ago.
+func expireLease(t *testing.T, repo repository.Repository) {
+	t.Helper()
+
+	ctx := context.Background()
+	task, err := repo.TaskDao().GetTask(ctx, TaskBaseLogMonitor)
+	require.NoError(t, err)
+	now, err := repo.TaskDao().Now(ctx)
+	require.NoError(t, err)
+	task.LeaseExpiresAt = now.Add(-time.Second)
+	_, err = repo.TaskDao().UpdateLease(ctx, task)
+	require.NoError(t, err)
+}
+
+func TestLease_Takeover(t *testing.T) {
+	for name, repo := range testLeaseRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, err := repo.TaskDao().InsertTask(ctx, do.Task{Name: TaskBaseLogMonitor})
+			require.NoError(t, err)
+			a := newLease(zaptest.NewLogger(t), TaskBaseLogMonitor, "a", newTestLeaseConfig(), repo)
+			b := newLease(zaptest.NewLogger(t), TaskBaseLogMonitor, "b", newTestLeaseConfig(), repo)
+
+			task, err := a.tryAcquire(ctx)
+			require.NoError(t, err)
+			require.Equal(t, "a", task.Owner)
+			require.Equal(t, int64(1), task.FencingToken)
+
+			// a standby waits while the lease is held
+			task, err = b.tryAcquire(ctx)
+			require.NoError(t, err)
+			require.Equal(t, "a", task.Owner)
+
+			// the owner extends the lease
+			expireLease(t, repo)
+			task, err = a.renew(ctx, 1)
+			require.NoError(t, err)
+			now, err := repo.TaskDao().Now(ctx)
+			require.NoError(t, err)
+			require.True(t, task.LeaseExpiresAt.After(now))
+			task, err = b.tryAcquire(ctx)
+			require.NoError(t, err)
+			require.Equal(t, "a", task.Owner)
+
+			// and a standby takes over once it expires
+			expireLease(t, repo)
+			task, err = b.tryAcquire(ctx)
+			require.NoError(t, err)
+			require.Equal(t, "b", task.Owner)
+			require.Equal(t, int64(2), task.FencingToken)
+
+			// the previous owner can neither renew the lease nor move the
+			// checkpoint
+			task, err = a.renew(ctx, 1)
+			require.NoError(t, err)
+			require.Equal(t, "b", task.Owner)
+			_, err = repo.TaskDao().UpdateTask(ctx, do.Task{Name: TaskBaseLogMonitor, LastProcessedBlockNumber: 10, FencingToken: 1})
+			require.ErrorIs(t, err, repository.ErrStaleFencingToken)
+			_, err = repo.TaskDao().UpdateTask(ctx, do.Task{Name: TaskBaseLogMonitor, LastProcessedBlockNumber: 10, FencingToken: 2})
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestLease_KeepAlive(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	ctx := context.Background()
+	_, err := repo.TaskDao().InsertTask(ctx, do.Task{Name: TaskBaseLogMonitor})
+	require.NoError(t, err)
+	a := newLease(zaptest.NewLogger(t), TaskBaseLogMonitor, "a", newTestLeaseConfig(), repo)
+
+	task, err := a.acquire(ctx)
+	require.NoError(t, err)
+	leaseCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go a.keepAlive(leaseCtx, cancel, task.FencingToken)
+
+	// the heartbeats renew the lease
+	expireLease(t, repo)
+	require.Eventually(t, func() bool {
+		task, err := repo.TaskDao().GetTask(ctx, TaskBaseLogMonitor)
+		return err == nil && task.LeaseExpiresAt.After(time.Now())
+	}, 3*time.Second, 10*time.Millisecond)
+	require.NoError(t, leaseCtx.Err())
+
+	// and the owner stops with ErrLeaseLost once a standby took over, here
+	// without waiting for the lease to expire
+	task.Owner = "b"
+	task.FencingToken++
+	_, err = repo.TaskDao().UpdateLease(ctx, task)
+	require.NoError(t, err)
+	select {
+	case <-leaseCtx.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("lease not lost")
+	}
+	require.ErrorIs(t, context.Cause(leaseCtx), ErrLeaseLost)
+}
@@ -0,0 +1,133 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/samber/lo"
+	"github.com/waynewu411/blocktasks/pkg/chain"
+	"github.com/waynewu411/blocktasks/pkg/config"
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"github.com/waynewu411/blocktasks/pkg/repository"
+	"github.com/waynewu411/blocktasks/pkg/retry"
+	"go.uber.org/zap"
+)
+
+// logMetadataBatchSize is the number of logs backfilled in each round.
+const logMetadataBatchSize = 500
+
+// ErrLogMetadataNotFound is returned when the receipts of the chain miss some
+// logs. Those logs are left without an address, so that the next run retries
+// them.
+var ErrLogMetadataNotFound = errors.New("log metadata not found")
+
+// LogMetadataBackfill fills the address and the transaction index of the
+// logs stored before they were recorded, re-fetching the receipts of their
+// transactions. It exits once every log of the chain has been tried.
+type LogMetadataBackfill struct {
+	baseTask
+	repo        repository.Repository
+	chain       chain.Chain
+	retryPolicy retry.Policy
+}
+
+func NewLogMetadataBackfill(lg *zap.Logger, name string, cfg config.EventMonitorConfig, repo repository.Repository, chain chain.Chain) Task {
+	return &LogMetadataBackfill{
+		baseTask: baseTask{
+			lg:   lg,
+			name: name,
+		},
+		repo:        repo,
+		chain:       chain,
+		retryPolicy: retry.NewPolicy(cfg.RetryConfig, cfg.MaxBlockRetries),
+	}
+}
+
+func (b *LogMetadataBackfill) Start(ctx context.Context) error {
+	total, missing := 0, 0
+	var after *do.Log
+	for {
+		logs, err := b.repo.LogDao().GetLogsWithoutAddress(ctx, b.chain.GetChainId(), after, logMetadataBatchSize)
+		if err != nil {
+			b.lg.Error("fail to get logs without address", zap.String("name", b.name), zap.Error(err))
+			return err
+		}
+		if len(logs) == 0 {
+			break
+		}
+		after = &logs[len(logs)-1]
+
+		n, err := b.backfillLogs(ctx, logs)
+		if err != nil {
+			b.lg.Error("fail to backfill log metadata", zap.String("name", b.name), zap.Error(err))
+			return err
+		}
+
+		total += n
+		missing += len(logs) - n
+		b.lg.Info(
+			"log metadata backfilled",
+			zap.String("name", b.name),
+			zap.Int64("blockNumber", after.BlockNumber),
+			zap.Int("logs", total),
+			zap.Int("missingLogs", missing),
+		)
+	}
+
+	if missing > 0 {
+		b.lg.Error("log metadata backfill incomplete", zap.String("name", b.name), zap.Int("logs", total), zap.Int("missingLogs", missing))
+		return fmt.Errorf("%w: %d logs", ErrLogMetadataNotFound, missing)
+	}
+
+	b.lg.Info("log metadata backfill completed", zap.String("name", b.name), zap.Int("logs", total))
+
+	return nil
+}
+
+// backfillLogs fills the logs found in the receipts of their transactions and
+// returns how many were filled.
+func (b *LogMetadataBackfill) backfillLogs(ctx context.Context, logs []do.Log) (int, error) {
+	txnHashes := lo.Uniq(lo.Map(logs, func(log do.Log, _ int) string {
+		return log.TxnHash
+	}))
+
+	var receipts map[string]chain.Receipt
+	err := b.retryPolicy.Do(ctx, func() error {
+		var err error
+		receipts, err = b.chain.GetTransactionReceipts(ctx, txnHashes)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var filled []do.Log
+	for _, log := range logs {
+		receipt, ok := receipts[log.TxnHash]
+		if !ok {
+			b.lg.Warn("receipt not found", zap.String("name", b.name), zap.Any("log", log))
+			continue
+		}
+		receiptLog, ok := lo.Find(receipt.Logs, func(receiptLog chain.Log) bool {
+			return receiptLog.LogIndex == log.LogIndex
+		})
+		if !ok || receiptLog.Address == "" {
+			b.lg.Warn("log not found in receipt", zap.String("name", b.name), zap.Any("log", log))
+			continue
+		}
+		log.TxnIndex = receipt.TxnIndex
+		log.Address = receiptLog.Address
+		filled = append(filled, log)
+	}
+
+	err = b.retryPolicy.Do(ctx, func() error {
+		return b.repo.Transaction(func(repo repository.Repository) error {
+			return repo.LogDao().UpdateLogMetadata(ctx, filled)
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(filled), nil
+}
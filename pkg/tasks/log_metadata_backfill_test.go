@@ -0,0 +1,49 @@
+package tasks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"github.com/waynewu411/blocktasks/pkg/repository"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestLogMetadataBackfill(t *testing.T) {
+	ctx := context.Background()
+	chain := newFakeChain(3)
+	repo := repository.NewMemoryRepository()
+
+	// logs stored before their address was recorded, one of a transaction
+	// the chain does not know
+	var logs []do.Log
+	for blockNumber := int64(1); blockNumber <= 3; blockNumber++ {
+		logs = append(logs, do.Log{
+			ChainId:     fakeChainId,
+			BlockNumber: blockNumber,
+			BlockHash:   fakeHash(0, blockNumber),
+			Data:        "0x",
+			Topics:      []string{fakeTransferTopic},
+			TxnHash:     fakeHash(0x80, blockNumber),
+			LogIndex:    0,
+		})
+	}
+	logs[1].TxnHash = fakeHash(0x90, 2)
+	require.NoError(t, repo.LogDao().InsertLogs(ctx, logs))
+
+	// the missing log is reported and left for the next run
+	backfill := NewLogMetadataBackfill(zaptest.NewLogger(t), TaskBaseLogMonitor, newTestMonitorConfig(), repo, chain)
+	require.ErrorIs(t, backfill.Start(ctx), ErrLogMetadataNotFound)
+	missing, err := repo.LogDao().GetLogsWithoutAddress(ctx, fakeChainId, nil, 10)
+	require.NoError(t, err)
+	require.Len(t, missing, 1)
+	require.Equal(t, int64(2), missing[0].BlockNumber)
+	count, err := repo.LogDao().CountLogsWithoutAddress(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+
+	stored := storedLogs(t, repo)
+	require.Len(t, stored, 3)
+	require.Equal(t, []string{fakeContract, "", fakeContract}, []string{stored[0].Address, stored[1].Address, stored[2].Address})
+}
@@ -2,35 +2,82 @@ package tasks
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/samber/lo"
 	"github.com/waynewu411/blocktasks/pkg/chain"
 	"github.com/waynewu411/blocktasks/pkg/config"
 	"github.com/waynewu411/blocktasks/pkg/do"
+	"github.com/waynewu411/blocktasks/pkg/metrics"
 	"github.com/waynewu411/blocktasks/pkg/repository"
+	"github.com/waynewu411/blocktasks/pkg/retry"
 	"go.uber.org/zap"
 )
 
+// ErrDeadLettered is returned when a range exhausts its retries under the
+// halt failure policy.
+var ErrDeadLettered = errors.New("blocks dead-lettered")
+
 type LogMonitor struct {
 	baseTask
 	cfg                      config.EventMonitorConfig
 	repo                     repository.Repository
 	chain                    chain.Chain
+	retryPolicy              retry.Policy
+	lease                    *lease
+	instanceId               string
+	onCommit                 func(repository.LogNotification)
+	fencingToken             int64
+	revision                 int64
 	lastProcessedBlockNumber int64
 	lastProcessedTimestamp   int64
+	latestBlock              chain.Block // latest safe block seen by the last poll
+}
+
+type LogMonitorOption func(*LogMonitor)
+
+// WithLease makes the instances running the monitor compete for a lease, so
+// that only one of them processes blocks while the others stand by.
+func WithLease(instanceId string, cfg config.LeaseConfig) LogMonitorOption {
+	return func(m *LogMonitor) {
+		m.lease = newLease(m.lg, m.name, instanceId, cfg, m.repo)
+	}
+}
+
+// WithInstanceId records the instance in the processed ranges of the monitor.
+func WithInstanceId(instanceId string) LogMonitorOption {
+	return func(m *LogMonitor) {
+		m.instanceId = instanceId
+	}
 }
 
-func NewLogMonitor(lg *zap.Logger, name string, cfg config.EventMonitorConfig, repo repository.Repository, chain chain.Chain) Task {
-	return &LogMonitor{
+// WithCommitListener calls fn after each range is committed, in the order
+// of the ranges.
+func WithCommitListener(fn func(repository.LogNotification)) LogMonitorOption {
+	return func(m *LogMonitor) {
+		m.onCommit = fn
+	}
+}
+
+func NewLogMonitor(lg *zap.Logger, name string, cfg config.EventMonitorConfig, repo repository.Repository, chain chain.Chain, opts ...LogMonitorOption) Task {
+	m := &LogMonitor{
 		baseTask: baseTask{
 			lg:   lg,
 			name: name,
 		},
-		cfg:   cfg,
-		repo:  repo,
-		chain: chain,
+		cfg:         cfg,
+		repo:        repo,
+		chain:       chain,
+		retryPolicy: retry.NewPolicy(cfg.RetryConfig, cfg.MaxBlockRetries),
+	}
+	for _, opt := range opts {
+		opt(m)
 	}
+	return m
 }
 
 func (m *LogMonitor) Start(ctx context.Context) error {
@@ -40,7 +87,66 @@ func (m *LogMonitor) Start(ctx context.Context) error {
 		return err
 	}
 
-	err = m.run(ctx)
+	if m.lease == nil {
+		return m.process(ctx)
+	}
+
+	for {
+		task, err := m.lease.acquire(ctx)
+		if err != nil {
+			return err
+		}
+
+		// the previous owner may have moved the checkpoint
+		m.load(task)
+
+		leaseCtx, cancel := context.WithCancelCause(ctx)
+		go m.lease.keepAlive(leaseCtx, cancel, task.FencingToken)
+		err = m.process(leaseCtx)
+		cancel(nil)
+
+		leaseLost := errors.Is(context.Cause(leaseCtx), ErrLeaseLost) || errors.Is(err, repository.ErrStaleFencingToken)
+		if ctx.Err() != nil || !leaseLost {
+			return err
+		}
+		m.lg.Warn("lease lost, standing by", zap.String("name", m.name))
+	}
+}
+
+// process runs the live monitor, with the backfill in the background when
+// enabled. A backfill which fails is resumed by the next start, except when
+// the lease is lost, which stops the monitor too.
+func (m *LogMonitor) process(ctx context.Context) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var wg sync.WaitGroup
+	if m.cfg.BackfillConfig.Enabled {
+		backfill, err := m.backfill(ctx)
+		if err != nil {
+			m.lg.Error("fail to backfill", zap.String("name", m.name), zap.Error(err))
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := backfill.Start(ctx)
+			if err == nil || ctx.Err() != nil {
+				return
+			}
+			m.lg.Error("fail to backfill", zap.String("name", m.name), zap.Error(err))
+			if errors.Is(err, repository.ErrStaleFencingToken) {
+				cancel(err)
+			}
+		}()
+	}
+
+	err := m.run(ctx)
+	if cause := context.Cause(ctx); errors.Is(cause, repository.ErrStaleFencingToken) {
+		err = cause
+	}
+	cancel(nil)
+	wg.Wait()
 	if err != nil {
 		m.lg.Error("fail to run", zap.String("name", m.name), zap.Error(err))
 		return err
@@ -59,26 +165,16 @@ func (m *LogMonitor) init(ctx context.Context) error {
 
 	if err == nil {
 		m.lg.Debug("task found", zap.String("name", m.name), zap.Any("task", task))
-		m.lastProcessedBlockNumber = task.LastProcessedBlockNumber
-		m.lastProcessedTimestamp = task.LastProcessedBlockTimestamp
+		m.load(task)
 		return nil
 	}
 
-	latestConfirmedBlock, err := m.chain.GetBlockByNumber(ctx, chain.BlockNumberSafe, false)
+	lastProcessedBlockNumber, lastProcessedBlockTimestamp, err := m.initialCheckpoint(ctx)
 	if err != nil {
-		m.lg.Error("fail to get latest confirmed block", zap.Error(err))
+		m.lg.Error("fail to get initial checkpoint", zap.String("name", m.name), zap.Error(err))
 		return err
 	}
 
-	lastProcessedBlockNumber := latestConfirmedBlock.BlockNumber
-	if lastProcessedBlockNumber > 0 {
-		lastProcessedBlockNumber = lastProcessedBlockNumber - 1
-	}
-	lastProcessedBlockTimestamp := latestConfirmedBlock.Timestamp
-	if lastProcessedBlockTimestamp > 0 {
-		lastProcessedBlockTimestamp = lastProcessedBlockTimestamp - 1
-	}
-
 	task = do.Task{
 		Name:                        m.name,
 		LastProcessedBlockNumber:    lastProcessedBlockNumber,
@@ -86,19 +182,158 @@ func (m *LogMonitor) init(ctx context.Context) error {
 	}
 
 	task, err = m.repo.TaskDao().InsertTask(ctx, task)
+	if err == repository.ErrDuplicatedKey {
+		// another instance created the task first
+		task, err = m.repo.TaskDao().GetTask(ctx, m.name)
+	}
 	if err != nil {
 		m.lg.Error("fail to insert task", zap.Any("task", task), zap.Error(err))
 		return err
 	}
 
-	m.lastProcessedBlockNumber = lastProcessedBlockNumber
-	m.lastProcessedTimestamp = lastProcessedBlockTimestamp
+	m.load(task)
 
 	m.lg.Debug("initialized", zap.String("name", m.name), zap.Any("task", task))
 
 	return nil
 }
 
+func (m *LogMonitor) load(task do.Task) {
+	m.fencingToken = task.FencingToken
+	m.revision = task.Revision
+	m.lastProcessedBlockNumber = task.LastProcessedBlockNumber
+	m.lastProcessedTimestamp = task.LastProcessedBlockTimestamp
+}
+
+// initialCheckpoint decides where a brand-new task starts: right after the
+// backfill range, at the configured start block or start date, or otherwise
+// at the latest safe block. A configured start never precedes the deployment
+// of the earliest monitored contract.
+func (m *LogMonitor) initialCheckpoint(ctx context.Context) (int64, int64, error) {
+	var startBlockNumber int64
+	switch {
+	case m.cfg.BackfillConfig.Enabled && m.cfg.BackfillConfig.ToBlockNumber > 0:
+		// the live monitor picks up right after the backfill range
+		startBlockNumber = m.cfg.BackfillConfig.ToBlockNumber + 1
+	case m.cfg.StartBlockNumber > 0:
+		startBlockNumber = max(m.cfg.StartBlockNumber, earliestDeployBlockNumber(m.cfg))
+	case m.cfg.StartDate != "":
+		startDate, err := time.Parse(time.RFC3339, m.cfg.StartDate)
+		if err != nil {
+			return 0, 0, err
+		}
+		startBlock, err := m.chain.GetBlockByTimestamp(ctx, startDate.UnixMilli())
+		if err != nil {
+			return 0, 0, err
+		}
+		m.lg.Info("start date resolved", zap.String("name", m.name), zap.String("startDate", m.cfg.StartDate), zap.Int64("blockNumber", startBlock.BlockNumber))
+		startBlockNumber = max(startBlock.BlockNumber, earliestDeployBlockNumber(m.cfg))
+	default:
+		latestConfirmedBlock, err := m.chain.GetBlockByNumber(ctx, chain.BlockNumberSafe, false)
+		if err != nil {
+			m.lg.Error("fail to get latest confirmed block", zap.Error(err))
+			return 0, 0, err
+		}
+
+		lastProcessedBlockNumber := latestConfirmedBlock.BlockNumber
+		if lastProcessedBlockNumber > 0 {
+			lastProcessedBlockNumber = lastProcessedBlockNumber - 1
+		}
+		lastProcessedBlockTimestamp := latestConfirmedBlock.Timestamp
+		if lastProcessedBlockTimestamp > 0 {
+			lastProcessedBlockTimestamp = lastProcessedBlockTimestamp - 1
+		}
+		return lastProcessedBlockNumber, lastProcessedBlockTimestamp, nil
+	}
+
+	if startBlockNumber == 0 {
+		return -1, 0, nil
+	}
+
+	lastProcessedBlock, err := m.chain.GetBlockByNumber(ctx, startBlockNumber-1, false)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return lastProcessedBlock.BlockNumber, lastProcessedBlock.Timestamp, nil
+}
+
+// backfill plans the configured historical range and hands off to the live
+// monitor, returning the backfill to run alongside it. Without an explicit
+// end block the range ends at the live checkpoint of the first run, so the
+// two meet without a gap.
+func (m *LogMonitor) backfill(ctx context.Context) (Task, error) {
+	backfillName := backfillTaskName(m.name)
+	chunks, err := m.repo.BackfillChunkDao().GetChunks(ctx, backfillName)
+	if err != nil {
+		return nil, err
+	}
+
+	fromBlockNumber := max(m.cfg.BackfillConfig.FromBlockNumber, earliestDeployBlockNumber(m.cfg))
+	toBlockNumber := m.cfg.BackfillConfig.ToBlockNumber
+	if toBlockNumber <= 0 {
+		toBlockNumber = m.lastProcessedBlockNumber
+		// the live monitor has processed the blocks since
+		if len(chunks) > 0 {
+			toBlockNumber = chunks[len(chunks)-1].ToBlockNumber
+		}
+	}
+
+	backfill := NewBackfill(
+		m.lg,
+		backfillName,
+		m.instanceId,
+		m.cfg,
+		m.repo,
+		m.chain,
+		WithChunkPlanning(fromBlockNumber, toBlockNumber),
+		WithFencing(m.name, m.fencingToken),
+	)
+	chunks, err = backfill.(*Backfill).planChunks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		return backfill, nil
+	}
+
+	// a live checkpoint inside the backfilled range is moved to its end,
+	// so that the backfilled blocks are not fetched a second time
+	fromBlockNumber = chunks[0].FromBlockNumber
+	toBlockNumber = chunks[len(chunks)-1].ToBlockNumber
+	if m.lastProcessedBlockNumber < fromBlockNumber-1 || m.lastProcessedBlockNumber >= toBlockNumber {
+		return backfill, nil
+	}
+
+	block, err := m.chain.GetBlockByNumber(ctx, toBlockNumber, false)
+	if err != nil {
+		return nil, err
+	}
+
+	task := do.Task{
+		Name:                        m.name,
+		LastProcessedBlockNumber:    block.BlockNumber,
+		LastProcessedBlockTimestamp: block.Timestamp,
+		FencingToken:                m.fencingToken,
+		Revision:                    m.revision,
+	}
+	task, err = m.repo.TaskDao().UpdateTask(ctx, task)
+	if errors.Is(err, repository.ErrTaskRevised) {
+		// the checkpoint set by an admin is taken by the next poll
+		return backfill, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m.lastProcessedBlockNumber = task.LastProcessedBlockNumber
+	m.lastProcessedTimestamp = task.LastProcessedBlockTimestamp
+
+	m.lg.Info("handed off from backfill", zap.String("name", m.name), zap.Any("task", task))
+
+	return backfill, nil
+}
+
 func (m *LogMonitor) run(ctx context.Context) error {
 	ticker := time.NewTicker(time.Duration(m.cfg.PollInterval) * time.Second)
 	defer ticker.Stop()
@@ -109,139 +344,417 @@ func (m *LogMonitor) run(ctx context.Context) error {
 			m.lg.Error("stopped", zap.String("name", m.name))
 			return ctx.Err()
 		case <-ticker.C:
-			func() {
-				defer func() {
-					if r := recover(); r != nil {
-						m.lg.Error("panic", zap.String("name", m.name), zap.Any("error", r), zap.Stack("stack"))
-					}
-				}()
-
-				lastProcessedBlockNumber := m.lastProcessedBlockNumber
-
-				latestConfirmedBlock, err := m.chain.GetBlockByNumber(ctx, chain.BlockNumberSafe, false)
-				if err != nil {
-					m.lg.Error("fail to get latest confirmed block", zap.String("name", m.name), zap.Error(err))
-					return
-				}
-				m.lg.Debug("latest confirmed block", zap.String("name", m.name), zap.Int64("blockNumber", latestConfirmedBlock.BlockNumber))
-				latestBlockNumber := latestConfirmedBlock.BlockNumber
-
-				if latestBlockNumber <= (lastProcessedBlockNumber + m.cfg.BlockDistance) {
-					return
-				}
+			// a revised task is reloaded by the next poll
+			err := m.poll(ctx)
+			if errors.Is(err, repository.ErrStaleFencingToken) {
+				return err
+			}
+			// the task was paused along with the dead letter, so the polls
+			// skip it until an admin resumes it once the range is fixed
+			if errors.Is(err, ErrDeadLettered) {
+				m.lg.Error("halted", zap.String("name", m.name), zap.Error(err))
+			}
+		}
+	}
+}
 
-				startBlockNumber := lastProcessedBlockNumber + 1
-				endBlockNumber := latestBlockNumber - m.cfg.BlockDistance
-				_ = m.processBlocks(ctx, startBlockNumber, endBlockNumber)
-			}()
+// poll processes the blocks confirmed since the checkpoint, keeping
+// BlockDistance blocks away from the latest safe block.
+func (m *LogMonitor) poll(ctx context.Context) error {
+	defer func() {
+		if r := recover(); r != nil {
+			m.lg.Error("panic", zap.String("name", m.name), zap.Any("error", r), zap.Stack("stack"))
 		}
+	}()
+
+	paused, err := m.refresh(ctx)
+	if err != nil {
+		m.lg.Error("fail to refresh task", zap.String("name", m.name), zap.Error(err))
+		return nil
+	}
+	if paused {
+		m.lg.Debug("paused", zap.String("name", m.name))
+		return nil
+	}
+
+	if err := m.rescan(ctx); err != nil {
+		m.lg.Error("fail to rescan", zap.String("name", m.name), zap.Error(err))
+	}
+
+	lastProcessedBlockNumber := m.lastProcessedBlockNumber
+
+	latestConfirmedBlock, err := m.chain.GetBlockByNumber(ctx, chain.BlockNumberSafe, false)
+	if err != nil {
+		m.lg.Error("fail to get latest confirmed block", zap.String("name", m.name), zap.Error(err))
+		return nil
+	}
+	m.lg.Debug("latest confirmed block", zap.String("name", m.name), zap.Int64("blockNumber", latestConfirmedBlock.BlockNumber))
+	latestBlockNumber := latestConfirmedBlock.BlockNumber
+	m.latestBlock = latestConfirmedBlock
+	m.observeLag()
+
+	if latestBlockNumber <= (lastProcessedBlockNumber + m.cfg.BlockDistance) {
+		return nil
+	}
+
+	startBlockNumber := lastProcessedBlockNumber + 1
+	endBlockNumber := latestBlockNumber - m.cfg.BlockDistance
+	err = m.processBlocks(ctx, startBlockNumber, endBlockNumber)
+	if err != nil {
+		m.lg.Error(
+			"fail to process blocks",
+			zap.String("name", m.name),
+			zap.Int64("fromBlockNumber", startBlockNumber),
+			zap.Int64("toBlockNumber", endBlockNumber),
+			zap.Error(err),
+		)
 	}
+	return err
+}
+
+// observeLag records how far the checkpoint is behind the latest safe block
+// seen by the last poll.
+func (m *LogMonitor) observeLag() {
+	metrics.TaskLagBlocks.WithLabelValues(m.name).Set(float64(max(m.latestBlock.BlockNumber-m.lastProcessedBlockNumber, 0)))
+	metrics.TaskLagSeconds.WithLabelValues(m.name).Set(float64(max(m.latestBlock.Timestamp-m.lastProcessedTimestamp, 0)) / 1000)
 }
 
+// refresh takes the checkpoint set by an admin since the task was loaded,
+// and tells whether the task is paused. A range committed with the previous
+// revision fails with ErrTaskRevised, so an admin change is never
+// overwritten.
+func (m *LogMonitor) refresh(ctx context.Context) (bool, error) {
+	task, err := m.repo.TaskDao().GetTask(ctx, m.name)
+	if err != nil {
+		return false, err
+	}
+	if task.Revision != m.revision {
+		m.lg.Info("task revised", zap.String("name", m.name), zap.Any("task", task))
+		m.revision = task.Revision
+		m.lastProcessedBlockNumber = task.LastProcessedBlockNumber
+		m.lastProcessedTimestamp = task.LastProcessedBlockTimestamp
+	}
+	return task.Paused, nil
+}
+
+// rescan fetches and stores again the ranges requested with RescanBlocks,
+// leaving the checkpoint untouched.
+func (m *LogMonitor) rescan(ctx context.Context) error {
+	rescanName := rescanTaskName(m.name)
+	chunks, err := m.repo.BackfillChunkDao().GetChunks(ctx, rescanName)
+	if err != nil {
+		return err
+	}
+	if !lo.ContainsBy(chunks, func(chunk do.BackfillChunk) bool { return !chunk.Completed }) {
+		return nil
+	}
+	return NewBackfill(m.lg, rescanName, m.instanceId, m.cfg, m.repo, m.chain, WithFencing(m.name, m.fencingToken)).Start(ctx)
+}
+
+type queryResult struct {
+	fromBlockNumber int64
+	toBlockNumber   int64
+	blocks          []chain.Block
+	attempts        int64
+	startedAt       time.Time
+	err             error
+}
+
+// processBlocks queries several windows of blocks concurrently and commits
+// them strictly in order. At most MaxConcurrentQueries windows are in flight
+// or waiting to be committed, which bounds the memory held and stops querying
+// while the database falls behind.
 func (m *LogMonitor) processBlocks(ctx context.Context, fromBlockNumber int64, toBlockNumber int64) error {
-	i := fromBlockNumber
-	for i <= toBlockNumber {
-		j := i + m.cfg.QueryMaxBlocks
-		if j > toBlockNumber {
-			j = toBlockNumber
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// the window being committed, or waited for, is taken off the channel,
+	// so the channel holds one window less than the bound
+	results := make(chan chan queryResult, max(m.cfg.MaxConcurrentQueries, 1)-1)
+	go func() {
+		defer close(results)
+
+		queryMaxBlocks := max(m.cfg.QueryMaxBlocks, 1)
+		for i := fromBlockNumber; i <= toBlockNumber; i += queryMaxBlocks {
+			j := min(i+queryMaxBlocks-1, toBlockNumber)
+
+			result := make(chan queryResult, 1)
+			select {
+			case results <- result:
+			case <-ctx.Done():
+				return
+			}
+
+			go func() {
+				startedAt := time.Now()
+				blocks, attempts, err := m.queryBlocksWithRetry(ctx, i, j)
+				result <- queryResult{
+					fromBlockNumber: i,
+					toBlockNumber:   j,
+					blocks:          blocks,
+					attempts:        attempts,
+					startedAt:       startedAt,
+					err:             err,
+				}
+			}()
 		}
-		err := m.queryAndFilterLogsInBlocksWithRetry(ctx, i, j)
-		if err != nil {
+	}()
+
+	for result := range results {
+		r := <-result
+		if r.err != nil {
+			if err := m.deadLetter(ctx, r.fromBlockNumber, r.toBlockNumber, r.attempts, r.err); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := m.commitBlocks(ctx, r.fromBlockNumber, r.toBlockNumber, r.blocks, r.startedAt); err != nil {
 			return err
 		}
-		i = j + 1
 	}
 
-	return nil
+	return ctx.Err()
 }
 
-func (m *LogMonitor) queryAndFilterLogsInBlocksWithRetry(ctx context.Context, fromBlockNumber int64, toBlockNumber int64) error {
-	var err error
-	for i := int64(0); i < m.cfg.MaxBlockRetries; i++ {
-		err = m.queryAndFilterLogsInBlocks(ctx, fromBlockNumber, toBlockNumber)
-		if err == nil {
-			m.lg.Debug(
-				"query and filter logs in blocks succeeded",
+func (m *LogMonitor) queryBlocksWithRetry(ctx context.Context, fromBlockNumber int64, toBlockNumber int64) ([]chain.Block, int64, error) {
+	var blocks []chain.Block
+	attempts := int64(0)
+	err := m.retryPolicy.Do(ctx, func() error {
+		attempts++
+		var err error
+		blocks, err = m.queryBlocks(ctx, fromBlockNumber, toBlockNumber)
+		if err != nil {
+			m.lg.Error(
+				"query blocks failed",
 				zap.String("name", m.name),
 				zap.Int64("fromBlockNumber", fromBlockNumber),
 				zap.Int64("toBlockNumber", toBlockNumber),
+				zap.Stringer("class", retry.Classify(err)),
+				zap.Error(err),
 			)
-			break
 		}
-		m.lg.Error(
-			"query and filter logs in blocks failed",
-			zap.String("name", m.name),
-			zap.Int64("fromBlockNumber", fromBlockNumber),
-			zap.Int64("toBlockNumber", toBlockNumber),
-			zap.Error(err),
-		)
+		return err
+	})
+	if err != nil {
+		return nil, attempts, err
 	}
 
-	return err
+	m.lg.Debug(
+		"query blocks succeeded",
+		zap.String("name", m.name),
+		zap.Int64("fromBlockNumber", fromBlockNumber),
+		zap.Int64("toBlockNumber", toBlockNumber),
+	)
+
+	return blocks, attempts, nil
+}
+
+func (m *LogMonitor) queryBlocks(ctx context.Context, fromBlockNumber int64, toBlockNumber int64) ([]chain.Block, error) {
+	return queryBlocks(ctx, m.chain, m.cfg, fromBlockNumber, toBlockNumber)
 }
 
-func (m *LogMonitor) queryAndFilterLogsInBlocks(ctx context.Context, fromBlockNumber int64, toBlockNumber int64) error {
-	blocks, err := m.chain.GetBlocks(
+// queryBlocks returns every block of the range in order, with the logs of the
+// monitored contracts, and fails unless the provider returned all of them.
+func queryBlocks(ctx context.Context, c chain.Chain, cfg config.EventMonitorConfig, fromBlockNumber int64, toBlockNumber int64) ([]chain.Block, error) {
+	addresses, includeLogs := monitoredAddresses(cfg, toBlockNumber)
+	blocks, err := c.GetBlocks(
 		ctx,
 		fromBlockNumber,
 		toBlockNumber,
-		false,
-		true,
-		m.cfg.MonitoredContractAddresses,
+		cfg.StoreTransactions,
+		includeLogs,
+		addresses,
 		[]string{},
 	)
 	if err != nil {
-		m.lg.Error(
-			"fail to get blocks",
+		return nil, err
+	}
+
+	if int64(len(blocks)) != toBlockNumber-fromBlockNumber+1 {
+		return nil, fmt.Errorf("expected %d blocks, got %d", toBlockNumber-fromBlockNumber+1, len(blocks))
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].BlockNumber < blocks[j].BlockNumber
+	})
+
+	return blocks, nil
+}
+
+// commitBlocks stores the logs of a queried range, records the range, moves
+// the checkpoint to its last block and announces the logs in a single
+// transaction.
+func (m *LogMonitor) commitBlocks(ctx context.Context, fromBlockNumber int64, toBlockNumber int64, blocks []chain.Block, startedAt time.Time) error {
+	// only the write is retried, the queried blocks are reused
+	attempts := int64(0)
+	err := m.retryPolicy.Do(ctx, func() error {
+		attempts++
+		return m.commitRange(ctx, blocks, startedAt)
+	})
+	if errors.Is(err, repository.ErrStaleFencingToken) || errors.Is(err, repository.ErrTaskRevised) {
+		return err
+	}
+	if err != nil {
+		return m.deadLetter(ctx, fromBlockNumber, toBlockNumber, attempts, err)
+	}
+
+	lastBlock := blocks[len(blocks)-1]
+	m.lastProcessedBlockNumber = lastBlock.BlockNumber
+	m.lastProcessedTimestamp = lastBlock.Timestamp
+	m.observeLag()
+
+	return nil
+}
+
+func (m *LogMonitor) commitRange(ctx context.Context, blocks []chain.Block, startedAt time.Time) error {
+	firstBlock, lastBlock := blocks[0], blocks[len(blocks)-1]
+	notification := repository.LogNotification{
+		TaskName:        m.name,
+		ChainId:         m.chain.GetChainId(),
+		FromBlockNumber: firstBlock.BlockNumber,
+		ToBlockNumber:   lastBlock.BlockNumber,
+	}
+
+	logs := 0
+	err := m.repo.Transaction(func(repo repository.Repository) error {
+		var err error
+		logs, err = storeBlocks(ctx, repo, m.cfg, m.chain.GetChainId(), blocks)
+		if err != nil {
+			m.lg.Error(
+				"fail to store blocks",
+				zap.String("name", m.name),
+				zap.Int64("fromBlockNumber", firstBlock.BlockNumber),
+				zap.Int64("toBlockNumber", lastBlock.BlockNumber),
+				zap.Error(err),
+			)
+			return err
+		}
+		m.lg.Debug(
+			"blocks stored",
 			zap.String("name", m.name),
-			zap.Int64("fromBlockNumber", fromBlockNumber),
-			zap.Int64("toBlockNumber", toBlockNumber),
-			zap.Error(err),
+			zap.Int64("fromBlockNumber", firstBlock.BlockNumber),
+			zap.Int64("toBlockNumber", lastBlock.BlockNumber),
+			zap.Int("logs", logs),
 		)
+
+		processedRange := newProcessedRange(m.name, m.instanceId, m.cfg, m.chain.GetChainId(), blocks, logs, startedAt)
+		if _, err := repo.ProcessedRangeDao().InsertProcessedRange(ctx, processedRange); err != nil {
+			m.lg.Error("fail to record processed range", zap.String("name", m.name), zap.Any("processedRange", processedRange), zap.Error(err))
+			return err
+		}
+
+		task := do.Task{
+			Name:                        m.name,
+			LastProcessedBlockNumber:    lastBlock.BlockNumber,
+			LastProcessedBlockTimestamp: lastBlock.Timestamp,
+			FencingToken:                m.fencingToken,
+			Revision:                    m.revision,
+		}
+		task, err = repo.TaskDao().UpdateTask(ctx, task)
+		if err != nil {
+			m.lg.Error("fail to update task", zap.String("name", m.name), zap.Error(err))
+			return err
+		}
+		m.lg.Debug("task updated", zap.String("name", m.name), zap.Any("task", task))
+
+		if err := repo.NotificationDao().NotifyLogs(ctx, notification); err != nil {
+			m.lg.Error("fail to notify logs", zap.String("name", m.name), zap.Any("notification", notification), zap.Error(err))
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
 		return err
 	}
+	metrics.LogsInserted.WithLabelValues(m.name).Add(float64(logs))
+	metrics.RangesProcessed.WithLabelValues(m.name).Inc()
 
-	for blockNumber := fromBlockNumber; blockNumber <= toBlockNumber; blockNumber++ {
-		block := blocks[blockNumber-fromBlockNumber]
-		txErr := m.repo.Transaction(func(repo repository.Repository) error {
-			logDOs := lo.Map(block.Logs, func(log chain.Log, _ int) do.Log {
-				return do.Log{
-					ChainId:     m.chain.GetChainId(),
-					BlockNumber: log.BlockNumber,
-					BlockHash:   log.BlockHash,
-					Data:        log.Data,
-					Topics:      log.Topics,
-					TxnHash:     log.TxnHash,
-					LogIndex:    log.LogIndex,
-					Removed:     log.Removed,
-					Timestamp:   block.Timestamp,
-				}
-			})
-			err := repo.LogDao().InsertLogs(ctx, logDOs)
+	if m.onCommit != nil {
+		m.onCommit(notification)
+	}
+	return nil
+}
+
+// deadLetter records a range which exhausted its retries. Under the skip
+// failure policy the checkpoint moves past the range, leaving a recorded gap
+// which can be retried later. Otherwise the task is paused, and
+// ErrDeadLettered is returned once both are committed.
+func (m *LogMonitor) deadLetter(ctx context.Context, fromBlockNumber int64, toBlockNumber int64, attempts int64, cause error) error {
+	if ctx.Err() != nil {
+		return cause
+	}
+
+	now := time.Now()
+	deadLetter := do.DeadLetter{
+		TaskName:        m.name,
+		FromBlockNumber: fromBlockNumber,
+		ToBlockNumber:   toBlockNumber,
+		Error:           cause.Error(),
+		Attempts:        attempts,
+		Status:          do.DeadLetterStatusOpen,
+		Skipped:         m.cfg.FailurePolicy == config.FailurePolicySkip,
+		FirstFailedAt:   now,
+		LastFailedAt:    now,
+	}
+
+	if !deadLetter.Skipped {
+		err := m.repo.Transaction(func(repo repository.Repository) error {
+			if _, err := repo.DeadLetterDao().RecordDeadLetter(ctx, deadLetter); err != nil {
+				return err
+			}
+			task, err := repo.TaskDao().GetTaskForUpdate(ctx, m.name)
 			if err != nil {
-				m.lg.Error("fail to insert logs", zap.String("name", m.name), zap.Int64("blockNumber", blockNumber), zap.Error(err))
 				return err
 			}
-			m.lg.Debug("logs inserted", zap.String("name", m.name), zap.Int64("blockNumber", blockNumber), zap.Int("logs", len(logDOs)))
-
-			task := do.Task{
-				Name:                        m.name,
-				LastProcessedBlockNumber:    block.BlockNumber,
-				LastProcessedBlockTimestamp: block.Timestamp,
+			if task.FencingToken != m.fencingToken {
+				return repository.ErrStaleFencingToken
 			}
-			task, err = repo.TaskDao().UpdateTask(ctx, task)
+			task.Paused = true
+			task, err = repo.TaskDao().ReviseTask(ctx, task)
 			if err != nil {
-				m.lg.Error("fail to update task", zap.String("name", m.name), zap.Error(err))
 				return err
 			}
-			m.lg.Debug("task updated", zap.String("name", m.name), zap.Any("task", task))
-
+			m.revision = task.Revision
 			return nil
 		})
-		if txErr != nil {
-			return txErr
+		if err != nil {
+			// the range is retried by the next poll
+			m.lg.Error("fail to record dead letter", zap.String("name", m.name), zap.Any("deadLetter", deadLetter), zap.Error(err))
+			return err
 		}
+		return fmt.Errorf("%w: blocks %d to %d: %w", ErrDeadLettered, fromBlockNumber, toBlockNumber, cause)
 	}
 
+	block, err := m.chain.GetBlockByNumber(ctx, toBlockNumber, false)
+	if err != nil {
+		return err
+	}
+
+	err = m.repo.Transaction(func(repo repository.Repository) error {
+		_, err := repo.DeadLetterDao().RecordDeadLetter(ctx, deadLetter)
+		if err != nil {
+			return err
+		}
+
+		task := do.Task{
+			Name:                        m.name,
+			LastProcessedBlockNumber:    block.BlockNumber,
+			LastProcessedBlockTimestamp: block.Timestamp,
+			FencingToken:                m.fencingToken,
+			Revision:                    m.revision,
+		}
+		_, err = repo.TaskDao().UpdateTask(ctx, task)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	m.lastProcessedBlockNumber = block.BlockNumber
+	m.lastProcessedTimestamp = block.Timestamp
+	m.observeLag()
+
+	m.lg.Warn("blocks skipped", zap.String("name", m.name), zap.Any("deadLetter", deadLetter))
+
 	return nil
 }
@@ -0,0 +1,200 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/waynewu411/blocktasks/pkg/chain"
+	"github.com/waynewu411/blocktasks/pkg/config"
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"github.com/waynewu411/blocktasks/pkg/repository"
+	"go.uber.org/zap"
+)
+
+// LogPartitionMaintainer keeps the log partitions of a chain ahead of the
+// checkpoints of the tasks storing its logs, and retires the partitions past
+// the retention. Since the partitions hold the logs of the whole chain, a
+// partition is only retired once every task on the chain is past it, with no
+// open dead letter or unfinished chunk left in it.
+type LogPartitionMaintainer struct {
+	baseTask
+	taskNames []string // tasks storing the logs of the chain
+	cfg       config.PartitionConfig
+	repo      repository.Repository
+	chain     chain.Chain
+
+	leaseTaskName string // task whose lease holder maintains the partitions
+	instanceId    string
+}
+
+type LogPartitionMaintainerOption func(*LogPartitionMaintainer)
+
+// WithLeaseHolder has only the instance holding the lease of the task
+// maintain the partitions, so that replicas do not retire them concurrently.
+func WithLeaseHolder(taskName string, instanceId string) LogPartitionMaintainerOption {
+	return func(p *LogPartitionMaintainer) {
+		p.leaseTaskName = taskName
+		p.instanceId = instanceId
+	}
+}
+
+func NewLogPartitionMaintainer(lg *zap.Logger, name string, taskNames []string, cfg config.PartitionConfig, repo repository.Repository, chain chain.Chain, opts ...LogPartitionMaintainerOption) Task {
+	p := &LogPartitionMaintainer{
+		baseTask: baseTask{
+			lg:   lg,
+			name: name,
+		},
+		taskNames: taskNames,
+		cfg:       cfg,
+		repo:      repo,
+		chain:     chain,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *LogPartitionMaintainer) Start(ctx context.Context) error {
+	ticker := time.NewTicker(time.Duration(max(p.cfg.MaintenanceInterval, 1)) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		// failures are retried on the next tick, since the partitions are
+		// also created on demand when logs are inserted
+		if err := p.maintain(ctx); err != nil && ctx.Err() == nil {
+			p.lg.Error("fail to maintain log partitions", zap.String("name", p.name), zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *LogPartitionMaintainer) maintain(ctx context.Context) error {
+	leaseHolder, err := p.holdsLease(ctx)
+	if err != nil {
+		return err
+	}
+	if !leaseHolder {
+		p.lg.Debug("standing by", zap.String("name", p.name))
+		return nil
+	}
+
+	var tasks []do.Task
+	for _, taskName := range p.taskNames {
+		task, err := p.repo.TaskDao().GetTask(ctx, taskName)
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		tasks = append(tasks, task)
+	}
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	chainId := p.chain.GetChainId()
+	lastProcessedBlockNumber := lo.MaxBy(tasks, func(a do.Task, b do.Task) bool {
+		return a.LastProcessedBlockNumber > b.LastProcessedBlockNumber
+	}).LastProcessedBlockNumber
+	fromBlockNumber := max(lastProcessedBlockNumber+1, 0)
+	toBlockNumber := fromBlockNumber + max(p.cfg.PremakeBlocks, 1) - 1
+	if err := p.repo.LogPartitionDao().EnsureLogPartitions(ctx, chainId, fromBlockNumber, toBlockNumber); err != nil {
+		return err
+	}
+
+	if p.cfg.RetentionBlocks <= 0 {
+		return nil
+	}
+
+	cutoffBlockNumber, err := p.cutoffBlockNumber(ctx, tasks)
+	if err != nil {
+		return err
+	}
+	partitions, err := p.repo.LogPartitionDao().GetLogPartitions(ctx, chainId)
+	if err != nil {
+		return err
+	}
+	for _, partition := range partitions {
+		if partition.ToBlockNumber > cutoffBlockNumber {
+			break
+		}
+
+		if p.cfg.RetentionAction == config.RetentionActionArchive {
+			err = p.repo.LogPartitionDao().ArchiveLogPartition(ctx, partition, p.cfg.ArchiveSchema)
+		} else {
+			err = p.repo.LogPartitionDao().DropLogPartition(ctx, partition)
+		}
+		if err != nil {
+			return err
+		}
+
+		p.lg.Info(
+			"log partition retired",
+			zap.String("name", p.name),
+			zap.String("action", p.cfg.RetentionAction),
+			zap.Int64("cutoffBlockNumber", cutoffBlockNumber),
+			zap.Any("partition", partition),
+		)
+	}
+
+	return nil
+}
+
+// holdsLease tells whether this instance holds the lease of the task, as of
+// the time of the database. Without a lease every instance maintains.
+func (p *LogPartitionMaintainer) holdsLease(ctx context.Context) (bool, error) {
+	if p.leaseTaskName == "" {
+		return true, nil
+	}
+	task, err := p.repo.TaskDao().GetTask(ctx, p.leaseTaskName)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	now, err := p.repo.TaskDao().Now(ctx)
+	if err != nil {
+		return false, err
+	}
+	return task.Owner == p.instanceId && now.Before(task.LeaseExpiresAt), nil
+}
+
+// cutoffBlockNumber returns the block before which the partitions may be
+// retired: RetentionBlocks behind the oldest checkpoint, and never past the
+// start of an open dead letter or an unfinished backfill or rescan chunk.
+func (p *LogPartitionMaintainer) cutoffBlockNumber(ctx context.Context, tasks []do.Task) (int64, error) {
+	cutoffBlockNumber := int64(math.MaxInt64)
+	for _, task := range tasks {
+		cutoffBlockNumber = min(cutoffBlockNumber, task.LastProcessedBlockNumber+1-p.cfg.RetentionBlocks)
+
+		deadLetters, err := p.repo.DeadLetterDao().GetDeadLetters(ctx, task.Name, do.DeadLetterStatusOpen)
+		if err != nil {
+			return 0, err
+		}
+		for _, deadLetter := range deadLetters {
+			cutoffBlockNumber = min(cutoffBlockNumber, deadLetter.FromBlockNumber)
+		}
+
+		chunks, err := GetChunks(ctx, p.repo, task.Name)
+		if err != nil {
+			return 0, err
+		}
+		for _, chunk := range chunks {
+			if !chunk.Completed {
+				cutoffBlockNumber = min(cutoffBlockNumber, chunk.FromBlockNumber)
+			}
+		}
+	}
+	return cutoffBlockNumber, nil
+}
@@ -0,0 +1,136 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/waynewu411/blocktasks/pkg/config"
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"github.com/waynewu411/blocktasks/pkg/repository"
+	"go.uber.org/zap/zaptest"
+)
+
+// fakeLogPartitionDao holds partitions of 100 blocks, and records the ones
+// retired.
+type fakeLogPartitionDao struct {
+	partitions []repository.LogPartition
+	retired    []int64 // first blocks of the retired partitions
+}
+
+func (d *fakeLogPartitionDao) GetLogPartitions(ctx context.Context, chainId int64) ([]repository.LogPartition, error) {
+	return slices.Clone(d.partitions), nil
+}
+
+func (d *fakeLogPartitionDao) EnsureLogPartitions(ctx context.Context, chainId int64, fromBlockNumber int64, toBlockNumber int64) error {
+	for blockNumber := fromBlockNumber - fromBlockNumber%100; blockNumber <= toBlockNumber; blockNumber += 100 {
+		if !slices.ContainsFunc(d.partitions, func(partition repository.LogPartition) bool {
+			return partition.FromBlockNumber == blockNumber
+		}) && !slices.Contains(d.retired, blockNumber) {
+			d.partitions = append(d.partitions, repository.LogPartition{
+				ChainId:         chainId,
+				Name:            fmt.Sprintf("Logs_%d_%d", chainId, blockNumber),
+				FromBlockNumber: blockNumber,
+				ToBlockNumber:   blockNumber + 100,
+			})
+		}
+	}
+	slices.SortFunc(d.partitions, func(a, b repository.LogPartition) int {
+		return int(a.FromBlockNumber - b.FromBlockNumber)
+	})
+	return nil
+}
+
+func (d *fakeLogPartitionDao) DropLogPartition(ctx context.Context, partition repository.LogPartition) error {
+	d.partitions = slices.DeleteFunc(d.partitions, func(p repository.LogPartition) bool {
+		return p.Name == partition.Name
+	})
+	d.retired = append(d.retired, partition.FromBlockNumber)
+	return nil
+}
+
+func (d *fakeLogPartitionDao) ArchiveLogPartition(ctx context.Context, partition repository.LogPartition, archiveSchema string) error {
+	return d.DropLogPartition(ctx, partition)
+}
+
+// partitionedRepository stores everything in memory but the partitions.
+type partitionedRepository struct {
+	repository.Repository
+	logPartitionDao *fakeLogPartitionDao
+}
+
+func (r partitionedRepository) LogPartitionDao() repository.LogPartitionDao {
+	return r.logPartitionDao
+}
+
+func TestLogPartitionMaintainer_Retire(t *testing.T) {
+	ctx := context.Background()
+	partitions := &fakeLogPartitionDao{}
+	repo := partitionedRepository{Repository: repository.NewMemoryRepository(), logPartitionDao: partitions}
+	const otherTask = "other-log-monitor"
+	_, err := repo.TaskDao().InsertTask(ctx, do.Task{Name: TaskBaseLogMonitor, LastProcessedBlockNumber: 999, Owner: "b"})
+	require.NoError(t, err)
+	_, err = repo.TaskDao().InsertTask(ctx, do.Task{Name: otherTask, LastProcessedBlockNumber: 449})
+	require.NoError(t, err)
+	require.NoError(t, partitions.EnsureLogPartitions(ctx, fakeChainId, 0, 999))
+
+	cfg := config.PartitionConfig{PremakeBlocks: 100, RetentionBlocks: 100, RetentionAction: config.RetentionActionDrop}
+	maintainer := NewLogPartitionMaintainer(
+		zaptest.NewLogger(t),
+		TaskBaseLogPartitionMaintainer,
+		[]string{TaskBaseLogMonitor, otherTask},
+		cfg,
+		repo,
+		newFakeChain(0),
+		WithLeaseHolder(TaskBaseLogMonitor, "a"),
+	).(*LogPartitionMaintainer)
+
+	// another instance holds the lease
+	task, err := repo.TaskDao().GetTask(ctx, TaskBaseLogMonitor)
+	require.NoError(t, err)
+	task.LeaseExpiresAt = time.Now().Add(time.Minute)
+	_, err = repo.TaskDao().UpdateLease(ctx, task)
+	require.NoError(t, err)
+	require.NoError(t, maintainer.maintain(ctx))
+	require.Empty(t, partitions.retired)
+	require.Len(t, partitions.partitions, 10)
+
+	// the lease holder keeps the open dead letters and unfinished chunks
+	task.Owner = "a"
+	_, err = repo.TaskDao().UpdateLease(ctx, task)
+	require.NoError(t, err)
+	_, err = repo.DeadLetterDao().RecordDeadLetter(ctx, do.DeadLetter{
+		TaskName:        TaskBaseLogMonitor,
+		FromBlockNumber: 250,
+		ToBlockNumber:   260,
+		Status:          do.DeadLetterStatusOpen,
+	})
+	require.NoError(t, err)
+	chunk := do.BackfillChunk{TaskName: backfillTaskName(otherTask), FromBlockNumber: 150, ToBlockNumber: 199, LastProcessedBlockNumber: 170}
+	require.NoError(t, repo.BackfillChunkDao().InsertChunks(ctx, []do.BackfillChunk{chunk}))
+	require.NoError(t, maintainer.maintain(ctx))
+	require.Equal(t, []int64{0}, partitions.retired)
+
+	chunk.LastProcessedBlockNumber = 199
+	chunk.Completed = true
+	_, err = repo.BackfillChunkDao().UpdateChunk(ctx, chunk)
+	require.NoError(t, err)
+	require.NoError(t, maintainer.maintain(ctx))
+	require.Equal(t, []int64{0, 100}, partitions.retired)
+
+	// and the partitions of the oldest checkpoint on the chain past the
+	// retention
+	deadLetters, err := repo.DeadLetterDao().GetDeadLetters(ctx, TaskBaseLogMonitor, do.DeadLetterStatusOpen)
+	require.NoError(t, err)
+	deadLetters[0].Status = do.DeadLetterStatusResolved
+	_, err = repo.DeadLetterDao().UpdateDeadLetter(ctx, deadLetters[0])
+	require.NoError(t, err)
+	require.NoError(t, maintainer.maintain(ctx))
+	require.Equal(t, []int64{0, 100, 200}, partitions.retired)
+
+	// partitions are made ahead of the latest checkpoint
+	require.Equal(t, int64(1100), partitions.partitions[len(partitions.partitions)-1].ToBlockNumber)
+}
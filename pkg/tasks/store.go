@@ -0,0 +1,169 @@
+package tasks
+
+import (
+	"context"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/waynewu411/blocktasks/pkg/chain"
+	"github.com/waynewu411/blocktasks/pkg/config"
+	"github.com/waynewu411/blocktasks/pkg/do"
+	"github.com/waynewu411/blocktasks/pkg/repository"
+)
+
+// storeBlocks inserts the logs of the blocks with repo, together with the
+// block headers and the transactions of the logs when they are enabled, and
+// returns the number of logs. repo is expected to be a transaction.
+func storeBlocks(ctx context.Context, repo repository.Repository, cfg config.EventMonitorConfig, chainId int64, blocks []chain.Block) (int, error) {
+	logDOs := lo.FlatMap(blocks, func(block chain.Block, _ int) []do.Log {
+		return newLogDOs(chainId, block)
+	})
+	if err := repo.LogDao().InsertLogs(ctx, logDOs); err != nil {
+		return 0, err
+	}
+
+	if cfg.StoreBlocks {
+		blockDOs := lo.Map(blocks, func(block chain.Block, _ int) do.Block {
+			return newBlockDO(chainId, block)
+		})
+		if err := repo.BlockDao().InsertBlocks(ctx, blockDOs); err != nil {
+			return 0, err
+		}
+	}
+
+	if cfg.StoreTransactions {
+		txnDOs := lo.FlatMap(blocks, func(block chain.Block, _ int) []do.Transaction {
+			return newTransactionDOs(chainId, block)
+		})
+		if err := repo.TransactionDao().InsertTransactions(ctx, txnDOs); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(logDOs), nil
+}
+
+func newLogDOs(chainId int64, block chain.Block) []do.Log {
+	return lo.Map(block.Logs, func(log chain.Log, _ int) do.Log {
+		return do.Log{
+			ChainId:        chainId,
+			BlockNumber:    log.BlockNumber,
+			BlockHash:      log.BlockHash,
+			Address:        log.Address,
+			Data:           log.Data,
+			Topics:         log.Topics,
+			TxnHash:        log.TxnHash,
+			TxnIndex:       log.TxnIndex,
+			LogIndex:       log.LogIndex,
+			Removed:        log.Removed,
+			Timestamp:      block.Timestamp,
+			BlockTimestamp: time.UnixMilli(block.Timestamp).UTC(),
+		}
+	})
+}
+
+// newProcessedRange returns the audit record of blocks committed by a task,
+// which started querying them at startedAt.
+func newProcessedRange(taskName string, instanceId string, cfg config.EventMonitorConfig, chainId int64, blocks []chain.Block, logs int, startedAt time.Time) do.ProcessedRange {
+	firstBlock := lo.MinBy(blocks, func(a chain.Block, b chain.Block) bool {
+		return a.BlockNumber < b.BlockNumber
+	})
+	lastBlock := lo.MaxBy(blocks, func(a chain.Block, b chain.Block) bool {
+		return a.BlockNumber > b.BlockNumber
+	})
+	now := time.Now()
+	return do.ProcessedRange{
+		TaskName:        taskName,
+		ChainId:         chainId,
+		FromBlockNumber: firstBlock.BlockNumber,
+		ToBlockNumber:   lastBlock.BlockNumber,
+		FromBlockHash:   firstBlock.BlockHash,
+		ToBlockHash:     lastBlock.BlockHash,
+		LogCount:        int64(logs),
+		RpcEndpoint:     rpcEndpoint(cfg.ChainConfig),
+		DurationMs:      now.Sub(startedAt).Milliseconds(),
+		InstanceId:      instanceId,
+		CommittedAt:     now,
+	}
+}
+
+// rpcEndpoint returns the endpoint of the chain without credentials. The api
+// key is appended to the endpoint by the chain, so it is never included.
+func rpcEndpoint(cfg config.ChainConfig) string {
+	u, err := url.Parse(cfg.ApiEndpoint)
+	if err != nil {
+		return ""
+	}
+	u.User = nil
+	u.RawQuery = ""
+	return u.String()
+}
+
+func newBlockDO(chainId int64, block chain.Block) do.Block {
+	var baseFeePerGas *string
+	if block.BaseFeePerGas != "" {
+		baseFeePerGas = lo.ToPtr(decimalQuantity(block.BaseFeePerGas))
+	}
+	return do.Block{
+		ChainId:       chainId,
+		BlockNumber:   block.BlockNumber,
+		BlockHash:     block.BlockHash,
+		ParentHash:    block.ParentHash,
+		Timestamp:     block.Timestamp,
+		GasLimit:      block.GasLimit,
+		GasUsed:       block.GasUsed,
+		BaseFeePerGas: baseFeePerGas,
+	}
+}
+
+// newTransactionDOs returns the transactions of the block which emitted the
+// logs of the block.
+func newTransactionDOs(chainId int64, block chain.Block) []do.Transaction {
+	txnHashes := lo.SliceToMap(block.Logs, func(log chain.Log) (string, struct{}) {
+		return log.TxnHash, struct{}{}
+	})
+	txns := lo.Filter(block.Txns, func(txn chain.Txn, _ int) bool {
+		_, ok := txnHashes[txn.TxnHash]
+		return ok
+	})
+	return lo.Map(txns, func(txn chain.Txn, _ int) do.Transaction {
+		return do.Transaction{
+			ChainId:     chainId,
+			TxnHash:     txn.TxnHash,
+			BlockNumber: block.BlockNumber,
+			BlockHash:   block.BlockHash,
+			TxnIndex:    txn.TxnIndex,
+			Type:        int64Quantity(txn.Type),
+			Nonce:       int64Quantity(txn.Nonce),
+			From:        txn.From,
+			To:          txn.To,
+			Value:       decimalQuantity(txn.Value),
+			Gas:         int64Quantity(txn.Gas),
+			GasPrice:    decimalQuantity(txn.GasPrice),
+			Input:       txn.Input,
+		}
+	})
+}
+
+// int64Quantity parses a hex quantity, returning 0 when it is malformed.
+func int64Quantity(s string) int64 {
+	n, err := strconv.ParseInt(strings.TrimPrefix(s, "0x"), 16, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// decimalQuantity converts a hex quantity of any size into decimal, returning
+// "0" when it is malformed.
+func decimalQuantity(s string) string {
+	n, ok := new(big.Int).SetString(strings.TrimPrefix(s, "0x"), 16)
+	if !ok {
+		return "0"
+	}
+	return n.String()
+}
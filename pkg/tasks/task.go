@@ -7,7 +7,8 @@ import (
 )
 
 const (
-	TaskBaseLogMonitor = "base-log-monitor"
+	TaskBaseLogMonitor             = "base-log-monitor"
+	TaskBaseLogPartitionMaintainer = "base-log-partition-maintainer"
 )
 
 type baseTask struct {